@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package admincmd provides the commands managing the connections, mediation and invitations of the mediator. They
+// call the admin endpoints of a running mediator, or, for the connections and mediation, work on the storage of a
+// stopped mediator with --offline.
+package admincmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/mediator/cmd/mediator/internal/adminclient"
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+const (
+	offlineFlagName  = "offline"
+	offlineFlagUsage = "Work on the storage of a stopped mediator, configured with the storage flags of the start" +
+		" command, instead of calling the admin endpoints of a running mediator."
+)
+
+// request is an admin operation: the admin endpoint running it on a running mediator, and the function running it
+// on the storage of a stopped mediator.
+type request struct {
+	method  string
+	path    string
+	offline func(c *hubaries.Connections) (interface{}, error)
+}
+
+// newRequestCmd returns a command running the request built from its arguments.
+func newRequestCmd(use, short string, numArgs int, newRequest func(args []string) *request) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short,
+		Args:  cobra.ExactArgs(numArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd, newRequest(args))
+		},
+	}
+
+	adminclient.AddFlags(cmd)
+	cmd.Flags().BoolP(offlineFlagName, "", false, offlineFlagUsage)
+	startcmd.AddStorageFlags(cmd)
+
+	return cmd
+}
+
+func run(cmd *cobra.Command, req *request) error {
+	offline, err := cmd.Flags().GetBool(offlineFlagName)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+
+	if offline {
+		out, err = runOffline(cmd, req)
+	} else {
+		out, err = send(cmd, req.method, req.path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+
+	return err
+}
+
+func runOffline(cmd *cobra.Command, req *request) ([]byte, error) {
+	err := startcmd.ApplyConfigFile(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	persistent, protocolStateStore, err := startcmd.OpenStorage(cmd, "_aries", "_ariesps")
+	if err != nil {
+		return nil, err
+	}
+
+	defer persistent.Close()         // nolint:errcheck // best effort
+	defer protocolStateStore.Close() // nolint:errcheck // best effort
+
+	connections, err := hubaries.NewConnections(persistent, protocolStateStore)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := req.offline(connections)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal response : %w", err)
+	}
+
+	return out, nil
+}
+
+// send sends the request to the admin endpoint with the given path, and returns the indented response.
+func send(cmd *cobra.Command, method, path string) ([]byte, error) {
+	client, err := adminclient.New(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := client.Do(method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+
+	err = json.Indent(out, respBytes, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("invalid response : %w", err)
+	}
+
+	return out.Bytes(), nil
+}
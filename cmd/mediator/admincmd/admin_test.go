@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admincmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/cmd/mediator/internal/adminclient"
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/pkg/restapi/operation"
+)
+
+func execute(cmd *cobra.Command, args ...string) (string, error) {
+	out := &bytes.Buffer{}
+
+	cmd.SetOut(out)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+
+	return out.String(), err
+}
+
+func TestOnline(t *testing.T) {
+	var gotMethod, gotURI, gotAuth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotMethod, gotURI, gotAuth = r.Method, r.URL.RequestURI(), r.Header.Get("Authorization")
+
+		switch r.URL.Path {
+		case "/connections/unknown":
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`{"errMessage":"data not found"}`)) // nolint:errcheck // test
+		case invitationPath, invitationV2Path:
+			_, _ = rw.Write([]byte(`{"invitation":{"id":"inv1","label":"` + // nolint:errcheck // test
+				r.URL.Query().Get("label") + `"}}`))
+		default:
+			_, _ = rw.Write([]byte(`{"connectionID":"conn1"}`)) // nolint:errcheck // test
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("connections", func(t *testing.T) {
+		out, err := execute(GetConnectionsCmd(), "list", "--"+adminclient.URLFlagName, srv.URL+"/")
+		require.NoError(t, err)
+		require.Equal(t, "{\n  \"connectionID\": \"conn1\"\n}\n", out)
+		require.Equal(t, http.MethodGet, gotMethod)
+		require.Equal(t, "/connections", gotURI)
+
+		_, err = execute(GetConnectionsCmd(), "show", "conn/1", "--"+adminclient.URLFlagName, srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, "/connections/conn%2F1", gotURI)
+
+		require.Empty(t, gotAuth)
+
+		_, err = execute(GetConnectionsCmd(), "delete", "conn1", "--"+adminclient.URLFlagName, srv.URL,
+			"--"+adminclient.TokenFlagName, "admin-secret")
+		require.NoError(t, err)
+		require.Equal(t, http.MethodDelete, gotMethod)
+		require.Equal(t, "/connections/conn1", gotURI)
+		require.Equal(t, "Bearer admin-secret", gotAuth)
+
+		_, err = execute(GetConnectionsCmd(), "show", "unknown", "--"+adminclient.URLFlagName, srv.URL)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 404")
+	})
+
+	t.Run("mediation", func(t *testing.T) {
+		_, err := execute(GetMediationCmd(), "list", "--"+adminclient.URLFlagName, srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, "/mediation", gotURI)
+
+		_, err = execute(GetMediationCmd(), "revoke", "conn1", "--"+adminclient.URLFlagName, srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.MethodPost, gotMethod)
+		require.Equal(t, "/mediation/conn1/revoke", gotURI)
+	})
+
+	t.Run("invitation", func(t *testing.T) {
+		out, err := execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, invitationPath, gotURI)
+		require.Contains(t, out, `"id": "inv1"`)
+
+		_, err = execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL,
+			"--"+v2FlagName, "--"+signedFlagName, "--"+labelFlagName, "Example Mediator")
+		require.NoError(t, err)
+		require.Equal(t, invitationV2Path+"?label=Example+Mediator&signed=true", gotURI)
+	})
+
+	t.Run("invitation url", func(t *testing.T) {
+		out, err := execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL,
+			"--"+v2FlagName, "--"+labelFlagName, "Example", "--"+formatFlagName, formatURL,
+			"--"+urlBaseFlagName, "https://example.com/connect")
+		require.NoError(t, err)
+
+		u, err := url.Parse(strings.TrimSpace(out))
+		require.NoError(t, err)
+		require.Equal(t, "example.com", u.Host)
+
+		invitation, err := base64.RawURLEncoding.DecodeString(u.Query().Get(oobV2Param))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"inv1","label":"Example"}`, string(invitation))
+
+		out, err = execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL,
+			"--"+formatFlagName, formatURL)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(out, srv.URL+"?"+oobV1Param+"="))
+	})
+
+	t.Run("invalid invitation options", func(t *testing.T) {
+		_, err := execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL, "--"+formatFlagName, "qr")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid format 'qr'")
+
+		_, err = execute(GetInvitationCmd(), "create", "--"+adminclient.URLFlagName, srv.URL, "--"+signedFlagName)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "signed is only supported with v2")
+	})
+
+	t.Run("missing admin url", func(t *testing.T) {
+		_, err := execute(GetConnectionsCmd(), "list")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Neither admin-url (command line flag) nor MEDIATOR_ADMIN_URL")
+	})
+
+	t.Run("invalid ca certs", func(t *testing.T) {
+		_, err := execute(GetMediationCmd(), "list", "--"+adminclient.URLFlagName, srv.URL, "--"+adminclient.TLSCACertsFlagName, "/not/found")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get root CAs")
+	})
+
+	t.Run("unreachable mediator", func(t *testing.T) {
+		_, err := execute(GetConnectionsCmd(), "list", "--"+adminclient.URLFlagName, "http://127.0.0.1:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "GET http://127.0.0.1:1/connections")
+	})
+}
+
+type connectionStorage struct {
+	persistent    storage.Provider
+	protocolState storage.Provider
+}
+
+func (s *connectionStorage) StorageProvider() storage.Provider {
+	return s.persistent
+}
+
+func (s *connectionStorage) ProtocolStateStorageProvider() storage.Provider {
+	return s.protocolState
+}
+
+func TestOffline(t *testing.T) {
+	dir := t.TempDir()
+	storageArgs := []string{
+		"--" + offlineFlagName,
+		"--dsn-p", "leveldb://" + filepath.Join(dir, "data"),
+		"--dsn-t", "leveldb://" + filepath.Join(dir, "data"),
+	}
+
+	// the mediator created a connection and granted mediation over it
+	func() {
+		cmd := &cobra.Command{}
+		startcmd.AddStorageFlags(cmd)
+		require.NoError(t, cmd.ParseFlags(storageArgs[1:]))
+
+		persistent, protocolState, err := startcmd.OpenStorage(cmd, "_aries", "_ariesps")
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, persistent.Close())
+			require.NoError(t, protocolState.Close())
+		}()
+
+		recorder, err := connection.NewRecorder(&connectionStorage{persistent: persistent, protocolState: protocolState})
+		require.NoError(t, err)
+
+		require.NoError(t, recorder.SaveConnectionRecord(&connection.Record{
+			ConnectionID: "conn1",
+			State:        connection.StateNameCompleted,
+			MyDID:        "did:peer:router",
+			TheirDID:     "did:peer:1",
+			Namespace:    connection.MyNSPrefix,
+		}))
+
+		routeStore, err := persistent.OpenStore("coordinatemediation")
+		require.NoError(t, err)
+
+		require.NoError(t, routeStore.Put("route-key1", []byte("did:peer:1")))
+	}()
+
+	t.Run("list connections", func(t *testing.T) {
+		out, err := execute(GetConnectionsCmd(), append([]string{"list"}, storageArgs...)...)
+		require.NoError(t, err)
+
+		resp := &operation.ConnectionsResp{}
+		require.NoError(t, json.Unmarshal([]byte(out), resp))
+		require.Len(t, resp.Connections, 1)
+		require.Equal(t, "did:peer:1", resp.Connections[0].TheirDID)
+	})
+
+	t.Run("list mediation", func(t *testing.T) {
+		out, err := execute(GetMediationCmd(), append([]string{"list"}, storageArgs...)...)
+		require.NoError(t, err)
+
+		resp := &operation.MediationsResp{}
+		require.NoError(t, json.Unmarshal([]byte(out), resp))
+		require.Len(t, resp.Mediations, 1)
+		require.Equal(t, "conn1", resp.Mediations[0].ConnectionID)
+		require.Equal(t, []string{"key1"}, resp.Mediations[0].RecipientKeys)
+	})
+
+	t.Run("revoke mediation", func(t *testing.T) {
+		out, err := execute(GetMediationCmd(), append([]string{"revoke", "conn1"}, storageArgs...)...)
+		require.NoError(t, err)
+
+		resp := &operation.RevokeMediationResp{}
+		require.NoError(t, json.Unmarshal([]byte(out), resp))
+		require.Equal(t, 1, resp.RevokedKeys)
+	})
+
+	t.Run("delete connection", func(t *testing.T) {
+		_, err := execute(GetConnectionsCmd(), append([]string{"delete", "conn1"}, storageArgs...)...)
+		require.NoError(t, err)
+
+		_, err = execute(GetConnectionsCmd(), append([]string{"show", "conn1"}, storageArgs...)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get connection conn1")
+	})
+
+	t.Run("missing storage", func(t *testing.T) {
+		_, err := execute(GetConnectionsCmd(), "list", "--"+offlineFlagName)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admincmd
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+	"github.com/trustbloc/mediator/pkg/restapi/operation"
+)
+
+const (
+	connectionsPath = "/connections"
+	mediationPath   = "/mediation"
+)
+
+// GetConnectionsCmd returns the Cobra connections command, which lists, shows and deletes the DIDComm connections
+// of the mediator.
+func GetConnectionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "connections",
+		Short: "Manage the DIDComm connections of the mediator",
+		Long:  "List, show or delete the DIDComm connections of the mediator",
+	}
+
+	cmd.AddCommand(
+		newRequestCmd("list", "List the connections", 0, func(_ []string) *request {
+			return &request{
+				method: http.MethodGet,
+				path:   connectionsPath,
+				offline: func(c *hubaries.Connections) (interface{}, error) {
+					records, err := c.List()
+
+					return &operation.ConnectionsResp{Connections: records}, err
+				},
+			}
+		}),
+		newRequestCmd("show <connection ID>", "Show a connection", 1, func(args []string) *request {
+			return &request{
+				method: http.MethodGet,
+				path:   connectionsPath + "/" + url.PathEscape(args[0]),
+				offline: func(c *hubaries.Connections) (interface{}, error) {
+					record, err := c.Get(args[0])
+
+					return &operation.ConnectionResp{Connection: record}, err
+				},
+			}
+		}),
+		newRequestCmd("delete <connection ID>",
+			"Delete a connection, without revoking the mediation granted over it", 1, func(args []string) *request {
+				return &request{
+					method: http.MethodDelete,
+					path:   connectionsPath + "/" + url.PathEscape(args[0]),
+					offline: func(c *hubaries.Connections) (interface{}, error) {
+						return &operation.DeleteConnectionResp{ConnectionID: args[0]}, c.Remove(args[0])
+					},
+				}
+			}),
+	)
+
+	return cmd
+}
+
+// GetMediationCmd returns the Cobra mediation command, which lists and revokes the mediation granted by the
+// mediator.
+func GetMediationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mediation",
+		Short: "Manage the mediation granted by the mediator",
+		Long:  "List the mediation granted by the mediator, or revoke the mediation granted over a connection",
+	}
+
+	cmd.AddCommand(
+		newRequestCmd("list", "List the mediation granted, with the routed recipient keys", 0,
+			func(_ []string) *request {
+				return &request{
+					method: http.MethodGet,
+					path:   mediationPath,
+					offline: func(c *hubaries.Connections) (interface{}, error) {
+						mediations, err := c.Mediations()
+
+						return &operation.MediationsResp{Mediations: mediations}, err
+					},
+				}
+			}),
+		newRequestCmd("revoke <connection ID>",
+			"Stop routing the messages forwarded to the recipient keys of a connection", 1,
+			func(args []string) *request {
+				return &request{
+					method: http.MethodPost,
+					path:   mediationPath + "/" + url.PathEscape(args[0]) + "/revoke",
+					offline: func(c *hubaries.Connections) (interface{}, error) {
+						count, err := c.RevokeMediation(args[0])
+
+						return &operation.RevokeMediationResp{ConnectionID: args[0], RevokedKeys: count}, err
+					},
+				}
+			}),
+	)
+
+	return cmd
+}
@@ -0,0 +1,189 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admincmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/cmd/mediator/internal/adminclient"
+)
+
+const (
+	v2FlagName  = "v2"
+	v2FlagUsage = "Create an out-of-band v2 invitation from the public DID instead of an out-of-band v1 invitation."
+
+	signedFlagName  = "signed"
+	signedFlagUsage = "Also sign the v2 invitation with the public DID. Ignored with the url format."
+
+	labelFlagName  = "label"
+	labelFlagUsage = "Label of the invitation. Defaults to mediator."
+
+	formatFlagName  = "format"
+	formatFlagUsage = "Output format: json prints the response of the mediator, url prints an invitation URL with" +
+		" the invitation in the " + oobV1Param + " (v1) or " + oobV2Param + " (v2) query parameter."
+
+	urlBaseFlagName  = "url-base"
+	urlBaseFlagUsage = "URL the invitation is added to with the url format. Defaults to the admin URL." +
+		" Alternatively, this can be set with the following environment variable: " + urlBaseEnvKey
+	urlBaseEnvKey = "MEDIATOR_INVITATION_URL_BASE"
+)
+
+const (
+	invitationPath   = "/didcomm/invitation"
+	invitationV2Path = "/didcomm/invitation-v2"
+
+	formatJSON = "json"
+	formatURL  = "url"
+
+	oobV1Param = "oob"
+	oobV2Param = "_oob"
+)
+
+// GetInvitationCmd returns the Cobra invitation command, which creates invitations to connect to a running
+// mediator.
+func GetInvitationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "invitation",
+		Short: "Create invitations to connect to the mediator",
+		Long:  "Create invitations to connect to a running mediator",
+	}
+
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create an invitation",
+		Long: "Create an out-of-band invitation with the mediator. Needs a running mediator, which creates the keys" +
+			" of v1 invitations and signs v2 invitations with its KMS.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createInvitation(cmd)
+		},
+	}
+
+	adminclient.AddFlags(create)
+	create.Flags().BoolP(v2FlagName, "", false, v2FlagUsage)
+	create.Flags().BoolP(signedFlagName, "", false, signedFlagUsage)
+	create.Flags().StringP(labelFlagName, "", "", labelFlagUsage)
+	create.Flags().StringP(formatFlagName, "", formatJSON, formatFlagUsage)
+	create.Flags().StringP(urlBaseFlagName, "", "", urlBaseFlagUsage)
+
+	cmd.AddCommand(create)
+
+	return cmd
+}
+
+func createInvitation(cmd *cobra.Command) error {
+	v2, err := cmd.Flags().GetBool(v2FlagName)
+	if err != nil {
+		return err
+	}
+
+	signed, err := cmd.Flags().GetBool(signedFlagName)
+	if err != nil {
+		return err
+	}
+
+	label, err := cmd.Flags().GetString(labelFlagName)
+	if err != nil {
+		return err
+	}
+
+	format, err := cmd.Flags().GetString(formatFlagName)
+	if err != nil {
+		return err
+	}
+
+	if format != formatJSON && format != formatURL {
+		return fmt.Errorf("invalid %s '%s': must be one of [%s %s]", formatFlagName, format, formatJSON, formatURL)
+	}
+
+	if signed && !v2 {
+		return fmt.Errorf("%s is only supported with %s", signedFlagName, v2FlagName)
+	}
+
+	path, param := invitationPath, oobV1Param
+	query := url.Values{}
+
+	if v2 {
+		path, param = invitationV2Path, oobV2Param
+
+		if signed && format == formatJSON {
+			query.Set("signed", "true")
+		}
+	}
+
+	if label != "" {
+		query.Set("label", label)
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	out, err := send(cmd, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+
+	if format == formatURL {
+		out, err = invitationURL(cmd, out, param)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+
+	return err
+}
+
+// invitationURL returns the URL with the invitation of the response base64URL encoded in the query parameter.
+func invitationURL(cmd *cobra.Command, resp []byte, param string) ([]byte, error) {
+	base, err := cmdutils.GetUserSetVarFromString(cmd, urlBaseFlagName, urlBaseEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if base == "" {
+		base, err = adminclient.GetURL(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s : %w", urlBaseFlagName, err)
+	}
+
+	invResp := struct {
+		Invitation json.RawMessage `json:"invitation"`
+	}{}
+
+	err = json.Unmarshal(resp, &invResp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response : %w", err)
+	}
+
+	// re-marshal to strip the indentation
+	invitation, err := json.Marshal(invResp.Invitation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response : %w", err)
+	}
+
+	query := u.Query()
+	query.Set(param, base64.RawURLEncoding.EncodeToString(invitation))
+	u.RawQuery = query.Encode()
+
+	return []byte(u.String()), nil
+}
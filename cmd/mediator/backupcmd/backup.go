@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backupcmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/pkg/storage/archive"
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+const (
+	backupKeyFileFlagName  = "backup-key-file"
+	backupKeyFileFlagUsage = "Path of a file holding the base64 encoded 32 byte key the archive is encrypted with." +
+		" Alternatively, this can be set with the following environment variable: " + backupKeyFileEnvKey
+	backupKeyFileEnvKey = "MEDIATOR_BACKUP_KEY_FILE"
+
+	forceFlagName  = "force"
+	forceFlagUsage = "Restore to storage that isn't empty. The entries of the archive replace the entries with the" +
+		" same keys."
+)
+
+// GetBackupCmd returns the Cobra backup command, which exports the storage of a stopped mediator to an encrypted
+// archive.
+func GetBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup <archive file>",
+		Short: "Back up the storage of the mediator",
+		Long: "Export all the stores of the storage of a stopped mediator (hubrouter, hubrouter_txn, hubrouter_aries" +
+			" and hubrouter_ariesps) to an archive encrypted with the backup key. The private keys are exported" +
+			" encrypted with the master key of the KMS, if any, which is needed to use them after a restore.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return backup(cmd, args[0])
+		},
+	}
+
+	startcmd.AddStorageFlags(cmd)
+	cmd.Flags().StringP(backupKeyFileFlagName, "", "", backupKeyFileFlagUsage)
+
+	return cmd
+}
+
+// GetRestoreCmd returns the Cobra restore command, which imports an archive written by the backup command into the
+// storage of a stopped mediator.
+func GetRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore <archive file>",
+		Short: "Restore the storage of the mediator from a backup",
+		Long: "Import the stores of an archive written by the backup command into the storage of a stopped" +
+			" mediator, which can use another driver than the backed up mediator.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return restore(cmd, args[0])
+		},
+	}
+
+	startcmd.AddStorageFlags(cmd)
+	cmd.Flags().StringP(backupKeyFileFlagName, "", "", backupKeyFileFlagUsage)
+	cmd.Flags().BoolP(forceFlagName, "", false, forceFlagUsage)
+
+	return cmd
+}
+
+func backup(cmd *cobra.Command, path string) error {
+	key, providers, err := prepare(cmd)
+	if err != nil {
+		return err
+	}
+
+	defer startcmd.CloseAllStorage(providers)
+
+	if _, err = os.Stat(path); err == nil {
+		return fmt.Errorf("archive %s already exists", path)
+	}
+
+	// the archive is written to a temporary file, so that an incomplete archive is never left at the path
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create archive : %w", err)
+	}
+
+	defer os.Remove(f.Name()) // nolint:errcheck // removed if not renamed
+	defer f.Close()           // nolint:errcheck // closed if not renamed
+
+	w, err := archive.NewWriter(f, key)
+	if err != nil {
+		return err
+	}
+
+	stats, err := archive.Backup(w, providers)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("write archive : %w", err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fmt.Errorf("write archive : %w", err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "backed up %d stores and %d entries to %s\n",
+		stats.Stores, stats.Entries, path)
+
+	return err
+}
+
+func restore(cmd *cobra.Command, path string) error {
+	force, err := cmd.Flags().GetBool(forceFlagName)
+	if err != nil {
+		return err
+	}
+
+	key, providers, err := prepare(cmd)
+	if err != nil {
+		return err
+	}
+
+	defer startcmd.CloseAllStorage(providers)
+
+	if !force {
+		err = checkEmpty(providers)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(path) // nolint:gosec // path set by the operator
+	if err != nil {
+		return fmt.Errorf("open archive : %w", err)
+	}
+
+	defer f.Close() // nolint:errcheck // read only
+
+	r, err := archive.NewReader(f, key)
+	if err != nil {
+		return err
+	}
+
+	stats, err := archive.Restore(r, providers)
+	if err != nil {
+		return fmt.Errorf("restore (%d stores and %d entries done) : %w", stats.Stores, stats.Entries, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "restored %d stores and %d entries from %s, backed up at %s\n",
+		stats.Stores, stats.Entries, path, r.Header().Created.Format(time.RFC3339))
+
+	return err
+}
+
+// prepare reads the backup key and opens the storage.
+func prepare(cmd *cobra.Command) ([]byte, map[string]storage.Provider, error) {
+	err := startcmd.ApplyConfigFile(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyFile, err := cmdutils.GetUserSetVarFromString(cmd, backupKeyFileFlagName, backupKeyFileEnvKey, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := archive.ReadKeyFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	providers, err := startcmd.OpenAllStorage(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, providers, nil
+}
+
+func checkEmpty(providers map[string]storage.Provider) error {
+	for prefix, p := range providers {
+		enumerableProvider, ok := p.(*enumerable.Provider)
+		if !ok {
+			return fmt.Errorf("the stores of storage %s can't be listed", prefix)
+		}
+
+		names, err := enumerable.Stores(enumerableProvider)
+		if err != nil {
+			return fmt.Errorf("list stores of storage %s : %w", prefix, err)
+		}
+
+		if len(names) > 0 {
+			return fmt.Errorf("storage %s isn't empty: restore with --%s to replace its entries", prefix,
+				forceFlagName)
+		}
+	}
+
+	return nil
+}
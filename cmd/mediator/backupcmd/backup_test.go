@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backupcmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/pkg/storage/archive"
+)
+
+func writeKey(t *testing.T, path string) {
+	t.Helper()
+
+	key := make([]byte, archive.KeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)), 0o600))
+}
+
+func storageArgs(dir string) []string {
+	return []string{
+		"--dsn-p", "leveldb://" + filepath.Join(dir, "persistent"),
+		"--dsn-t", "leveldb://" + filepath.Join(dir, "transient"),
+	}
+}
+
+// withStorage opens the storage of the mediator in dir and runs fn with its stores, by storage prefix.
+func withStorage(t *testing.T, dir string, fn func(open func(prefix, name string) map[string][]byte)) {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	startcmd.AddStorageFlags(cmd)
+	require.NoError(t, cmd.ParseFlags(storageArgs(dir)))
+
+	providers, err := startcmd.OpenAllStorage(cmd)
+	require.NoError(t, err)
+
+	defer startcmd.CloseAllStorage(providers)
+
+	fn(func(prefix, name string) map[string][]byte {
+		s, err := providers[prefix].OpenStore(name)
+		require.NoError(t, err)
+
+		entries := map[string][]byte{}
+
+		for _, k := range []string{"k1", "k2"} {
+			v, err := s.Get(k)
+			if err == nil {
+				entries[k] = v
+			}
+		}
+
+		if len(entries) == 0 {
+			require.NoError(t, s.Put("k1", []byte(prefix+"/"+name)))
+		}
+
+		return entries
+	})
+}
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	target := filepath.Join(dir, "target")
+	keyFile := filepath.Join(dir, "backup.key")
+	archiveFile := filepath.Join(dir, "mediator.backup")
+
+	writeKey(t, keyFile)
+
+	withStorage(t, source, func(open func(prefix, name string) map[string][]byte) {
+		open("hubrouter", "router-invitation-did")
+		open("hubrouter_txn", "create-conn-req")
+		open("hubrouter_aries", "kmsdb")
+		open("hubrouter_ariesps", "didexchange")
+	})
+
+	execute := func(cmd *cobra.Command, args ...string) (string, error) {
+		out := &bytes.Buffer{}
+
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+
+		return out.String(), err
+	}
+
+	t.Run("backup", func(t *testing.T) {
+		out, err := execute(GetBackupCmd(), append(storageArgs(source),
+			archiveFile, "--"+backupKeyFileFlagName, keyFile)...)
+		require.NoError(t, err)
+		require.Equal(t, "backed up 4 stores and 4 entries to "+archiveFile+"\n", out)
+
+		_, err = execute(GetBackupCmd(), append(storageArgs(source),
+			archiveFile, "--"+backupKeyFileFlagName, keyFile)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "already exists")
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		out, err := execute(GetRestoreCmd(), append(storageArgs(target),
+			archiveFile, "--"+backupKeyFileFlagName, keyFile)...)
+		require.NoError(t, err)
+		require.Contains(t, out, "restored 4 stores and 4 entries from "+archiveFile)
+
+		withStorage(t, target, func(open func(prefix, name string) map[string][]byte) {
+			require.Equal(t, map[string][]byte{"k1": []byte("hubrouter_aries/kmsdb")}, open("hubrouter_aries", "kmsdb"))
+			require.Equal(t, map[string][]byte{"k1": []byte("hubrouter_ariesps/didexchange")},
+				open("hubrouter_ariesps", "didexchange"))
+		})
+	})
+
+	t.Run("restore to storage that isn't empty", func(t *testing.T) {
+		_, err := execute(GetRestoreCmd(), append(storageArgs(target),
+			archiveFile, "--"+backupKeyFileFlagName, keyFile)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't empty")
+
+		_, err = execute(GetRestoreCmd(), append(storageArgs(target),
+			archiveFile, "--"+backupKeyFileFlagName, keyFile, "--"+forceFlagName)...)
+		require.NoError(t, err)
+	})
+
+	t.Run("wrong backup key", func(t *testing.T) {
+		otherKeyFile := filepath.Join(dir, "other.key")
+		writeKey(t, otherKeyFile)
+
+		_, err := execute(GetRestoreCmd(), append(storageArgs(filepath.Join(dir, "other")),
+			archiveFile, "--"+backupKeyFileFlagName, otherKeyFile)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wrong backup key")
+	})
+
+	t.Run("missing backup key", func(t *testing.T) {
+		_, err := execute(GetBackupCmd(), append(storageArgs(source), filepath.Join(dir, "other.backup"))...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Neither backup-key-file (command line flag) nor MEDIATOR_BACKUP_KEY_FILE")
+	})
+
+	t.Run("missing archive", func(t *testing.T) {
+		_, err := execute(GetRestoreCmd(), append(storageArgs(filepath.Join(dir, "other")),
+			filepath.Join(dir, "missing.backup"), "--"+backupKeyFileFlagName, keyFile)...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open archive")
+	})
+}
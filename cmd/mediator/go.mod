@@ -8,9 +8,14 @@ go 1.15
 
 require (
 	github.com/cenkalti/backoff/v4 v4.1.3
+	github.com/google/uuid v1.3.0
 	github.com/gorilla/mux v1.8.0
 	github.com/hyperledger/aries-framework-go v0.1.9-0.20220809201627-6c0753b49bcd
+	github.com/hyperledger/aries-framework-go-ext/component/storage/couchdb v0.0.0-20220629202442-ce8776c10037
 	github.com/hyperledger/aries-framework-go-ext/component/storage/mongodb v0.0.0-20220615170242-cda5092b4faf
+	github.com/hyperledger/aries-framework-go-ext/component/storage/mysql v0.0.0-20220629202442-ce8776c10037
+	github.com/hyperledger/aries-framework-go-ext/component/storage/postgresql v0.0.0-20220629202442-ce8776c10037
+	github.com/hyperledger/aries-framework-go/component/storage/leveldb v0.0.0-20220614152730-3d817acfa48b
 	github.com/hyperledger/aries-framework-go/component/storageutil v0.0.0-20220428211718-66cc046674a1
 	github.com/hyperledger/aries-framework-go/spi v0.0.0-20220614152730-3d817acfa48b
 	github.com/phayes/freeport v0.0.0-20180830031419-95f893ade6f2
@@ -19,6 +24,9 @@ require (
 	github.com/stretchr/testify v1.7.2
 	github.com/trustbloc/edge-core v0.1.8
 	github.com/trustbloc/mediator v0.0.0-00010101000000-000000000000
+	golang.org/x/net v0.0.0-20220127200216-cd36cc0744dd
+	gopkg.in/yaml.v3 v3.0.1
+	nhooyr.io/websocket v1.8.3
 )
 
 replace github.com/trustbloc/mediator => ../..
@@ -0,0 +1,152 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package adminclient provides the flags and the HTTP client of the commands calling the admin endpoints of a running
+// mediator.
+package adminclient
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
+)
+
+const (
+	// URLFlagName is the flag of the URL of the mediator REST API.
+	URLFlagName  = "admin-url"
+	urlFlagUsage = "URL of the mediator REST API, ex: https://localhost:8080." +
+		" Alternatively, this can be set with the following environment variable: " + urlEnvKey
+	urlEnvKey = "MEDIATOR_ADMIN_URL"
+
+	// TokenFlagName is the flag of the admin token of the mediator.
+	TokenFlagName  = "admin-token"
+	tokenFlagUsage = "Admin token of the mediator, sent as bearer token." +
+		" Alternatively, this can be set with the following environment variable: " + tokenEnvKey
+	tokenEnvKey = "MEDIATOR_ADMIN_TOKEN"
+
+	// TLSCACertsFlagName is the flag of the CA certificates of the mediator REST API.
+	TLSCACertsFlagName  = "tls-cacerts"
+	tlsCACertsFlagUsage = "Comma-Separated list of ca certs path." +
+		" Alternatively, this can be set with the following environment variable: " + tlsCACertsEnvKey
+	tlsCACertsEnvKey = "MEDIATOR_TLS_CACERTS"
+)
+
+const requestTimeout = 2 * time.Minute
+
+// AddFlags adds the flags of the client to the command.
+func AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(URLFlagName, "", "", urlFlagUsage)
+	cmd.Flags().StringP(TokenFlagName, "", "", tokenFlagUsage)
+	cmd.Flags().StringArrayP(TLSCACertsFlagName, "", []string{}, tlsCACertsFlagUsage)
+}
+
+// GetURL returns the URL of the mediator REST API, without trailing slash.
+func GetURL(cmd *cobra.Command) (string, error) {
+	adminURL, err := cmdutils.GetUserSetVarFromString(cmd, URLFlagName, urlEnvKey, false)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(adminURL, "/"), nil
+}
+
+// Client calls the admin endpoints of a running mediator with the admin token.
+type Client struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// New returns the client configured with the flags of the command.
+func New(cmd *cobra.Command) (*Client, error) {
+	adminURL, err := GetURL(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := cmdutils.GetUserSetVarFromString(cmd, TokenFlagName, tokenEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	caCerts, err := cmdutils.GetUserSetVarFromArrayString(cmd, TLSCACertsFlagName, tlsCACertsEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rootCAs, err := tlsutils.GetCertPool(false, caCerts)
+	if err != nil {
+		return nil, fmt.Errorf("get root CAs : %w", err)
+	}
+
+	return &Client{
+		url:   adminURL,
+		token: token,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+// Do sends the request to the admin endpoint with the given path, with req as JSON body unless nil. Returns the
+// response body, responses other than 200 are errors.
+func (c *Client) Do(method, path string, req interface{}) ([]byte, error) {
+	var body io.Reader
+
+	if req != nil {
+		reqBytes, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request : %w", err)
+		}
+
+		body = bytes.NewReader(reqBytes)
+	}
+
+	url := c.url + path
+
+	httpReq, err := http.NewRequest(method, url, body) // nolint:noctx // client has a timeout
+	if err != nil {
+		return nil, fmt.Errorf("%s %s : %w", method, url, err)
+	}
+
+	if req != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s : %w", method, url, err)
+	}
+
+	defer resp.Body.Close() // nolint:errcheck // read only
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response : %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s : status %d : %s", method, url, resp.StatusCode,
+			strings.TrimSpace(string(respBytes)))
+	}
+
+	return respBytes, nil
+}
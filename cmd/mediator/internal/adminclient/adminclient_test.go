@@ -0,0 +1,105 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package adminclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func newCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	AddFlags(cmd)
+
+	require.NoError(t, cmd.ParseFlags(args))
+
+	return cmd
+}
+
+func TestClient(t *testing.T) {
+	type received struct {
+		method, path, auth, contentType, body string
+	}
+
+	requests := make(chan received, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		requests <- received{
+			method:      r.Method,
+			path:        r.URL.Path,
+			auth:        r.Header.Get("Authorization"),
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		}
+
+		if r.URL.Path == "/missing" {
+			http.Error(rw, "not found", http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(`{"ok":true}`)) // nolint:errcheck // test
+	}))
+	defer srv.Close()
+
+	t.Run("request with a JSON body and the admin token", func(t *testing.T) {
+		client, err := New(newCmd(t, "--"+URLFlagName, srv.URL+"/", "--"+TokenFlagName, "admin-secret"))
+		require.NoError(t, err)
+
+		resp, err := client.Do(http.MethodPost, "/public-did/update", map[string]string{"serviceEndpoint": "e"})
+		require.NoError(t, err)
+		require.Equal(t, `{"ok":true}`, string(resp))
+		require.Equal(t, received{
+			method:      http.MethodPost,
+			path:        "/public-did/update",
+			auth:        "Bearer admin-secret",
+			contentType: "application/json",
+			body:        `{"serviceEndpoint":"e"}`,
+		}, <-requests)
+	})
+
+	t.Run("request without body nor token", func(t *testing.T) {
+		client, err := New(newCmd(t, "--"+URLFlagName, srv.URL))
+		require.NoError(t, err)
+
+		_, err = client.Do(http.MethodGet, "/connections", nil)
+		require.NoError(t, err)
+		require.Equal(t, received{method: http.MethodGet, path: "/connections"}, <-requests)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		client, err := New(newCmd(t, "--"+URLFlagName, srv.URL))
+		require.NoError(t, err)
+
+		_, err = client.Do(http.MethodGet, "/missing", nil)
+		<-requests
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "GET "+srv.URL+"/missing : status 404 : not found")
+	})
+
+	t.Run("missing admin url", func(t *testing.T) {
+		_, err := New(newCmd(t))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Neither admin-url (command line flag) nor MEDIATOR_ADMIN_URL")
+	})
+
+	t.Run("invalid ca certs", func(t *testing.T) {
+		_, err := New(newCmd(t, "--"+URLFlagName, srv.URL, "--"+TLSCACertsFlagName, "/missing/ca.pem"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get root CAs")
+	})
+}
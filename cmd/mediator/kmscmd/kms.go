@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmscmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+const (
+	oldSecretLockKeyPathFlagName  = "old-secret-lock-key-path"
+	oldSecretLockKeyPathFlagUsage = "Path of a file holding the base64URL encoded master key the private keys are" +
+		" currently encrypted with. The key itself can be set with the " + oldSecretLockKeyEnvKey +
+		" environment variable instead. Without it, the private keys are expected to be unencrypted." +
+		" Alternatively, this can be set with the following environment variable: " + oldSecretLockKeyPathEnvKey
+	oldSecretLockKeyPathEnvKey = "MEDIATOR_OLD_SECRET_LOCK_KEY_PATH"
+	oldSecretLockKeyEnvKey     = "MEDIATOR_OLD_SECRET_LOCK_KEY"
+)
+
+// GetKMSCmd returns the Cobra kms command, which manages the private keys of the local KMS of a stopped mediator.
+func GetKMSCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kms",
+		Short: "Manage the private keys of the mediator",
+		Long:  "Manage the private keys of the local KMS of a stopped mediator",
+	}
+
+	cmd.AddCommand(newRewrapCmd())
+
+	return cmd
+}
+
+func newRewrapCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrap",
+		Short: "Encrypt the private keys with a new master key",
+		Long: "Encrypt the private keys of the local KMS with the master key set with secret-lock-key-path," +
+			" when the master key is introduced or rotated. The mediator must be stopped. Keys already encrypted" +
+			" with the new master key are skipped, so an interrupted run can be run again.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rewrap(cmd)
+		},
+	}
+
+	startcmd.AddStorageFlags(cmd)
+	startcmd.AddSecretLockFlags(cmd)
+	cmd.Flags().StringP(oldSecretLockKeyPathFlagName, "", "", oldSecretLockKeyPathFlagUsage)
+
+	return cmd
+}
+
+func rewrap(cmd *cobra.Command) error {
+	err := startcmd.ApplyConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+
+	from, err := getOldSecretLock(cmd)
+	if err != nil {
+		return err
+	}
+
+	to, err := startcmd.GetSecretLock(cmd)
+	if err != nil {
+		return err
+	}
+
+	persistent, protocolStateStore, err := startcmd.OpenStorage(cmd, "_aries", "_ariesps")
+	if err != nil {
+		return err
+	}
+
+	defer persistent.Close()         // nolint:errcheck // best effort
+	defer protocolStateStore.Close() // nolint:errcheck // best effort
+
+	count, err := hubaries.RewrapKeys(persistent, from, to)
+	if err != nil {
+		return fmt.Errorf("rewrap keys (%d keys done) : %w", count, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "re-encrypted %d keys\n", count)
+
+	return err
+}
+
+func getOldSecretLock(cmd *cobra.Command) (secretlock.Service, error) {
+	path, err := cmdutils.GetUserSetVarFromString(cmd, oldSecretLockKeyPathFlagName, oldSecretLockKeyPathEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, isSet := os.LookupEnv(oldSecretLockKeyEnvKey)
+
+	var masterKey []byte
+
+	switch {
+	case path != "" && isSet:
+		return nil, fmt.Errorf("set either %s or %s", oldSecretLockKeyPathFlagName, oldSecretLockKeyEnvKey)
+	case path != "":
+		masterKey, err = hubaries.ReadMasterKey(path)
+	case isSet:
+		masterKey, err = hubaries.ParseMasterKey(encoded)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("old master key : %w", err)
+	}
+
+	return hubaries.NewSecretLock(masterKey)
+}
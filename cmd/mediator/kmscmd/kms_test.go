@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kmscmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+type kmsProvider struct {
+	store storage.Provider
+	lock  secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.store
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.lock
+}
+
+func writeMasterKey(t *testing.T, dir, name string) (string, []byte) {
+	t.Helper()
+
+	key := make([]byte, hubaries.MasterKeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.URLEncoding.EncodeToString(key)), 0o600))
+
+	return path, key
+}
+
+// withKMS opens the storage of the mediator and runs fn with its local KMS.
+func withKMS(t *testing.T, storageArgs []string, masterKey []byte, fn func(k kms.KeyManager)) {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	startcmd.AddStorageFlags(cmd)
+	require.NoError(t, cmd.ParseFlags(storageArgs))
+
+	persistent, protocolStateStore, err := startcmd.OpenStorage(cmd, "_aries", "_ariesps")
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, persistent.Close())
+		require.NoError(t, protocolStateStore.Close())
+	}()
+
+	lock, err := hubaries.NewSecretLock(masterKey)
+	require.NoError(t, err)
+
+	k, err := localkms.New("local-lock://default/master/key/", &kmsProvider{store: persistent, lock: lock})
+	require.NoError(t, err)
+
+	fn(k)
+}
+
+func TestRewrapCmd(t *testing.T) {
+	dir := t.TempDir()
+	storageArgs := []string{
+		"--dsn-p", "leveldb://" + filepath.Join(dir, "data"),
+		"--dsn-t", "leveldb://" + filepath.Join(dir, "data"),
+	}
+
+	firstKeyPath, firstKey := writeMasterKey(t, dir, "first.key")
+	secondKeyPath, secondKey := writeMasterKey(t, dir, "second.key")
+
+	var kid string
+
+	withKMS(t, storageArgs, nil, func(k kms.KeyManager) {
+		var err error
+
+		kid, _, err = k.Create(kms.ED25519Type)
+		require.NoError(t, err)
+	})
+
+	execute := func(args ...string) (string, error) {
+		cmd := GetKMSCmd()
+		out := &bytes.Buffer{}
+
+		cmd.SetOut(out)
+		cmd.SetArgs(append(append([]string{"rewrap"}, storageArgs...), args...))
+
+		err := cmd.Execute()
+
+		return out.String(), err
+	}
+
+	t.Run("introduce a master key", func(t *testing.T) {
+		out, err := execute("--secret-lock-key-path", firstKeyPath)
+		require.NoError(t, err)
+		require.Equal(t, "re-encrypted 1 keys\n", out)
+
+		withKMS(t, storageArgs, firstKey, func(k kms.KeyManager) {
+			_, err = k.Get(kid)
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("rotate the master key", func(t *testing.T) {
+		out, err := execute("--secret-lock-key-path", secondKeyPath, "--"+oldSecretLockKeyPathFlagName, firstKeyPath)
+		require.NoError(t, err)
+		require.Equal(t, "re-encrypted 1 keys\n", out)
+
+		withKMS(t, storageArgs, secondKey, func(k kms.KeyManager) {
+			_, err = k.Get(kid)
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("wrong old master key", func(t *testing.T) {
+		otherKeyPath, _ := writeMasterKey(t, dir, "other.key")
+
+		_, err := execute("--secret-lock-key-path", firstKeyPath, "--"+oldSecretLockKeyPathFlagName, otherKeyPath)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decrypt key")
+	})
+
+	t.Run("no new master key", func(t *testing.T) {
+		_, err := execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no master key")
+	})
+
+	t.Run("invalid old master key", func(t *testing.T) {
+		_, err := execute("--secret-lock-key-path", firstKeyPath,
+			"--"+oldSecretLockKeyPathFlagName, filepath.Join(dir, "missing.key"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "old master key")
+	})
+}
@@ -11,7 +11,13 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/trustbloc/mediator/cmd/mediator/admincmd"
+	"github.com/trustbloc/mediator/cmd/mediator/backupcmd"
+	"github.com/trustbloc/mediator/cmd/mediator/kmscmd"
+	"github.com/trustbloc/mediator/cmd/mediator/migratecmd"
+	"github.com/trustbloc/mediator/cmd/mediator/publicdidcmd"
 	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/cmd/mediator/versioncmd"
 )
 
 func main() {
@@ -23,6 +29,17 @@ func main() {
 	}
 
 	cmd.AddCommand(startcmd.GetStartCmd(&startcmd.HTTPServer{}))
+	cmd.AddCommand(publicdidcmd.GetPublicDIDCmd())
+	cmd.AddCommand(kmscmd.GetKMSCmd())
+	cmd.AddCommand(startcmd.GetCapabilitiesCmd())
+	cmd.AddCommand(startcmd.GetConfigCmd())
+	cmd.AddCommand(versioncmd.GetVersionCmd())
+	cmd.AddCommand(admincmd.GetConnectionsCmd())
+	cmd.AddCommand(admincmd.GetMediationCmd())
+	cmd.AddCommand(admincmd.GetInvitationCmd())
+	cmd.AddCommand(backupcmd.GetBackupCmd())
+	cmd.AddCommand(backupcmd.GetRestoreCmd())
+	cmd.AddCommand(migratecmd.GetMigrateCmd())
 
 	if err := cmd.Execute(); err != nil {
 		log.Fatalf("failed to run mediator: %s", err.Error())
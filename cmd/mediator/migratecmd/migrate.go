@@ -0,0 +1,244 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migratecmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+	"github.com/trustbloc/mediator/pkg/storage/migration"
+)
+
+const (
+	fromFlagName  = "from"
+	fromFlagUsage = "Datasource to migrate from, with the format of the dsn-p flag of the start command" +
+		" (ex: leveldb://data)." +
+		" Alternatively, this can be set with the following environment variable: " + fromEnvKey
+	fromEnvKey = "MEDIATOR_MIGRATE_FROM"
+
+	fromTransientFlagName  = "from-transient"
+	fromTransientFlagUsage = "Transient datasource to migrate from, if the mediator used a dsn-t other than its dsn-p." +
+		" Defaults to the from datasource." +
+		" Alternatively, this can be set with the following environment variable: " + fromTransientEnvKey
+	fromTransientEnvKey = "MEDIATOR_MIGRATE_FROM_TRANSIENT"
+
+	toFlagName  = "to"
+	toFlagUsage = "Datasource to migrate to, with the format of the dsn-p flag of the start command" +
+		" (ex: mongodb://mongodb.example.com:27017)." +
+		" Alternatively, this can be set with the following environment variable: " + toEnvKey
+	toEnvKey = "MEDIATOR_MIGRATE_TO"
+
+	toTransientFlagName  = "to-transient"
+	toTransientFlagUsage = "Transient datasource to migrate to, for the mediator to use a dsn-t other than its dsn-p." +
+		" Defaults to the to datasource." +
+		" Alternatively, this can be set with the following environment variable: " + toTransientEnvKey
+	toTransientEnvKey = "MEDIATOR_MIGRATE_TO_TRANSIENT"
+
+	progressFileFlagName  = "progress-file"
+	progressFileFlagUsage = "File recording the stores migrated, to resume an interrupted migration." +
+		" It's removed once the migration is complete. Defaults to " + progressFileDefault + "." +
+		" Alternatively, this can be set with the following environment variable: " + progressFileEnvKey
+	progressFileEnvKey  = "MEDIATOR_MIGRATE_PROGRESS_FILE"
+	progressFileDefault = "mediator-migrate.progress"
+
+	dryRunFlagName  = "dry-run"
+	dryRunFlagUsage = "Only list the stores and count the entries to migrate, without writing to the target datasource."
+
+	memDriverPrefix = "mem:"
+)
+
+// GetMigrateCmd returns the Cobra migrate command, which copies the storage of a stopped mediator to another
+// datasource.
+func GetMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the storage of the mediator to another datasource",
+		Long: "Copy all the stores of the storage of a stopped mediator (hubrouter, hubrouter_txn, hubrouter_aries" +
+			" and hubrouter_ariesps), with their tags, to another datasource, which can use another driver. The" +
+			" number of entries of each store copied is verified. An interrupted migration resumes after the last" +
+			" store copied when run again with the same datasources. The storage encryption flags apply to both" +
+			" datasources.",
+		Args: cobra.NoArgs,
+		RunE: migrate,
+	}
+
+	startcmd.AddStorageOptionFlags(cmd)
+	cmd.Flags().StringP(fromFlagName, "", "", fromFlagUsage)
+	cmd.Flags().StringP(fromTransientFlagName, "", "", fromTransientFlagUsage)
+	cmd.Flags().StringP(toFlagName, "", "", toFlagUsage)
+	cmd.Flags().StringP(toTransientFlagName, "", "", toTransientFlagUsage)
+	cmd.Flags().StringP(progressFileFlagName, "", "", progressFileFlagUsage)
+	cmd.Flags().BoolP(dryRunFlagName, "", false, dryRunFlagUsage)
+
+	return cmd
+}
+
+type datasources struct {
+	from, fromTransient, to, toTransient string
+}
+
+func migrate(cmd *cobra.Command, _ []string) error {
+	err := startcmd.ApplyConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool(dryRunFlagName)
+	if err != nil {
+		return err
+	}
+
+	ds, err := getDatasources(cmd)
+	if err != nil {
+		return err
+	}
+
+	progressFile, err := cmdutils.GetUserSetVarFromString(cmd, progressFileFlagName, progressFileEnvKey, true)
+	if err != nil {
+		return err
+	}
+
+	if progressFile == "" {
+		progressFile = progressFileDefault
+	}
+
+	from, err := startcmd.OpenAllStorageAt(cmd, ds.from, ds.fromTransient)
+	if err != nil {
+		return fmt.Errorf("open source storage : %w", err)
+	}
+
+	defer startcmd.CloseAllStorage(from)
+
+	opts := &migration.Options{
+		DryRun: dryRun,
+		Report: func(s *migration.StoreStats) {
+			status := ""
+			if s.Skipped {
+				status = " (migrated before)"
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s/%s: %d entries%s\n", s.Provider, s.Name, s.Entries, status) // nolint:errcheck // report only
+		},
+	}
+
+	var to map[string]storage.Provider
+
+	if !dryRun {
+		opts.Progress, err = migration.LoadProgress(progressFile,
+			migration.Fingerprint(ds.from, ds.fromTransient, ds.to, ds.toTransient))
+		if err != nil {
+			return err
+		}
+
+		to, err = startcmd.OpenAllStorageAt(cmd, ds.to, ds.toTransient)
+		if err != nil {
+			return fmt.Errorf("open target storage : %w", err)
+		}
+
+		defer startcmd.CloseAllStorage(to)
+
+		if !opts.Progress.Resumed() {
+			err = checkEmpty(to)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	stats, err := migration.Migrate(from, to, opts)
+	if err != nil {
+		return fmt.Errorf("migrate (%d stores and %d entries done, progress in %s) : %w",
+			stats.Stores, stats.Entries, progressFile, err)
+	}
+
+	if dryRun {
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%d stores and %d entries to migrate\n", stats.Stores, stats.Entries)
+
+		return err
+	}
+
+	err = opts.Progress.Remove()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "migrated %d stores and %d entries\n", stats.Stores, stats.Entries)
+
+	return err
+}
+
+func getDatasources(cmd *cobra.Command) (*datasources, error) {
+	from, err := cmdutils.GetUserSetVarFromString(cmd, fromFlagName, fromEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := cmdutils.GetUserSetVarFromString(cmd, toFlagName, toEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTransient, err := cmdutils.GetUserSetVarFromString(cmd, fromTransientFlagName, fromTransientEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	toTransient, err := cmdutils.GetUserSetVarFromString(cmd, toTransientFlagName, toTransientEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &datasources{from: from, fromTransient: fromTransient, to: to, toTransient: toTransient}
+
+	if ds.fromTransient == "" {
+		ds.fromTransient = ds.from
+	}
+
+	if ds.toTransient == "" {
+		ds.toTransient = ds.to
+	}
+
+	for _, dsn := range []string{ds.from, ds.fromTransient, ds.to, ds.toTransient} {
+		if strings.HasPrefix(dsn, memDriverPrefix) {
+			return nil, errors.New("can't migrate from or to the mem driver: its data is only in the memory of" +
+				" the running mediator")
+		}
+	}
+
+	if ds.from == ds.to || ds.fromTransient == ds.toTransient {
+		return nil, errors.New("can't migrate to the datasource migrated from")
+	}
+
+	return ds, nil
+}
+
+func checkEmpty(providers map[string]storage.Provider) error {
+	for prefix, p := range providers {
+		enumerableProvider, ok := p.(*enumerable.Provider)
+		if !ok {
+			return fmt.Errorf("the stores of storage %s can't be listed", prefix)
+		}
+
+		names, err := enumerable.Stores(enumerableProvider)
+		if err != nil {
+			return fmt.Errorf("list stores of storage %s : %w", prefix, err)
+		}
+
+		if len(names) > 0 {
+			return fmt.Errorf("target storage %s isn't empty", prefix)
+		}
+	}
+
+	return nil
+}
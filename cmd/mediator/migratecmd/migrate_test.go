@@ -0,0 +1,163 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migratecmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/cmd/mediator/startcmd"
+	"github.com/trustbloc/mediator/pkg/storage/migration"
+)
+
+func leveldb(dir string) string {
+	return "leveldb://" + dir
+}
+
+// withStorage opens the storage of the mediator in dir and runs fn with a function putting or reading an entry of a
+// store, by storage prefix.
+func withStorage(t *testing.T, dir string, fn func(open func(prefix, name string) []byte)) {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	startcmd.AddStorageOptionFlags(cmd)
+
+	providers, err := startcmd.OpenAllStorageAt(cmd, leveldb(dir), leveldb(dir))
+	require.NoError(t, err)
+
+	defer startcmd.CloseAllStorage(providers)
+
+	fn(func(prefix, name string) []byte {
+		s, err := providers[prefix].OpenStore(name)
+		require.NoError(t, err)
+
+		v, err := s.Get("k1")
+		if err != nil {
+			require.NoError(t, s.Put("k1", []byte(prefix+"/"+name)))
+		}
+
+		return v
+	})
+}
+
+func execute(args ...string) (string, error) {
+	cmd := GetMigrateCmd()
+	out := &bytes.Buffer{}
+
+	cmd.SetOut(out)
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+
+	return out.String(), err
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source")
+	target := filepath.Join(dir, "target")
+	progressFile := filepath.Join(dir, "migrate.progress")
+
+	withStorage(t, source, func(open func(prefix, name string) []byte) {
+		open("hubrouter", "router-invitation-did")
+		open("hubrouter_txn", "create-conn-req")
+		open("hubrouter_aries", "kmsdb")
+		open("hubrouter_ariesps", "didexchange")
+	})
+
+	args := []string{
+		"--" + fromFlagName, leveldb(source),
+		"--" + toFlagName, leveldb(target),
+		"--" + progressFileFlagName, progressFile,
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		out, err := execute(append(args, "--"+dryRunFlagName)...)
+		require.NoError(t, err)
+		require.Contains(t, out, "hubrouter_aries/kmsdb: 1 entries\n")
+		require.Contains(t, out, "4 stores and 4 entries to migrate\n")
+
+		_, err = os.Stat(target)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		out, err := execute(args...)
+		require.NoError(t, err)
+		require.Contains(t, out, "hubrouter_ariesps/didexchange: 1 entries\n")
+		require.Contains(t, out, "migrated 4 stores and 4 entries\n")
+
+		_, err = os.Stat(progressFile)
+		require.True(t, os.IsNotExist(err))
+
+		withStorage(t, target, func(open func(prefix, name string) []byte) {
+			require.Equal(t, []byte("hubrouter_aries/kmsdb"), open("hubrouter_aries", "kmsdb"))
+			require.Equal(t, []byte("hubrouter_txn/create-conn-req"), open("hubrouter_txn", "create-conn-req"))
+		})
+	})
+
+	t.Run("target isn't empty", func(t *testing.T) {
+		_, err := execute(args...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't empty")
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		other := filepath.Join(dir, "other")
+
+		resumeArgs := []string{
+			"--" + fromFlagName, leveldb(source),
+			"--" + toFlagName, leveldb(other),
+			"--" + progressFileFlagName, progressFile,
+		}
+
+		// the target has a store migrated before the interruption
+		withStorage(t, other, func(open func(prefix, name string) []byte) {
+			open("hubrouter_aries", "kmsdb")
+		})
+
+		require.NoError(t, ioutil.WriteFile(progressFile, []byte(`{"version":1,"fingerprint":"other"}`), 0o600))
+
+		_, err := execute(resumeArgs...)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is the one of a migration between other storage")
+
+		progress := fmt.Sprintf(`{"version":1,"fingerprint":%q,"stores":{"hubrouter_aries/kmsdb":1}}`,
+			migration.Fingerprint(leveldb(source), leveldb(source), leveldb(other), leveldb(other)))
+		require.NoError(t, ioutil.WriteFile(progressFile, []byte(progress), 0o600))
+
+		out, err := execute(resumeArgs...)
+		require.NoError(t, err)
+		require.Contains(t, out, "hubrouter_aries/kmsdb: 1 entries (migrated before)\n")
+		require.Contains(t, out, "migrated 4 stores and 4 entries\n")
+
+		withStorage(t, other, func(open func(prefix, name string) []byte) {
+			require.Equal(t, []byte("hubrouter_ariesps/didexchange"), open("hubrouter_ariesps", "didexchange"))
+		})
+	})
+
+	t.Run("invalid datasources", func(t *testing.T) {
+		_, err := execute("--"+fromFlagName, "mem://test", "--"+toFlagName, leveldb(target))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't migrate from or to the mem driver")
+
+		_, err = execute("--"+fromFlagName, leveldb(source), "--"+toFlagName, leveldb(source))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "can't migrate to the datasource migrated from")
+
+		_, err = execute("--"+fromFlagName, leveldb(source))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Neither to (command line flag) nor MEDIATOR_MIGRATE_TO")
+	})
+}
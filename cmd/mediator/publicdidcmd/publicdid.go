@@ -0,0 +1,117 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publicdidcmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/cmd/mediator/internal/adminclient"
+)
+
+const (
+	serviceEndpointFlagName  = "service-endpoint"
+	serviceEndpointFlagUsage = "New DIDComm endpoint of the public DID. Defaults to the endpoint the mediator is" +
+		" configured with. Alternatively, this can be set with the following environment variable: " +
+		serviceEndpointEnvKey
+	serviceEndpointEnvKey = "MEDIATOR_PUBLIC_DID_SERVICE_ENDPOINT"
+
+	rotateKeysFlagName  = "rotate-keys"
+	rotateKeysFlagUsage = "Replace the keys of the public DID. By default, the keys are only replaced if the key types" +
+		" the mediator is configured with changed."
+)
+
+const (
+	updatePath     = "/public-did/update"
+	recoverPath    = "/public-did/recover"
+	deactivatePath = "/public-did/deactivate"
+)
+
+// GetPublicDIDCmd returns the Cobra public-did command, which manages the public DID of a running mediator
+// through its admin endpoints.
+func GetPublicDIDCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "public-did",
+		Short: "Manage the public DID of the mediator",
+		Long:  "Update, recover or deactivate the public DID of a running mediator",
+	}
+
+	cmd.AddCommand(
+		newSubCmd("update", "Update the DIDComm endpoint and key types of the public DID", updatePath, true, true),
+		newSubCmd("recover", "Recover the public DID with new keys", recoverPath, true, false),
+		newSubCmd("deactivate", "Deactivate the public DID, a new one is created when the mediator restarts",
+			deactivatePath, false, false),
+	)
+
+	return cmd
+}
+
+func newSubCmd(use, short, path string, withEndpoint, withRotateKeys bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd, path, withEndpoint, withRotateKeys)
+		},
+	}
+
+	adminclient.AddFlags(cmd)
+
+	if withEndpoint {
+		cmd.Flags().StringP(serviceEndpointFlagName, "", "", serviceEndpointFlagUsage)
+	}
+
+	if withRotateKeys {
+		cmd.Flags().BoolP(rotateKeysFlagName, "", false, rotateKeysFlagUsage)
+	}
+
+	return cmd
+}
+
+func run(cmd *cobra.Command, path string, withEndpoint, withRotateKeys bool) error {
+	client, err := adminclient.New(cmd)
+	if err != nil {
+		return err
+	}
+
+	req := map[string]interface{}{}
+
+	if withEndpoint {
+		endpoint, e := cmdutils.GetUserSetVarFromString(cmd, serviceEndpointFlagName, serviceEndpointEnvKey, true)
+		if e != nil {
+			return e
+		}
+
+		if endpoint != "" {
+			req["serviceEndpoint"] = endpoint
+		}
+	}
+
+	if withRotateKeys {
+		rotateKeys, e := cmd.Flags().GetBool(rotateKeysFlagName)
+		if e != nil {
+			return e
+		}
+
+		if rotateKeys {
+			req["rotateKeys"] = true
+		}
+	}
+
+	respBytes, err := client.Do(http.MethodPost, path, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(respBytes))
+
+	return err
+}
@@ -0,0 +1,104 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publicdidcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/cmd/mediator/internal/adminclient"
+)
+
+func TestPublicDIDCmd(t *testing.T) {
+	var (
+		gotPath string
+		gotAuth string
+		gotReq  map[string]interface{}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotReq = map[string]interface{}{}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+
+		if r.URL.Path == deactivatePath {
+			rw.WriteHeader(http.StatusInternalServerError)
+			_, _ = rw.Write([]byte(`{"errMessage":"orb error"}`)) // nolint:errcheck // test
+
+			return
+		}
+
+		_, _ = rw.Write([]byte(`{"did":"did:orb:foo"}`)) // nolint:errcheck // test
+	}))
+	defer srv.Close()
+
+	execute := func(args ...string) (string, error) {
+		cmd := GetPublicDIDCmd()
+
+		out := &bytes.Buffer{}
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+
+		err := cmd.Execute()
+
+		return out.String(), err
+	}
+
+	t.Run("update", func(t *testing.T) {
+		out, err := execute("update", "--"+adminclient.URLFlagName, srv.URL+"/",
+			"--"+serviceEndpointFlagName, "https://example.com/didcomm", "--"+adminclient.TokenFlagName, "admin-secret")
+		require.NoError(t, err)
+		require.Contains(t, out, "did:orb:foo")
+		require.Equal(t, updatePath, gotPath)
+		require.Equal(t, "Bearer admin-secret", gotAuth)
+		require.Equal(t, map[string]interface{}{"serviceEndpoint": "https://example.com/didcomm"}, gotReq)
+
+		_, err = execute("update", "--"+adminclient.URLFlagName, srv.URL, "--"+rotateKeysFlagName)
+		require.NoError(t, err)
+		require.Empty(t, gotAuth)
+		require.Equal(t, map[string]interface{}{"rotateKeys": true}, gotReq)
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		_, err := execute("recover", "--"+adminclient.URLFlagName, srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, recoverPath, gotPath)
+		require.Empty(t, gotReq)
+	})
+
+	t.Run("deactivate error", func(t *testing.T) {
+		_, err := execute("deactivate", "--"+adminclient.URLFlagName, srv.URL)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 500")
+		require.Contains(t, err.Error(), "orb error")
+	})
+
+	t.Run("missing admin url", func(t *testing.T) {
+		_, err := execute("update")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Neither admin-url (command line flag) nor MEDIATOR_ADMIN_URL")
+	})
+
+	t.Run("invalid ca certs", func(t *testing.T) {
+		_, err := execute("update", "--"+adminclient.URLFlagName, srv.URL, "--"+adminclient.TLSCACertsFlagName, "/not/found")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get root CAs")
+	})
+
+	t.Run("unreachable mediator", func(t *testing.T) {
+		_, err := execute("deactivate", "--"+adminclient.URLFlagName, "http://127.0.0.1:1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "POST http://127.0.0.1:1/public-did/deactivate")
+	})
+}
@@ -0,0 +1,65 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// capabilities are the values supported by the start options.
+type capabilities struct {
+	KeyTypes                []string `json:"keyTypes"`
+	DefaultKeyType          string   `json:"defaultKeyType"`
+	KeyAgreementTypes       []string `json:"keyAgreementTypes"`
+	DefaultKeyAgreementType string   `json:"defaultKeyAgreementType"`
+	StorageDrivers          []string `json:"storageDrivers"`
+	MediaTypeProfiles       []string `json:"mediaTypeProfiles"`
+}
+
+// GetCapabilitiesCmd returns the Cobra capabilities command, which prints the key types, key agreement types,
+// storage drivers and media type profiles supported by the mediator.
+func GetCapabilitiesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "capabilities",
+		Short: "Print the capabilities of the mediator",
+		Long: "Print the key types, key agreement types and storage drivers supported by the start options, and the" +
+			" media type profiles of the mediator, as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := json.MarshalIndent(getCapabilities(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal capabilities : %w", err)
+			}
+
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+
+			return err
+		},
+	}
+}
+
+func getCapabilities() *capabilities {
+	drivers := make([]string, 0, len(supportedStorageProviders))
+
+	for driver := range supportedStorageProviders {
+		drivers = append(drivers, driver)
+	}
+
+	sort.Strings(drivers)
+
+	return &capabilities{
+		KeyTypes:                sortedNames(keyTypes),
+		DefaultKeyType:          defaultKeyType,
+		KeyAgreementTypes:       sortedNames(keyAgreementTypes),
+		DefaultKeyAgreementType: defaultKeyAgreementType,
+		StorageDrivers:          drivers,
+		MediaTypeProfiles:       mediaTypeProfiles,
+	}
+}
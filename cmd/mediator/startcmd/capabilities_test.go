@@ -0,0 +1,34 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilitiesCmd(t *testing.T) {
+	cmd := GetCapabilitiesCmd()
+	out := &bytes.Buffer{}
+
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+
+	caps := &capabilities{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), caps))
+
+	require.Contains(t, caps.KeyTypes, "bls12381g2")
+	require.Contains(t, caps.KeyTypes, caps.DefaultKeyType)
+	require.Equal(t, []string{"p256kw", "p384kw", "p521kw", "x25519kw"}, caps.KeyAgreementTypes)
+	require.Contains(t, caps.KeyAgreementTypes, caps.DefaultKeyAgreementType)
+	require.Equal(t, []string{"couchdb", "leveldb", "mem", "mongodb", "mysql", "postgresql"}, caps.StorageDrivers)
+	require.Equal(t, mediaTypeProfiles, caps.MediaTypeProfiles)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileFlagName  = "config-file"
+	configFileFlagUsage = "Path of a YAML or JSON file with the start options, named after the command line flags" +
+		" (ex: 'host-url: localhost:8080'). Command line flags and environment variables take precedence over the file." +
+		" Alternatively, this can be set with the following environment variable: " + configFileEnvKey
+	configFileEnvKey = "MEDIATOR_CONFIG_FILE"
+)
+
+type configOptionKind int
+
+const (
+	// stringOption is a scalar: string, number or boolean.
+	stringOption configOptionKind = iota
+	// listOption is a list of scalars, or a single scalar.
+	listOption
+	// keyValuesOption is a list of key=value scalars, or a map.
+	keyValuesOption
+)
+
+type configOption struct {
+	envKey string
+	kind   configOptionKind
+}
+
+// configOptions are the options of the config file, by flag name.
+// nolint:gochecknoglobals // config file schema
+var configOptions = map[string]configOption{
+	hostURLFlagName:                    {hostURLEnvKey, stringOption},
+	tlsSystemCertPoolFlagName:          {tlsSystemCertPoolEnvKey, stringOption},
+	tlsCACertsFlagName:                 {tlsCACertsEnvKey, listOption},
+	tlsServeCertPathFlagName:           {tlsServeCertPathEnvKey, stringOption},
+	tlsServeKeyPathFlagName:            {tlsServeKeyPathFlagEnvKey, stringOption},
+	datasourcePersistentFlagName:       {datasourcePersistentEnvKey, stringOption},
+	datasourceTransientFlagName:        {datasourceTransientEnvKey, stringOption},
+	datasourceTimeoutFlagName:          {datasourceTimeoutEnvKey, stringOption},
+	storageEncryptionKeyFileFlagName:   {storageEncryptionKeyFileEnvKey, stringOption},
+	storageEncryptionStoresFlagName:    {storageEncryptionStoresEnvKey, listOption},
+	secretLockKeyPathFlagName:          {secretLockKeyPathEnvKey, stringOption},
+	kmsURLFlagName:                     {kmsURLEnvKey, stringOption},
+	didCommHTTPHostFlagName:            {didCommHTTPHostEnvKey, stringOption},
+	didCommHTTPHostExternalFlagName:    {didCommHTTPHostExternalEnvKey, stringOption},
+	didCommWSHostFlagName:              {didCommWSHostEnvKey, stringOption},
+	didCommWSHostExternalFlagName:      {didCommWSHostExternalEnvKey, stringOption},
+	didCommHTTPPriorityFlagName:        {didCommHTTPPriorityEnvKey, stringOption},
+	didCommWSPriorityFlagName:          {didCommWSPriorityEnvKey, stringOption},
+	didCommHTTPAcceptFlagName:          {didCommHTTPAcceptEnvKey, listOption},
+	didCommWSAcceptFlagName:            {didCommWSAcceptEnvKey, listOption},
+	keyTypeFlagName:                    {keyTypeEnvKey, stringOption},
+	keyAgreementTypeFlagName:           {keyAgreementTypeEnvKey, stringOption},
+	connReqReplayWindowFlagName:        {connReqReplayWindowEnvKey, stringOption},
+	connReqDIDMethodsFlagName:          {connReqDIDMethodsEnvKey, listOption},
+	connReqMaxDIDDocSizeFlagName:       {connReqMaxDIDDocSizeEnvKey, stringOption},
+	publicDIDMethodFlagName:            {publicDIDMethodEnvKey, stringOption},
+	publicDIDWebDomainFlagName:         {publicDIDWebDomainEnvKey, stringOption},
+	publicDIDWebPathFlagName:           {publicDIDWebPathEnvKey, stringOption},
+	publicDIDDegradedStartupFlagName:   {publicDIDDegradedStartupEnvKey, stringOption},
+	publicDIDRegenerateOnDriftFlagName: {publicDIDRegenerateOnDriftEnvKey, stringOption},
+	orbDomainsFlagName:                 {orbDomainsEnvKey, listOption},
+	requestTokensFlagName:              {requestTokensEnvKey, keyValuesOption},
+	adminTokenFlagName:                 {adminTokenEnvKey, stringOption},
+	agentHTTPResolverFlagName:          {agentHTTPResolverEnvKey, listOption},
+	corsAllowedOriginsFlagName:         {corsAllowedOriginsEnvKey, listOption},
+	corsAllowedMethodsFlagName:         {corsAllowedMethodsEnvKey, listOption},
+	corsAllowedHeadersFlagName:         {corsAllowedHeadersEnvKey, listOption},
+	corsAllowCredentialsFlagName:       {corsAllowCredentialsEnvKey, stringOption},
+	corsMaxAgeFlagName:                 {corsMaxAgeEnvKey, stringOption},
+	corsAdminAllowedOriginsFlagName:    {corsAdminAllowedOriginsEnvKey, listOption},
+	corsAdminAllowedMethodsFlagName:    {corsAdminAllowedMethodsEnvKey, listOption},
+	corsAdminAllowedHeadersFlagName:    {corsAdminAllowedHeadersEnvKey, listOption},
+	corsAdminAllowCredentialsFlagName:  {corsAdminAllowCredentialsEnvKey, stringOption},
+	corsAdminMaxAgeFlagName:            {corsAdminMaxAgeEnvKey, stringOption},
+	outboundProxyFlagName:              {outboundProxyEnvKey, stringOption},
+	outboundNoProxyFlagName:            {outboundNoProxyEnvKey, listOption},
+	outboundHostTokensFlagName:         {outboundHostTokensEnvKey, keyValuesOption},
+	outboundTimeoutFlagName:            {outboundTimeoutEnvKey, stringOption},
+	logLevelFlagName:                   {logLevelEnvKey, stringOption},
+	shutdownTimeoutFlagName:            {shutdownTimeoutEnvKey, stringOption},
+}
+
+// applyConfigFile sets the flags of the command that are set neither on the command line nor in the
+// environment from the config file, if any.
+func applyConfigFile(cmd *cobra.Command) error {
+	path, err := cmdutils.GetUserSetVarFromString(cmd, configFileFlagName, configFileEnvKey, true)
+	if err != nil || path == "" {
+		return err
+	}
+
+	options, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range options {
+		// the offline commands only have some of the options of the start command
+		if cmd.Flags().Lookup(name) == nil || cmd.Flags().Changed(name) {
+			continue
+		}
+
+		if _, isSet := os.LookupEnv(configOptions[name].envKey); isSet {
+			continue
+		}
+
+		for _, value := range values {
+			err = cmd.Flags().Set(name, value)
+			if err != nil {
+				return fmt.Errorf("config file %s: option '%s': %w", path, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadConfigFile reads and validates a YAML or JSON config file. Returns the values of the options by name.
+func loadConfigFile(path string) (map[string][]string, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec // path set by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read config file : %w", err)
+	}
+
+	raw := map[string]interface{}{}
+
+	// JSON is valid YAML
+	err = yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s : %w", path, err)
+	}
+
+	options := map[string][]string{}
+
+	var problems []string
+
+	for _, name := range sortedKeys(raw) {
+		opt, ok := configOptions[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown option '%s'", name))
+
+			continue
+		}
+
+		values, e := configValues(raw[name], opt.kind)
+		if e != nil {
+			problems = append(problems, fmt.Sprintf("option '%s': %s", name, e))
+
+			continue
+		}
+
+		options[name] = values
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config file %s: %s", path, strings.Join(problems, "; "))
+	}
+
+	return options, nil
+}
+
+func configValues(value interface{}, kind configOptionKind) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		if kind == stringOption {
+			return nil, errors.New("expected a single value, got a list")
+		}
+
+		values := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("list item: %w", err)
+			}
+
+			values = append(values, s)
+		}
+
+		return values, nil
+	case map[string]interface{}:
+		if kind != keyValuesOption {
+			return nil, errors.New("expected a value, got a map")
+		}
+
+		values := make([]string, 0, len(v))
+
+		for _, k := range sortedKeys(v) {
+			s, err := configScalar(v[k])
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", k, err)
+			}
+
+			values = append(values, k+"="+s)
+		}
+
+		return values, nil
+	default:
+		s, err := configScalar(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{s}, nil
+	}
+}
+
+func configScalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return "", errors.New("empty value")
+		}
+
+		return v, nil
+	case int, float64, bool:
+		return fmt.Sprint(v), nil
+	case nil:
+		return "", errors.New("empty value")
+	default:
+		return "", fmt.Errorf("expected a string, number or boolean, got %T", v)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
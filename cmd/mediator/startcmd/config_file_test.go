@@ -0,0 +1,201 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+host-url: localhost:8080
+conn-req-max-did-doc-size: 32768
+public-did-degraded-startup: true
+orb-domains:
+  - orb1.example.com
+  - orb2.example.com
+http-resolver-url: orb@https://orb.example.com
+request-tokens:
+  sidetreeToken: abc
+  orbToken: def
+`)
+
+		options, err := loadConfigFile(path)
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{
+			hostURLFlagName:                  {"localhost:8080"},
+			connReqMaxDIDDocSizeFlagName:     {"32768"},
+			publicDIDDegradedStartupFlagName: {"true"},
+			orbDomainsFlagName:               {"orb1.example.com", "orb2.example.com"},
+			agentHTTPResolverFlagName:        {"orb@https://orb.example.com"},
+			requestTokensFlagName:            {"orbToken=def", "sidetreeToken=abc"},
+		}, options)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{
+	"host-url": "localhost:8080",
+	"request-tokens": ["sidetreeToken=abc"]
+}`)
+
+		options, err := loadConfigFile(path)
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{
+			hostURLFlagName:       {"localhost:8080"},
+			requestTokensFlagName: {"sidetreeToken=abc"},
+		}, options)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `
+host-url:
+  - localhost:8080
+log-level:
+unknown: value
+orb-domains:
+  - a: b
+key-type:
+  x: y
+`)
+
+		_, err := loadConfigFile(path)
+		require.Error(t, err)
+		require.Equal(t, "invalid config file "+path+": "+
+			"option 'host-url': expected a single value, got a list; "+
+			"option 'key-type': expected a value, got a map; "+
+			"option 'log-level': empty value; "+
+			"option 'orb-domains': list item: expected a string, number or boolean, got map[string]interface {}; "+
+			"unknown option 'unknown'", err.Error())
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", "host-url: [")
+
+		_, err := loadConfigFile(path)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse config file "+path)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read config file")
+	})
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+didcomm-http-host: localhost:8081
+didcomm-ws-host: localhost:8082
+log-level: DEBUG
+orb-domains: [orb1.example.com, orb2.example.com]
+`)
+
+	t.Run("precedence: flag > env > file", func(t *testing.T) {
+		require.NoError(t, os.Setenv(didCommWSHostEnvKey, "localhost:9092"))
+
+		defer func() {
+			require.NoError(t, os.Unsetenv(didCommWSHostEnvKey))
+		}()
+
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags([]string{
+			"--" + configFileFlagName, path,
+			"--" + didCommHTTPHostFlagName, "localhost:9091",
+		}))
+
+		require.NoError(t, applyConfigFile(startCmd))
+
+		httpHost, err := startCmd.Flags().GetString(didCommHTTPHostFlagName)
+		require.NoError(t, err)
+		require.Equal(t, "localhost:9091", httpHost)
+
+		wsHost, err := startCmd.Flags().GetString(didCommWSHostFlagName)
+		require.NoError(t, err)
+		require.Empty(t, wsHost)
+
+		logLevel, err := startCmd.Flags().GetString(logLevelFlagName)
+		require.NoError(t, err)
+		require.Equal(t, "DEBUG", logLevel)
+
+		orbDomains, err := startCmd.Flags().GetStringArray(orbDomainsFlagName)
+		require.NoError(t, err)
+		require.Equal(t, []string{"orb1.example.com", "orb2.example.com"}, orbDomains)
+	})
+
+	t.Run("config file from env", func(t *testing.T) {
+		require.NoError(t, os.Setenv(configFileEnvKey, path))
+
+		defer func() {
+			require.NoError(t, os.Unsetenv(configFileEnvKey))
+		}()
+
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(nil))
+
+		require.NoError(t, applyConfigFile(startCmd))
+
+		httpHost, err := startCmd.Flags().GetString(didCommHTTPHostFlagName)
+		require.NoError(t, err)
+		require.Equal(t, "localhost:8081", httpHost)
+	})
+
+	t.Run("no config file", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(nil))
+
+		require.NoError(t, applyConfigFile(startCmd))
+		require.False(t, startCmd.Flags().Changed(didCommHTTPHostFlagName))
+	})
+
+	t.Run("start from config file", func(t *testing.T) {
+		orbDomain, closeOrb := dummySidetree(t)
+		defer closeOrb()
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{
+			"--" + configFileFlagName, writeConfigFile(t, "config.yaml", `
+host-url: localhost:8080
+didcomm-http-host: `+randomURL(t)+`
+didcomm-ws-host: `+randomURL(t)+`
+dsn-p: mem://tests
+dsn-t: mem://tests
+orb-domains: `+orbDomain+`
+http-resolver-url: orb@`+orbDomain+`
+`),
+		})
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("invalid config file", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{
+			"--" + configFileFlagName, writeConfigFile(t, "config.yaml", "hosturl: localhost:8080"),
+		})
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unknown option 'hosturl'")
+	})
+}
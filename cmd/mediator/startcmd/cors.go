@@ -0,0 +1,345 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+// CORS config of the public routes: health check, readiness, invitations and did:web public DID doc.
+const (
+	corsAllowedOriginsFlagName  = "cors-allowed-origins"
+	corsAllowedOriginsEnvKey    = "MEDIATOR_CORS_ALLOWED_ORIGINS"
+	corsAllowedOriginsFlagUsage = "Origins (ex: https://wallet.example.com, https://*.example.com) allowed to call the" +
+		" public routes (health check, readiness, invitations and did:web public DID doc) from a browser." +
+		" Use * to allow any origin and none to disallow cross-origin requests. Defaults to *." +
+		" Alternatively, this can be set with the following environment variable: " + corsAllowedOriginsEnvKey
+
+	corsAllowedMethodsFlagName  = "cors-allowed-methods"
+	corsAllowedMethodsEnvKey    = "MEDIATOR_CORS_ALLOWED_METHODS"
+	corsAllowedMethodsFlagUsage = "HTTP methods allowed in cross-origin requests to the public routes." +
+		" Defaults to GET, POST and HEAD." +
+		" Alternatively, this can be set with the following environment variable: " + corsAllowedMethodsEnvKey
+
+	corsAllowedHeadersFlagName  = "cors-allowed-headers"
+	corsAllowedHeadersEnvKey    = "MEDIATOR_CORS_ALLOWED_HEADERS"
+	corsAllowedHeadersFlagUsage = "Request headers allowed in cross-origin requests to the public routes, * for any." +
+		" Defaults to Accept, Content-Type and X-Requested-With." +
+		" Alternatively, this can be set with the following environment variable: " + corsAllowedHeadersEnvKey
+
+	corsAllowCredentialsFlagName  = "cors-allow-credentials"
+	corsAllowCredentialsEnvKey    = "MEDIATOR_CORS_ALLOW_CREDENTIALS"
+	corsAllowCredentialsFlagUsage = "Allow cross-origin requests to the public routes with credentials (cookies," +
+		" authorization headers or client certificates). Requires explicit origins. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + corsAllowCredentialsEnvKey
+
+	corsMaxAgeFlagName  = "cors-max-age"
+	corsMaxAgeEnvKey    = "MEDIATOR_CORS_MAX_AGE"
+	corsMaxAgeFlagUsage = "Time (ex: 10m) browsers can cache the preflight responses of the public routes." +
+		" Defaults to no caching." +
+		" Alternatively, this can be set with the following environment variable: " + corsMaxAgeEnvKey
+)
+
+// CORS config of the admin routes: KMS, public DID, reload, connections and mediation.
+const (
+	corsAdminAllowedOriginsFlagName  = "cors-admin-allowed-origins"
+	corsAdminAllowedOriginsEnvKey    = "MEDIATOR_CORS_ADMIN_ALLOWED_ORIGINS"
+	corsAdminAllowedOriginsFlagUsage = "Origins allowed to call the admin routes (KMS, public DID, reload," +
+		" connections and mediation) from a browser, * for any and none to disallow cross-origin requests." +
+		" Defaults to " + corsAllowedOriginsFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + corsAdminAllowedOriginsEnvKey
+
+	corsAdminAllowedMethodsFlagName  = "cors-admin-allowed-methods"
+	corsAdminAllowedMethodsEnvKey    = "MEDIATOR_CORS_ADMIN_ALLOWED_METHODS"
+	corsAdminAllowedMethodsFlagUsage = "HTTP methods allowed in cross-origin requests to the admin routes." +
+		" Defaults to " + corsAllowedMethodsFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + corsAdminAllowedMethodsEnvKey
+
+	corsAdminAllowedHeadersFlagName  = "cors-admin-allowed-headers"
+	corsAdminAllowedHeadersEnvKey    = "MEDIATOR_CORS_ADMIN_ALLOWED_HEADERS"
+	corsAdminAllowedHeadersFlagUsage = "Request headers allowed in cross-origin requests to the admin routes." +
+		" Defaults to " + corsAllowedHeadersFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + corsAdminAllowedHeadersEnvKey
+
+	corsAdminAllowCredentialsFlagName  = "cors-admin-allow-credentials"
+	corsAdminAllowCredentialsEnvKey    = "MEDIATOR_CORS_ADMIN_ALLOW_CREDENTIALS"
+	corsAdminAllowCredentialsFlagUsage = "Allow cross-origin requests to the admin routes with credentials." +
+		" Defaults to " + corsAllowCredentialsFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + corsAdminAllowCredentialsEnvKey
+
+	corsAdminMaxAgeFlagName  = "cors-admin-max-age"
+	corsAdminMaxAgeEnvKey    = "MEDIATOR_CORS_ADMIN_MAX_AGE"
+	corsAdminMaxAgeFlagUsage = "Time browsers can cache the preflight responses of the admin routes." +
+		" Defaults to " + corsMaxAgeFlagName + "." +
+		" Alternatively, this can be set with the following environment variable: " + corsAdminMaxAgeEnvKey
+)
+
+const (
+	corsAnyOrigin = "*"
+	corsNoOrigin  = "none"
+)
+
+// nolint:gochecknoglobals // allowed values
+var corsMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// corsParameters are the CORS policies of the route groups.
+type corsParameters struct {
+	public *corsPolicy
+	admin  *corsPolicy
+}
+
+// corsPolicy is the CORS policy of a route group.
+type corsPolicy struct {
+	// cors answers the preflight requests and adds the CORS headers. Nil if cross-origin requests aren't allowed.
+	cors *cors.Cors
+}
+
+// route registers the handler of a route of the group. The preflight requests of its path are answered.
+func (p *corsPolicy) route(router *mux.Router, path, method string, handler http.HandlerFunc) {
+	if p.cors == nil {
+		router.HandleFunc(path, handler).Methods(method)
+
+		return
+	}
+
+	router.Handle(path, p.cors.Handler(handler)).Methods(method, http.MethodOptions)
+}
+
+// corsSettings are the CORS options of a route group, as set.
+type corsSettings struct {
+	origins     []string
+	methods     []string
+	headers     []string
+	credentials string
+	maxAge      string
+}
+
+// corsFlag is the flag name and environment variable of a CORS option.
+type corsFlag struct {
+	name, envKey string
+}
+
+// corsFlags are the flags of the CORS options of a route group.
+type corsFlags struct {
+	origins, methods, headers, credentials, maxAge corsFlag
+}
+
+// nolint:gochecknoglobals // flag names
+var (
+	corsPublicFlags = corsFlags{
+		origins:     corsFlag{corsAllowedOriginsFlagName, corsAllowedOriginsEnvKey},
+		methods:     corsFlag{corsAllowedMethodsFlagName, corsAllowedMethodsEnvKey},
+		headers:     corsFlag{corsAllowedHeadersFlagName, corsAllowedHeadersEnvKey},
+		credentials: corsFlag{corsAllowCredentialsFlagName, corsAllowCredentialsEnvKey},
+		maxAge:      corsFlag{corsMaxAgeFlagName, corsMaxAgeEnvKey},
+	}
+
+	corsAdminFlags = corsFlags{
+		origins:     corsFlag{corsAdminAllowedOriginsFlagName, corsAdminAllowedOriginsEnvKey},
+		methods:     corsFlag{corsAdminAllowedMethodsFlagName, corsAdminAllowedMethodsEnvKey},
+		headers:     corsFlag{corsAdminAllowedHeadersFlagName, corsAdminAllowedHeadersEnvKey},
+		credentials: corsFlag{corsAdminAllowCredentialsFlagName, corsAdminAllowCredentialsEnvKey},
+		maxAge:      corsFlag{corsAdminMaxAgeFlagName, corsAdminMaxAgeEnvKey},
+	}
+)
+
+func addCORSFlags(startCmd *cobra.Command) {
+	startCmd.Flags().StringArrayP(corsAllowedOriginsFlagName, "", []string{}, corsAllowedOriginsFlagUsage)
+	startCmd.Flags().StringArrayP(corsAllowedMethodsFlagName, "", []string{}, corsAllowedMethodsFlagUsage)
+	startCmd.Flags().StringArrayP(corsAllowedHeadersFlagName, "", []string{}, corsAllowedHeadersFlagUsage)
+	startCmd.Flags().StringP(corsAllowCredentialsFlagName, "", "", corsAllowCredentialsFlagUsage)
+	startCmd.Flags().StringP(corsMaxAgeFlagName, "", "", corsMaxAgeFlagUsage)
+	startCmd.Flags().StringArrayP(corsAdminAllowedOriginsFlagName, "", []string{}, corsAdminAllowedOriginsFlagUsage)
+	startCmd.Flags().StringArrayP(corsAdminAllowedMethodsFlagName, "", []string{}, corsAdminAllowedMethodsFlagUsage)
+	startCmd.Flags().StringArrayP(corsAdminAllowedHeadersFlagName, "", []string{}, corsAdminAllowedHeadersFlagUsage)
+	startCmd.Flags().StringP(corsAdminAllowCredentialsFlagName, "", "", corsAdminAllowCredentialsFlagUsage)
+	startCmd.Flags().StringP(corsAdminMaxAgeFlagName, "", "", corsAdminMaxAgeFlagUsage)
+}
+
+// getCORSParams reads the CORS policies. The options of the admin routes that aren't set are the ones of the public
+// routes.
+func getCORSParams(cmd *cobra.Command) (*corsParameters, error) {
+	public, err := getCORSSettings(cmd, &corsPublicFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := getCORSSettings(cmd, &corsAdminFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	admin.setDefaults(public)
+
+	publicPolicy, err := public.policy(&corsPublicFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	adminPolicy, err := admin.policy(&corsAdminFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corsParameters{public: publicPolicy, admin: adminPolicy}, nil
+}
+
+func getCORSSettings(cmd *cobra.Command, flags *corsFlags) (*corsSettings, error) {
+	s := &corsSettings{}
+
+	var err error
+
+	for _, option := range []struct {
+		flag   corsFlag
+		values *[]string
+	}{
+		{flags.origins, &s.origins},
+		{flags.methods, &s.methods},
+		{flags.headers, &s.headers},
+	} {
+		*option.values, err = cmdutils.GetUserSetVarFromArrayString(cmd, option.flag.name, option.flag.envKey, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.credentials, err = cmdutils.GetUserSetVarFromString(cmd, flags.credentials.name, flags.credentials.envKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.maxAge, err = cmdutils.GetUserSetVarFromString(cmd, flags.maxAge.name, flags.maxAge.envKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// setDefaults sets the options that aren't set to the ones of other settings.
+func (s *corsSettings) setDefaults(defaults *corsSettings) {
+	if len(s.origins) == 0 {
+		s.origins = defaults.origins
+	}
+
+	if len(s.methods) == 0 {
+		s.methods = defaults.methods
+	}
+
+	if len(s.headers) == 0 {
+		s.headers = defaults.headers
+	}
+
+	if s.credentials == "" {
+		s.credentials = defaults.credentials
+	}
+
+	if s.maxAge == "" {
+		s.maxAge = defaults.maxAge
+	}
+}
+
+func (s *corsSettings) policy(flags *corsFlags) (*corsPolicy, error) {
+	origins, err := corsOrigins(flags.origins.name, s.origins)
+	if err != nil {
+		return nil, err
+	}
+
+	if origins == nil {
+		return &corsPolicy{}, nil
+	}
+
+	opts := cors.Options{AllowedOrigins: origins, AllowedHeaders: s.headers}
+
+	for _, m := range s.methods {
+		method := strings.ToUpper(m)
+
+		if !isCORSMethod(method) {
+			return nil, fmt.Errorf("invalid %s '%s': must be one of [%s]", flags.methods.name, m,
+				strings.Join(corsMethods, ", "))
+		}
+
+		opts.AllowedMethods = append(opts.AllowedMethods, method)
+	}
+
+	if s.credentials != "" {
+		opts.AllowCredentials, err = strconv.ParseBool(s.credentials)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s: %w", flags.credentials.name, s.credentials, err)
+		}
+	}
+
+	// with credentials, any origin would let any site act with the credentials of the users
+	if opts.AllowCredentials && origins[0] == corsAnyOrigin {
+		return nil, fmt.Errorf("%s requires explicit %s", flags.credentials.name, flags.origins.name)
+	}
+
+	if s.maxAge != "" {
+		d, e := time.ParseDuration(s.maxAge)
+		if e != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s %s: must be a positive duration", flags.maxAge.name, s.maxAge)
+		}
+
+		opts.MaxAge = int(d / time.Second)
+	}
+
+	return &corsPolicy{cors: cors.New(opts)}, nil
+}
+
+// corsOrigins validates the allowed origins. Returns nil if cross-origin requests aren't allowed.
+func corsOrigins(flagName string, origins []string) ([]string, error) {
+	if len(origins) == 0 {
+		return []string{corsAnyOrigin}, nil
+	}
+
+	for _, origin := range origins {
+		switch origin {
+		case corsAnyOrigin:
+			if len(origins) > 1 {
+				return nil, fmt.Errorf("invalid %s: * can't be used with other origins", flagName)
+			}
+		case corsNoOrigin:
+			if len(origins) > 1 {
+				return nil, fmt.Errorf("invalid %s: none can't be used with other origins", flagName)
+			}
+
+			return nil, nil
+		default:
+			// an origin is a scheme and a host, with a single wildcard allowed in the host
+			u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+				origin != u.Scheme+"://"+strings.Replace(u.Host, "wildcard", "*", 1) {
+				return nil, fmt.Errorf("invalid %s '%s': must be *, none or an origin, ex: https://wallet.example.com",
+					flagName, origin)
+			}
+		}
+	}
+
+	return origins, nil
+}
+
+func isCORSMethod(method string) bool {
+	for _, m := range corsMethods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCORSParams(t *testing.T) {
+	parse := func(args ...string) (*corsParameters, error) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(args))
+
+		return getCORSParams(startCmd)
+	}
+
+	// preflight sends a preflight request from the origin to a route of the policy, and returns the response.
+	preflight := func(p *corsPolicy, origin, method string) *http.Response {
+		router := mux.NewRouter()
+		p.route(router, "/test", method, func(w http.ResponseWriter, _ *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w.Result()
+	}
+
+	allowedOrigin := func(p *corsPolicy, origin, method string) string {
+		resp := preflight(p, origin, method)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.Header.Get("Access-Control-Allow-Origin")
+	}
+
+	t.Run("defaults: any origin for GET and POST on all the routes", func(t *testing.T) {
+		params, err := parse()
+		require.NoError(t, err)
+
+		for _, p := range []*corsPolicy{params.public, params.admin} {
+			require.Equal(t, "*", allowedOrigin(p, "https://wallet.example.com", http.MethodPost))
+			require.Empty(t, allowedOrigin(p, "https://wallet.example.com", http.MethodDelete))
+		}
+	})
+
+	t.Run("policy per route group", func(t *testing.T) {
+		params, err := parse("--"+corsAllowedOriginsFlagName, "https://wallet.example.com",
+			"--"+corsAllowedMethodsFlagName, "get", "--"+corsAllowCredentialsFlagName, "true",
+			"--"+corsMaxAgeFlagName, "10m",
+			"--"+corsAdminAllowedOriginsFlagName, "https://*.ops.example.com",
+			"--"+corsAdminAllowedMethodsFlagName, "DELETE")
+		require.NoError(t, err)
+
+		resp := preflight(params.public, "https://wallet.example.com", http.MethodGet)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, "https://wallet.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		require.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+		require.Empty(t, allowedOrigin(params.public, "https://other.example.com", http.MethodGet))
+
+		// the admin routes have the credentials and max age of the public routes
+		resp = preflight(params.admin, "https://console.ops.example.com", http.MethodDelete)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, "https://console.ops.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+		require.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+		require.Empty(t, allowedOrigin(params.admin, "https://wallet.example.com", http.MethodDelete))
+	})
+
+	t.Run("cross-origin requests disallowed", func(t *testing.T) {
+		params, err := parse("--"+corsAdminAllowedOriginsFlagName, "none")
+		require.NoError(t, err)
+		require.Nil(t, params.admin.cors)
+
+		resp := preflight(params.admin, "https://wallet.example.com", http.MethodPost)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+		require.Equal(t, "*", allowedOrigin(params.public, "https://wallet.example.com", http.MethodPost))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for _, tc := range []struct {
+			args   []string
+			errMsg string
+		}{
+			{
+				args:   []string{"--" + corsAllowedOriginsFlagName, "wallet.example.com"},
+				errMsg: "invalid cors-allowed-origins 'wallet.example.com': must be *, none or an origin",
+			},
+			{
+				args:   []string{"--" + corsAdminAllowedOriginsFlagName, "https://ops.example.com/admin"},
+				errMsg: "invalid cors-admin-allowed-origins 'https://ops.example.com/admin'",
+			},
+			{
+				args: []string{
+					"--" + corsAllowedOriginsFlagName, "none", "--" + corsAllowedOriginsFlagName, "https://example.com",
+				},
+				errMsg: "invalid cors-allowed-origins: none can't be used with other origins",
+			},
+			{
+				args:   []string{"--" + corsAllowedMethodsFlagName, "CONNECT"},
+				errMsg: "invalid cors-allowed-methods 'CONNECT'",
+			},
+			{
+				args:   []string{"--" + corsAdminAllowCredentialsFlagName, "maybe"},
+				errMsg: "invalid cors-admin-allow-credentials maybe",
+			},
+			{
+				args:   []string{"--" + corsAllowCredentialsFlagName, "true"},
+				errMsg: "cors-allow-credentials requires explicit cors-allowed-origins",
+			},
+			{
+				args:   []string{"--" + corsMaxAgeFlagName, "-1s"},
+				errMsg: "invalid cors-max-age -1s: must be a positive duration",
+			},
+		} {
+			_, err := parse(tc.args...)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+// DIDComm endpoints config.
+const (
+	didCommHTTPPriorityFlagName  = "didcomm-http-priority"
+	didCommHTTPPriorityEnvKey    = "MEDIATOR_DIDCOMM_HTTP_PRIORITY"
+	didCommHTTPPriorityFlagUsage = "Priority of the DIDComm HTTP external endpoint in the public DID, the OOB v1" +
+		" invitations and the blinded routing peer DIDs. The endpoints are listed by priority, the lowest first." +
+		" Defaults to 1." +
+		" Alternatively, this can be set with the following environment variable: " + didCommHTTPPriorityEnvKey
+
+	didCommWSPriorityFlagName  = "didcomm-ws-priority"
+	didCommWSPriorityEnvKey    = "MEDIATOR_DIDCOMM_WS_PRIORITY"
+	didCommWSPriorityFlagUsage = "Priority of the DIDComm WebSocket external endpoint in the public DID, the OOB v1" +
+		" invitations and the blinded routing peer DIDs. The endpoints are listed by priority, the lowest first." +
+		" Defaults to 0." +
+		" Alternatively, this can be set with the following environment variable: " + didCommWSPriorityEnvKey
+
+	didCommHTTPAcceptFlagName  = "didcomm-http-accept"
+	didCommHTTPAcceptEnvKey    = "MEDIATOR_DIDCOMM_HTTP_ACCEPT"
+	didCommHTTPAcceptFlagUsage = "Media type profiles accepted at the DIDComm HTTP external endpoint, advertised with" +
+		" it. Possible values are the media type profiles of the mediator: [" + mediaTypeProfilesUsage + "]." +
+		" Defaults to the profiles of the invitation or DID the endpoint is advertised in." +
+		" Alternatively, this can be set with the following environment variable: " + didCommHTTPAcceptEnvKey
+
+	didCommWSAcceptFlagName  = "didcomm-ws-accept"
+	didCommWSAcceptEnvKey    = "MEDIATOR_DIDCOMM_WS_ACCEPT"
+	didCommWSAcceptFlagUsage = "Media type profiles accepted at the DIDComm WebSocket external endpoint, advertised" +
+		" with it. Possible values are the media type profiles of the mediator: [" + mediaTypeProfilesUsage + "]." +
+		" Defaults to the profiles of the invitation or DID the endpoint is advertised in." +
+		" Alternatively, this can be set with the following environment variable: " + didCommWSAcceptEnvKey
+
+	mediaTypeProfilesUsage = "didcomm/aip2;env=rfc587, didcomm/v2, didcomm/aip2;env=rfc19, didcomm/aip1"
+
+	didCommHTTPPriorityDefault = 1
+	didCommWSPriorityDefault   = 0
+)
+
+// getDIDCommEndpoints returns the DIDComm endpoints of the router: its HTTP and WebSocket external hosts, by priority.
+func getDIDCommEndpoints(cmd *cobra.Command, httpHost, wsHost string) ([]hubaries.DIDCommEndpoint, error) {
+	httpEndpoint, err := getDIDCommEndpoint(cmd, httpHost, didCommHTTPPriorityFlagName, didCommHTTPPriorityEnvKey,
+		didCommHTTPPriorityDefault, didCommHTTPAcceptFlagName, didCommHTTPAcceptEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wsEndpoint, err := getDIDCommEndpoint(cmd, wsHost, didCommWSPriorityFlagName, didCommWSPriorityEnvKey,
+		didCommWSPriorityDefault, didCommWSAcceptFlagName, didCommWSAcceptEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// on equal priorities, the WebSocket endpoint comes first, as it was the only endpoint of the public DID
+	return hubaries.SortedEndpoints([]hubaries.DIDCommEndpoint{*wsEndpoint, *httpEndpoint}), nil
+}
+
+func getDIDCommEndpoint(cmd *cobra.Command, host, priorityFlagName, priorityEnvKey string, priorityDefault uint,
+	acceptFlagName, acceptEnvKey string) (*hubaries.DIDCommEndpoint, error) {
+	endpoint := &hubaries.DIDCommEndpoint{URI: host, Priority: priorityDefault}
+
+	priority, err := cmdutils.GetUserSetVarFromString(cmd, priorityFlagName, priorityEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if priority != "" {
+		p, e := strconv.ParseUint(priority, 10, 32)
+		if e != nil {
+			return nil, fmt.Errorf("invalid %s %s: must be a non-negative number", priorityFlagName, priority)
+		}
+
+		endpoint.Priority = uint(p)
+	}
+
+	endpoint.Accept, err = cmdutils.GetUserSetVarFromArrayString(cmd, acceptFlagName, acceptEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range endpoint.Accept {
+		if !isMediaTypeProfile(profile) {
+			return nil, fmt.Errorf("invalid %s '%s': must be one of [%s]", acceptFlagName, profile,
+				strings.Join(mediaTypeProfiles, ", "))
+		}
+	}
+
+	return endpoint, nil
+}
+
+func isMediaTypeProfile(profile string) bool {
+	for _, p := range mediaTypeProfiles {
+		if p == profile {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+func TestGetDIDCommEndpoints(t *testing.T) {
+	const (
+		httpHost = "https://example.com"
+		wsHost   = "wss://example.com"
+	)
+
+	parse := func(args ...string) ([]hubaries.DIDCommEndpoint, error) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(args))
+
+		return getDIDCommEndpoints(startCmd, httpHost, wsHost)
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		endpoints, err := parse()
+		require.NoError(t, err)
+		require.Equal(t, []hubaries.DIDCommEndpoint{
+			{URI: wsHost, Accept: []string{}, Priority: 0},
+			{URI: httpHost, Accept: []string{}, Priority: 1},
+		}, endpoints)
+	})
+
+	t.Run("priorities and accept", func(t *testing.T) {
+		endpoints, err := parse("--"+didCommHTTPPriorityFlagName, "0", "--"+didCommWSPriorityFlagName, "5",
+			"--"+didCommWSAcceptFlagName, "didcomm/v2", "--"+didCommHTTPAcceptFlagName, "didcomm/aip1",
+			"--"+didCommHTTPAcceptFlagName, "didcomm/aip2;env=rfc19")
+		require.NoError(t, err)
+		require.Equal(t, []hubaries.DIDCommEndpoint{
+			{URI: httpHost, Accept: []string{"didcomm/aip1", "didcomm/aip2;env=rfc19"}, Priority: 0},
+			{URI: wsHost, Accept: []string{"didcomm/v2"}, Priority: 5},
+		}, endpoints)
+	})
+
+	t.Run("invalid priority", func(t *testing.T) {
+		_, err := parse("--"+didCommWSPriorityFlagName, "-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid didcomm-ws-priority -1: must be a non-negative number")
+	})
+
+	t.Run("invalid accept", func(t *testing.T) {
+		_, err := parse("--"+didCommHTTPAcceptFlagName, "didcomm/v3")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid didcomm-http-accept 'didcomm/v3'")
+	})
+}
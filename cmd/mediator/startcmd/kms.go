@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	webcrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+)
+
+// KMS config.
+const (
+	secretLockKeyPathFlagName  = "secret-lock-key-path"
+	secretLockKeyPathFlagUsage = "Path of a file holding the base64URL encoded 32 byte master key encrypting the" +
+		" private keys of the local KMS. The key itself can be set with the " + secretLockKeyEnvKey +
+		" environment variable instead. Without a master key, the private keys are stored unencrypted." +
+		" Use 'mediator kms rewrap' to encrypt the existing keys when introducing or rotating the master key." +
+		" Alternatively, this can be set with the following environment variable: " + secretLockKeyPathEnvKey
+	secretLockKeyPathEnvKey = "MEDIATOR_SECRET_LOCK_KEY_PATH"
+	secretLockKeyEnvKey     = "MEDIATOR_SECRET_LOCK_KEY"
+
+	kmsURLFlagName  = "kms-url"
+	kmsURLFlagUsage = "Keystore URL on an aries KMS REST server (ex: https://kms.example.com/v1/keystores/<id>)." +
+		" The private keys are created and used by the remote KMS rather than stored by the mediator." +
+		" Requests are authorized with the '" + kmsTokenName + "' request token, if any." +
+		" Alternatively, this can be set with the following environment variable: " + kmsURLEnvKey
+	kmsURLEnvKey = "MEDIATOR_KMS_URL"
+
+	kmsTokenName = "kmsToken"
+)
+
+type kmsParameters struct {
+	// masterKey encrypts the private keys of the local KMS, they're stored unencrypted without it.
+	masterKey []byte
+	// remoteURL is the keystore URL of the remote KMS, the local KMS is used without it.
+	remoteURL string
+}
+
+func getKMSParams(cmd *cobra.Command) (*kmsParameters, error) {
+	masterKey, err := getMasterKey(cmd, secretLockKeyPathFlagName, secretLockKeyPathEnvKey, secretLockKeyEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteURL, err := cmdutils.GetUserSetVarFromString(cmd, kmsURLFlagName, kmsURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if masterKey != nil && remoteURL != "" {
+		return nil, fmt.Errorf("%s is not used with %s, the keys are stored by the remote KMS",
+			secretLockKeyPathFlagName, kmsURLFlagName)
+	}
+
+	return &kmsParameters{masterKey: masterKey, remoteURL: remoteURL}, nil
+}
+
+// getMasterKey returns the master key read from the file set with the flag, or from the key environment variable.
+// Returns nil if neither is set.
+func getMasterKey(cmd *cobra.Command, pathFlagName, pathEnvKey, keyEnvKey string) ([]byte, error) {
+	path, err := cmdutils.GetUserSetVarFromString(cmd, pathFlagName, pathEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, isSet := os.LookupEnv(keyEnvKey)
+
+	switch {
+	case path != "" && isSet:
+		return nil, fmt.Errorf("set either %s or %s", pathFlagName, keyEnvKey)
+	case path != "":
+		return hubaries.ReadMasterKey(path)
+	case isSet:
+		key, e := hubaries.ParseMasterKey(encoded)
+		if e != nil {
+			return nil, fmt.Errorf("%s : %w", keyEnvKey, e)
+		}
+
+		return key, nil
+	default:
+		return nil, nil
+	}
+}
+
+// ariesOptions returns the options of the aries framework for the KMS and crypto. Without parameters, the local KMS
+// stores the private keys unencrypted.
+func (p *kmsParameters) ariesOptions(client *http.Client, tokens *requestTokens) ([]aries.Option, error) {
+	if p == nil {
+		p = &kmsParameters{}
+	}
+
+	if p.remoteURL != "" {
+		headers := webkms.WithHeaders(func(req *http.Request) (*http.Header, error) {
+			header := req.Header.Clone()
+
+			if token := tokens.get(kmsTokenName); token != "" {
+				header.Set("Authorization", "Bearer "+token)
+			}
+
+			return &header, nil
+		})
+
+		logger.Infof("kms - using the remote KMS at %s", p.remoteURL)
+
+		return []aries.Option{
+			aries.WithKMS(func(kms.Provider) (kms.KeyManager, error) {
+				return webkms.New(p.remoteURL, client, headers), nil
+			}),
+			aries.WithCrypto(webcrypto.New(p.remoteURL, client, headers)),
+		}, nil
+	}
+
+	lock, err := hubaries.NewSecretLock(p.masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.masterKey == nil {
+		logger.Warnf("kms - no master key, the private keys are stored unencrypted")
+	}
+
+	return []aries.Option{aries.WithSecretLock(lock)}, nil
+}
+
+// AddSecretLockFlags adds the flags configuring the master key of the local KMS to an offline command.
+func AddSecretLockFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(secretLockKeyPathFlagName, "", "", secretLockKeyPathFlagUsage)
+}
+
+// GetSecretLock returns the secret lock of the local KMS configured with the flags added with AddSecretLockFlags.
+// Fails if no master key is configured.
+func GetSecretLock(cmd *cobra.Command) (secretlock.Service, error) {
+	masterKey, err := getMasterKey(cmd, secretLockKeyPathFlagName, secretLockKeyPathEnvKey, secretLockKeyEnvKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if masterKey == nil {
+		return nil, errors.New("no master key: set " + secretLockKeyPathFlagName + " or " + secretLockKeyEnvKey)
+	}
+
+	return hubaries.NewSecretLock(masterKey)
+}
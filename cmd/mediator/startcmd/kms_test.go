@@ -0,0 +1,166 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/stretchr/testify/require"
+
+	hubaries "github.com/trustbloc/mediator/pkg/aries"
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+func newMasterKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, hubaries.MasterKeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return base64.URLEncoding.EncodeToString(key)
+}
+
+func TestGetKMSParams(t *testing.T) {
+	masterKey := newMasterKey(t)
+	keyFile := writeConfigFile(t, "master.key", masterKey)
+
+	parse := func(args ...string) (*kmsParameters, error) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(args))
+
+		return getKMSParams(startCmd)
+	}
+
+	t.Run("no master key", func(t *testing.T) {
+		params, err := parse()
+		require.NoError(t, err)
+		require.Nil(t, params.masterKey)
+		require.Empty(t, params.remoteURL)
+	})
+
+	t.Run("master key file", func(t *testing.T) {
+		params, err := parse("--"+secretLockKeyPathFlagName, keyFile)
+		require.NoError(t, err)
+		require.Len(t, params.masterKey, hubaries.MasterKeySize)
+	})
+
+	t.Run("master key environment variable", func(t *testing.T) {
+		require.NoError(t, os.Setenv(secretLockKeyEnvKey, masterKey))
+
+		defer func() {
+			require.NoError(t, os.Unsetenv(secretLockKeyEnvKey))
+		}()
+
+		params, err := parse()
+		require.NoError(t, err)
+		require.Len(t, params.masterKey, hubaries.MasterKeySize)
+
+		_, err = parse("--"+secretLockKeyPathFlagName, keyFile)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "set either secret-lock-key-path or MEDIATOR_SECRET_LOCK_KEY")
+	})
+
+	t.Run("invalid master key", func(t *testing.T) {
+		_, err := parse("--"+secretLockKeyPathFlagName, writeConfigFile(t, "invalid.key", "invalid"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "master key must be 32 bytes")
+	})
+
+	t.Run("remote kms", func(t *testing.T) {
+		params, err := parse("--"+kmsURLFlagName, "https://kms.example.com/v1/keystores/test")
+		require.NoError(t, err)
+		require.Equal(t, "https://kms.example.com/v1/keystores/test", params.remoteURL)
+
+		_, err = parse("--"+kmsURLFlagName, "https://kms.example.com/v1/keystores/test",
+			"--"+secretLockKeyPathFlagName, keyFile)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "secret-lock-key-path is not used with kms-url")
+	})
+}
+
+func TestKMSAriesOptions(t *testing.T) {
+	newFramework := func(t *testing.T, params *kmsParameters, tokens *requestTokens) *aries.Aries {
+		t.Helper()
+
+		opts, err := params.ariesOptions(&http.Client{}, tokens)
+		require.NoError(t, err)
+
+		framework, err := aries.New(append(opts, aries.WithStoreProvider(enumerable.NewProvider(mem.NewProvider())))...)
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			require.NoError(t, framework.Close())
+		})
+
+		return framework
+	}
+
+	t.Run("local kms with a master key", func(t *testing.T) {
+		masterKey, err := hubaries.ParseMasterKey(newMasterKey(t))
+		require.NoError(t, err)
+
+		framework := newFramework(t, &kmsParameters{masterKey: masterKey}, &requestTokens{})
+
+		ctx, err := framework.Context()
+		require.NoError(t, err)
+
+		_, _, err = ctx.KMS().Create(kms.ED25519Type)
+		require.NoError(t, err)
+
+		lock, err := hubaries.NewSecretLock(masterKey)
+		require.NoError(t, err)
+
+		// the key is already encrypted with the master key
+		count, err := hubaries.RewrapKeys(ctx.StorageProvider(), &noop.NoLock{}, lock)
+		require.NoError(t, err)
+		require.Equal(t, 0, count)
+	})
+
+	t.Run("remote kms", func(t *testing.T) {
+		var authorization string
+
+		kmsServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+
+			if r.Method != http.MethodPost || r.URL.Path != "/v1/keystores/test/keys" {
+				rw.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			require.NoError(t, json.NewEncoder(rw).Encode(map[string]interface{}{
+				"key_url":    "http://" + r.Host + r.URL.Path + "/key1",
+				"public_key": []byte("public key"),
+			}))
+		}))
+		defer kmsServer.Close()
+
+		framework := newFramework(t, &kmsParameters{remoteURL: kmsServer.URL + "/v1/keystores/test"},
+			&requestTokens{tokens: map[string]string{kmsTokenName: "secret"}})
+
+		ctx, err := framework.Context()
+		require.NoError(t, err)
+
+		kid, pubKey, err := ctx.KMS().CreateAndExportPubKeyBytes(kms.ED25519Type)
+		require.NoError(t, err)
+		require.Equal(t, "key1", kid)
+		require.Equal(t, []byte("public key"), pubKey)
+		require.Equal(t, "Bearer secret", authorization)
+	})
+}
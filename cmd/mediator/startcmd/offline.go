@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+)
+
+// AddStorageFlags adds the flags configuring the storage of the mediator, and the config file, to an offline command
+// working on the data of a stopped mediator.
+func AddStorageFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(datasourcePersistentFlagName, "", "", datasourcePersistentFlagUsage)
+	cmd.Flags().StringP(datasourceTransientFlagName, "", "", datasourceTransientFlagUsage)
+	cmd.Flags().StringP(datasourceTimeoutFlagName, "", "", datasourceTimeoutFlagUsage)
+	cmd.Flags().StringP(storageEncryptionKeyFileFlagName, "", "", storageEncryptionKeyFileFlagUsage)
+	cmd.Flags().StringArrayP(storageEncryptionStoresFlagName, "", []string{}, storageEncryptionStoresFlagUsage)
+	cmd.Flags().StringP(configFileFlagName, "", "", configFileFlagUsage)
+}
+
+// ApplyConfigFile sets the flags of an offline command that are set neither on the command line nor in the
+// environment from the config file of the mediator, if any. The options of the config file the command doesn't have
+// are ignored.
+func ApplyConfigFile(cmd *cobra.Command) error {
+	return applyConfigFile(cmd)
+}
+
+// OpenStorage opens the persistent and protocol state storage providers of the mediator with the usage prefixes
+// (ex: "_aries" and "_ariesps"), configured with the flags added with AddStorageFlags.
+func OpenStorage(cmd *cobra.Command,
+	persistentUsagePrefix, transientUsagePrefix string) (persistent, protocolStateStore storage.Provider, err error) {
+	params, err := getDatasourceParams(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return initStores(params, persistentUsagePrefix, transientUsagePrefix)
+}
+
+// AddStorageOptionFlags adds the flags configuring the storage of the mediator other than the datasources, and the
+// config file, to an offline command opening storage at other datasources with OpenAllStorageAt.
+func AddStorageOptionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(datasourceTimeoutFlagName, "", "", datasourceTimeoutFlagUsage)
+	cmd.Flags().StringP(storageEncryptionKeyFileFlagName, "", "", storageEncryptionKeyFileFlagUsage)
+	cmd.Flags().StringArrayP(storageEncryptionStoresFlagName, "", []string{}, storageEncryptionStoresFlagUsage)
+	cmd.Flags().StringP(configFileFlagName, "", "", configFileFlagUsage)
+}
+
+// OpenAllStorage opens all the storage providers of the mediator, by storage prefix (ex: hubrouter_aries), configured
+// with the flags added with AddStorageFlags.
+func OpenAllStorage(cmd *cobra.Command) (map[string]storage.Provider, error) {
+	params, err := getDatasourceParams(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return openAllStorage(params)
+}
+
+// OpenAllStorageAt opens all the storage providers of the mediator at the given persistent and transient datasources
+// (ex: leveldb://data), by storage prefix, configured with the flags added with AddStorageOptionFlags.
+func OpenAllStorageAt(cmd *cobra.Command, persistentURL, transientURL string) (map[string]storage.Provider, error) {
+	params, err := getDatasourceOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	params.persistentURL = persistentURL
+	params.transientURL = transientURL
+
+	return openAllStorage(params)
+}
+
+func openAllStorage(params *datasourceParams) (map[string]storage.Provider, error) {
+	providers := map[string]storage.Provider{}
+
+	for _, prefix := range storagePrefixes {
+		dbURL := params.persistentURL
+		if isTransientPrefix(prefix) {
+			dbURL = params.transientURL
+		}
+
+		p, e := initEncryptedStore(params, dbURL, prefix)
+		if e != nil {
+			CloseAllStorage(providers)
+
+			return nil, fmt.Errorf("init storage %s: %w", prefix, e)
+		}
+
+		providers[prefix] = p
+	}
+
+	return providers, nil
+}
+
+// CloseAllStorage closes the storage providers opened with OpenAllStorage.
+func CloseAllStorage(providers map[string]storage.Provider) {
+	for prefix, p := range providers {
+		if err := p.Close(); err != nil {
+			logger.Warnf("close storage %s : %s", prefix, err)
+		}
+	}
+}
+
+// isTransientPrefix tells if the storage with the prefix is opened with the transient datasource.
+func isTransientPrefix(prefix string) bool {
+	return prefix == storagePrefix+"_txn" || prefix == storagePrefix+"_ariesps"
+}
@@ -0,0 +1,188 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+// reloadableParameters are the parameters the mediator applies on reload, without restarting.
+type reloadableParameters struct {
+	logLevel      string
+	serveCertPath string
+	serveKeyPath  string
+	requestTokens map[string]string
+	hostTokens    map[string]string
+}
+
+func getReloadableParameters(cmd *cobra.Command) (*reloadableParameters, error) {
+	logLevel, err := getLogLevel(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = log.ParseLevel(logLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse log level '%s' : %w", logLevel, err)
+	}
+
+	tlsParams, err := getTLS(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	requestTokens, err := getRequestTokens(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	hostTokens, err := getHostTokens(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reloadableParameters{
+		logLevel:      logLevel,
+		serveCertPath: tlsParams.serveCertPath,
+		serveKeyPath:  tlsParams.serveKeyPath,
+		requestTokens: requestTokens,
+		hostTokens:    hostTokens,
+	}, nil
+}
+
+// reloadableParametersLoader returns a function reading the reloadable parameters again, with the command line flags
+// of the start command and the current environment and config file. It must be called before the config file is
+// applied to the start command.
+func reloadableParametersLoader(cmd *cobra.Command) func() (*reloadableParameters, error) {
+	options := commandLineOptions(cmd)
+
+	return func() (*reloadableParameters, error) {
+		reloadCmd := GetStartCmd(nil)
+
+		for name, values := range options {
+			for _, value := range values {
+				err := reloadCmd.Flags().Set(name, value)
+				if err != nil {
+					return nil, fmt.Errorf("option '%s': %w", name, err)
+				}
+			}
+		}
+
+		err := applyConfigFile(reloadCmd)
+		if err != nil {
+			return nil, err
+		}
+
+		return getReloadableParameters(reloadCmd)
+	}
+}
+
+// commandLineOptions returns the values of the flags set on the command line, by flag name.
+func commandLineOptions(cmd *cobra.Command) map[string][]string {
+	options := map[string][]string{}
+
+	for name, opt := range configOptions {
+		if !cmd.Flags().Changed(name) {
+			continue
+		}
+
+		if opt.kind == stringOption {
+			options[name] = []string{cmd.Flags().Lookup(name).Value.String()}
+
+			continue
+		}
+
+		options[name], _ = cmd.Flags().GetStringArray(name) // nolint:errcheck // array flags
+	}
+
+	if cmd.Flags().Changed(configFileFlagName) {
+		options[configFileFlagName] = []string{cmd.Flags().Lookup(configFileFlagName).Value.String()}
+	}
+
+	return options
+}
+
+// requestTokens are the tokens of the requests to other services, by name. They are refreshed on reload.
+type requestTokens struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+func (t *requestTokens) get(name string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.tokens[name]
+}
+
+func (t *requestTokens) set(tokens map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens = tokens
+}
+
+// reloader re-reads the configuration on SIGHUP or on the reload admin endpoint, and applies the log level, the TLS
+// certificates, the request tokens and the outbound host tokens. The other parameters need a restart.
+type reloader struct {
+	mu         sync.Mutex
+	load       func() (*reloadableParameters, error)
+	certs      *certificates
+	tokens     *requestTokens
+	hostTokens *hostTokens
+}
+
+// Reload applies the reloadable parameters. Nothing is applied if the configuration or the certificates are invalid.
+func (r *reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	params, err := r.load()
+	if err != nil {
+		return fmt.Errorf("reload configuration: %w", err)
+	}
+
+	if r.certs != nil {
+		if params.serveCertPath == "" || params.serveKeyPath == "" {
+			return errors.New("reload configuration: cert path and key path are mandatory with tls")
+		}
+
+		err = r.certs.load(params.serveCertPath, params.serveKeyPath)
+		if err != nil {
+			return fmt.Errorf("reload configuration: %w", err)
+		}
+	}
+
+	err = setLogLevel(params.logLevel)
+	if err != nil {
+		return fmt.Errorf("reload configuration: %w", err)
+	}
+
+	r.tokens.set(params.requestTokens)
+	r.hostTokens.set(params.hostTokens)
+
+	logger.Infof("configuration reloaded, logger level set to %s", params.logLevel)
+
+	return nil
+}
+
+// reloadOnSignal reloads the configuration on each signal received.
+func (r *reloader) reloadOnSignal(signals <-chan os.Signal) {
+	for sig := range signals {
+		logger.Infof("received %s, reloading configuration", sig)
+
+		if err := r.Reload(); err != nil {
+			logger.Errorf("%s", err)
+		}
+	}
+}
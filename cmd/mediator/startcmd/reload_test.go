@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+func TestReloadableParametersLoader(t *testing.T) {
+	configFile := writeConfigFile(t, "config.yaml", `
+log-level: DEBUG
+request-tokens:
+  sidetreeToken: t1
+`)
+
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{
+		"--" + configFileFlagName, configFile,
+		"--" + requestTokensFlagName, "other=t0",
+	}))
+
+	load := reloadableParametersLoader(startCmd)
+
+	require.NoError(t, applyConfigFile(startCmd))
+
+	params, err := load()
+	require.NoError(t, err)
+	require.Equal(t, "DEBUG", params.logLevel)
+	require.Equal(t, map[string]string{"other": "t0"}, params.requestTokens)
+
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("log-level: WARNING\n"), 0o600))
+
+	params, err = load()
+	require.NoError(t, err)
+	require.Equal(t, "WARNING", params.logLevel)
+
+	t.Run("invalid config file", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(configFile, []byte("loglevel: WARNING\n"), 0o600))
+
+		_, err = load()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unknown option 'loglevel'")
+	})
+
+	t.Run("invalid log level", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(configFile, []byte("log-level: LOUD\n"), 0o600))
+
+		_, err = load()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse log level 'LOUD'")
+	})
+}
+
+func TestReloader(t *testing.T) {
+	defer func() {
+		require.NoError(t, setLogLevel("INFO"))
+	}()
+
+	newReloaderWith := func(params *reloadableParameters, err error, certs *certificates) *reloader {
+		return &reloader{
+			load:       func() (*reloadableParameters, error) { return params, err },
+			certs:      certs,
+			tokens:     &requestTokens{tokens: map[string]string{"sidetreeToken": "t1"}},
+			hostTokens: &hostTokens{tokens: map[string]string{"resolver.example.com": "h1"}},
+		}
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		certs, err := newCertificates(writeTestCertificate(t, "a.example.com"))
+		require.NoError(t, err)
+
+		certFile, keyFile := writeTestCertificate(t, "b.example.com")
+
+		r := newReloaderWith(&reloadableParameters{
+			logLevel:      "DEBUG",
+			serveCertPath: certFile,
+			serveKeyPath:  keyFile,
+			requestTokens: map[string]string{"sidetreeToken": "t2"},
+			hostTokens:    map[string]string{"resolver.example.com": "h2"},
+		}, nil, certs)
+
+		require.NoError(t, r.Reload())
+		require.Equal(t, log.DEBUG, log.GetLevel(""))
+		require.Equal(t, "t2", r.tokens.get("sidetreeToken"))
+		require.Equal(t, "h2", r.hostTokens.get(&url.URL{Host: "resolver.example.com"}))
+		require.Equal(t, "b.example.com", servedCertificate(t, certs).Subject.CommonName)
+	})
+
+	t.Run("reload without tls", func(t *testing.T) {
+		r := newReloaderWith(&reloadableParameters{logLevel: "WARNING"}, nil, nil)
+
+		require.NoError(t, r.Reload())
+		require.Equal(t, log.WARNING, log.GetLevel(""))
+		require.Empty(t, r.tokens.get("sidetreeToken"))
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		r := newReloaderWith(nil, errors.New("invalid config file"), nil)
+
+		err := r.Reload()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "reload configuration: invalid config file")
+	})
+
+	t.Run("invalid certificate applies nothing", func(t *testing.T) {
+		require.NoError(t, setLogLevel("INFO"))
+
+		certs, err := newCertificates(writeTestCertificate(t, "a.example.com"))
+		require.NoError(t, err)
+
+		r := newReloaderWith(&reloadableParameters{
+			logLevel:      "DEBUG",
+			serveCertPath: "/missing/cert.pem",
+			serveKeyPath:  "/missing/key.pem",
+			requestTokens: map[string]string{"sidetreeToken": "t2"},
+		}, nil, certs)
+
+		err = r.Reload()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "load TLS certificate")
+		require.Equal(t, log.INFO, log.GetLevel(""))
+		require.Equal(t, "t1", r.tokens.get("sidetreeToken"))
+		require.Equal(t, "h1", r.hostTokens.get(&url.URL{Host: "resolver.example.com"}))
+		require.Equal(t, "a.example.com", servedCertificate(t, certs).Subject.CommonName)
+
+		r = newReloaderWith(&reloadableParameters{logLevel: "DEBUG"}, nil, certs)
+
+		err = r.Reload()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cert path and key path are mandatory with tls")
+	})
+
+	t.Run("reload on signal", func(t *testing.T) {
+		r := newReloaderWith(&reloadableParameters{
+			logLevel:      "INFO",
+			requestTokens: map[string]string{"sidetreeToken": "t2"},
+		}, nil, nil)
+
+		signals := make(chan os.Signal, 1)
+		signals <- syscall.SIGHUP
+		close(signals)
+
+		r.reloadOnSignal(signals)
+		require.Equal(t, "t2", r.tokens.get("sidetreeToken"))
+	})
+}
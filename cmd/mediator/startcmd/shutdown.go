@@ -0,0 +1,142 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+)
+
+// stopper is a server that stops accepting connections and waits for the in-flight requests until ctx is done.
+type stopper interface {
+	Shutdown(ctx context.Context) error
+}
+
+type stopperFunc func(ctx context.Context) error
+
+func (f stopperFunc) Shutdown(ctx context.Context) error {
+	return f(ctx)
+}
+
+// stopInbound stops an inbound transport of the aries framework, which waits for the in-flight requests with no
+// deadline. WebSocket connections are left open until the process exits.
+func stopInbound(inbound transport.InboundTransport) stopper {
+	return stopperFunc(func(ctx context.Context) error {
+		done := make(chan error, 1)
+
+		go func() {
+			done <- inbound.Stop()
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+type namedStopper struct {
+	name string
+	stopper
+}
+
+type namedCloser struct {
+	name string
+	io.Closer
+}
+
+// shutdown stops the mediator gracefully: the servers stop accepting connections and drain the in-flight requests
+// within the timeout, then the aries framework and the storage providers are closed, in registration order.
+type shutdown struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	servers []namedStopper
+	closers []namedCloser
+
+	failed chan error
+}
+
+func newShutdown(timeout time.Duration) *shutdown {
+	return &shutdown{timeout: timeout, failed: make(chan error, 1)}
+}
+
+// fail reports that a server failed, so that the mediator is shut down. Only the first failure is kept.
+func (s *shutdown) fail(err error) {
+	select {
+	case s.failed <- err:
+	default:
+	}
+}
+
+func (s *shutdown) addServer(name string, server stopper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.servers = append(s.servers, namedStopper{name: name, stopper: server})
+}
+
+func (s *shutdown) addCloser(name string, closer io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closers = append(s.closers, namedCloser{name: name, Closer: closer})
+}
+
+// run shuts the mediator down. The closers are closed even if the servers didn't drain within the timeout.
+func (s *shutdown) run() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		problems []string
+		errMu    sync.Mutex
+	)
+
+	for _, server := range s.servers {
+		wg.Add(1)
+
+		go func(server namedStopper) {
+			defer wg.Done()
+
+			if err := server.Shutdown(ctx); err != nil {
+				errMu.Lock()
+				problems = append(problems, fmt.Sprintf("stop %s: %s", server.name, err))
+				errMu.Unlock()
+			}
+		}(server)
+	}
+
+	wg.Wait()
+
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			problems = append(problems, fmt.Sprintf("close %s: %s", closer.name, err))
+		}
+	}
+
+	s.servers, s.closers = nil, nil
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Run("stops the servers then closes in order", func(t *testing.T) {
+		var (
+			mu     sync.Mutex
+			events []string
+		)
+
+		event := func(e string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			events = append(events, e)
+		}
+
+		sd := newShutdown(time.Second)
+		sd.addServer("a", stopperFunc(func(context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			event("stop a")
+
+			return nil
+		}))
+		sd.addServer("b", stopperFunc(func(context.Context) error {
+			event("stop b")
+
+			return nil
+		}))
+		sd.addCloser("c", &mockCloser{close: func() error { event("close c"); return nil }})
+		sd.addCloser("d", &mockCloser{close: func() error { event("close d"); return nil }})
+
+		require.NoError(t, sd.run())
+		require.Equal(t, []string{"stop b", "stop a", "close c", "close d"}, events)
+
+		require.NoError(t, sd.run())
+		require.Len(t, events, 4)
+	})
+
+	t.Run("errors and timeout", func(t *testing.T) {
+		closed := false
+
+		sd := newShutdown(10 * time.Millisecond)
+		sd.addServer("slow", stopperFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		}))
+		sd.addCloser("storage", &mockCloser{close: func() error { closed = true; return errors.New("close error") }})
+
+		err := sd.run()
+		require.Error(t, err)
+		require.Equal(t, "stop slow: context deadline exceeded; close storage: close error", err.Error())
+		require.True(t, closed)
+	})
+}
+
+func TestStopInbound(t *testing.T) {
+	t.Run("stopped", func(t *testing.T) {
+		inbound := &mockInbound{stop: func() error { return nil }}
+
+		require.NoError(t, stopInbound(inbound).Shutdown(context.Background()))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		inbound := &mockInbound{stop: func() error {
+			<-release
+
+			return nil
+		}}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, stopInbound(inbound).Shutdown(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestServeUntilStopped(t *testing.T) {
+	t.Run("drains in-flight requests on signal", func(t *testing.T) {
+		host := randomURL(t)
+		started := make(chan struct{})
+
+		router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("done")) // nolint:errcheck,gosec // test server
+		})
+
+		closed := false
+
+		sd := newShutdown(5 * time.Second)
+		sd.addCloser("storage", &mockCloser{close: func() error { closed = true; return nil }})
+
+		stop := make(chan os.Signal, 1)
+		stopped := make(chan error, 1)
+
+		go func() {
+			stopped <- serveUntilStopped(&hubRouterParameters{hostURL: host, shutdownTimeout: 5 * time.Second},
+				&HTTPServer{}, router, nil, sd, stop)
+		}()
+
+		response := make(chan string, 1)
+
+		go func() {
+			for {
+				resp, err := http.Get("http://" + host) // nolint:noctx // test
+				if err != nil {
+					time.Sleep(10 * time.Millisecond)
+
+					continue
+				}
+
+				body, _ := ioutil.ReadAll(resp.Body) // nolint:errcheck // checked by value
+				resp.Body.Close()                    // nolint:errcheck,gosec // test
+
+				response <- string(body)
+
+				return
+			}
+		}()
+
+		<-started
+		stop <- syscall.SIGTERM
+
+		require.NoError(t, <-stopped)
+		require.Equal(t, "done", <-response)
+		require.True(t, closed)
+
+		_, err := http.Get("http://" + host) // nolint:noctx,bodyclose // test
+		require.Error(t, err)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		host := randomURL(t)
+
+		busy := &HTTPServer{}
+
+		go busy.ListenAndServe(host, http.NotFoundHandler()) // nolint:errcheck // test
+
+		defer busy.Shutdown(context.Background()) // nolint:errcheck // test
+
+		time.Sleep(50 * time.Millisecond)
+
+		err := serveUntilStopped(&hubRouterParameters{hostURL: host}, &HTTPServer{}, http.NotFoundHandler(), nil,
+			newShutdown(time.Second), make(chan os.Signal))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "address already in use")
+	})
+
+	t.Run("transport failure", func(t *testing.T) {
+		sd := newShutdown(time.Second)
+		sd.fail(errors.New("inbound failed"))
+		sd.fail(errors.New("ignored"))
+
+		err := serveUntilStopped(&hubRouterParameters{hostURL: randomURL(t)}, &HTTPServer{}, http.NotFoundHandler(),
+			nil, sd, make(chan os.Signal))
+		require.EqualError(t, err, "inbound failed")
+		require.NoError(t, sd.run())
+	})
+}
+
+func TestHTTPServer_Shutdown(t *testing.T) {
+	srv := &HTTPServer{}
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	require.NoError(t, srv.ListenAndServe(randomURL(t), http.NotFoundHandler()))
+}
+
+type mockCloser struct {
+	close func() error
+}
+
+func (m *mockCloser) Close() error {
+	return m.close()
+}
+
+type mockInbound struct {
+	stop func() error
+}
+
+func (m *mockInbound) Start(transport.Provider) error {
+	return nil
+}
+
+func (m *mockInbound) Stop() error {
+	return m.stop()
+}
+
+func (m *mockInbound) Endpoint() string {
+	return ""
+}
@@ -0,0 +1,116 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/mediator/pkg/storage/encrypted"
+)
+
+// Storage encryption config.
+const (
+	storageEncryptionKeyFileFlagName  = "storage-encryption-key-file"
+	storageEncryptionKeyFileFlagUsage = "Path of a file holding the base64 encoded 32 byte key used to encrypt" +
+		" the data at rest with AES-GCM. Keys and tags are stored as keyed hashes, so that lookups and tag queries" +
+		" still work. Without it, the data is stored unencrypted." +
+		" Alternatively, this can be set with the following environment variable: " + storageEncryptionKeyFileEnvKey
+	storageEncryptionKeyFileEnvKey = "MEDIATOR_STORAGE_ENCRYPTION_KEY_FILE"
+
+	storageEncryptionStoresFlagName  = "storage-encryption-stores"
+	storageEncryptionStoresFlagUsage = "Storage prefixes to encrypt with the storage encryption key." +
+		" Possible values are [hubrouter, hubrouter_txn, hubrouter_aries, hubrouter_ariesps]. Default: all of them." +
+		" Alternatively, this can be set with the following environment variable: " + storageEncryptionStoresEnvKey
+	storageEncryptionStoresEnvKey = "MEDIATOR_STORAGE_ENCRYPTION_STORES"
+)
+
+// storagePrefixes are the prefixes of the storage providers opened by the mediator.
+// nolint:gochecknoglobals // constant list
+var storagePrefixes = []string{
+	storagePrefix, storagePrefix + "_txn", storagePrefix + "_aries", storagePrefix + "_ariesps",
+}
+
+type storageEncryptionParams struct {
+	key []byte
+	// prefixes are the storage prefixes to encrypt, none if there's no key.
+	prefixes map[string]bool
+}
+
+func getStorageEncryptionParams(cmd *cobra.Command) (*storageEncryptionParams, error) {
+	keyFile, err := cmdutils.GetUserSetVarFromString(cmd, storageEncryptionKeyFileFlagName,
+		storageEncryptionKeyFileEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stores, err := cmdutils.GetUserSetVarFromArrayString(cmd, storageEncryptionStoresFlagName,
+		storageEncryptionStoresEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyFile == "" {
+		if len(stores) > 0 {
+			return nil, fmt.Errorf("%s requires %s", storageEncryptionStoresFlagName, storageEncryptionKeyFileFlagName)
+		}
+
+		return &storageEncryptionParams{}, nil
+	}
+
+	key, err := encrypted.ReadKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stores) == 0 {
+		stores = storagePrefixes
+	}
+
+	params := &storageEncryptionParams{key: key, prefixes: map[string]bool{}}
+
+	for _, store := range stores {
+		if !isStoragePrefix(store) {
+			return nil, fmt.Errorf("invalid %s %s: must be one of [%s]", storageEncryptionStoresFlagName, store,
+				strings.Join(storagePrefixes, ", "))
+		}
+
+		params.prefixes[store] = true
+	}
+
+	return params, nil
+}
+
+func isStoragePrefix(prefix string) bool {
+	for _, p := range storagePrefixes {
+		if p == prefix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encrypt returns the provider opened with the prefix, encrypting its data if configured.
+func (p *storageEncryptionParams) encrypt(provider storage.Provider, prefix string) (storage.Provider, error) {
+	if p == nil || !p.prefixes[prefix] {
+		return provider, nil
+	}
+
+	encryptedProvider, err := encrypted.NewProvider(provider, p.key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt storage %s : %w", prefix, err)
+	}
+
+	logger.Infof("store init - encrypting storage %s", prefix)
+
+	return encryptedProvider, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/storage/encrypted"
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+func writeStorageEncryptionKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, encrypted.KeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return writeConfigFile(t, "storage.key", base64.StdEncoding.EncodeToString(key))
+}
+
+func TestGetStorageEncryptionParams(t *testing.T) {
+	keyFile := writeStorageEncryptionKey(t)
+
+	parse := func(args ...string) (*storageEncryptionParams, error) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags(args))
+
+		return getStorageEncryptionParams(startCmd)
+	}
+
+	t.Run("not encrypted", func(t *testing.T) {
+		params, err := parse()
+		require.NoError(t, err)
+		require.Empty(t, params.prefixes)
+	})
+
+	t.Run("all stores by default", func(t *testing.T) {
+		params, err := parse("--"+storageEncryptionKeyFileFlagName, keyFile)
+		require.NoError(t, err)
+		require.Len(t, params.key, encrypted.KeySize)
+		require.Len(t, params.prefixes, len(storagePrefixes))
+	})
+
+	t.Run("selected stores", func(t *testing.T) {
+		params, err := parse("--"+storageEncryptionKeyFileFlagName, keyFile,
+			"--"+storageEncryptionStoresFlagName, "hubrouter_aries")
+		require.NoError(t, err)
+		require.Equal(t, map[string]bool{"hubrouter_aries": true}, params.prefixes)
+	})
+
+	t.Run("unknown store", func(t *testing.T) {
+		_, err := parse("--"+storageEncryptionKeyFileFlagName, keyFile,
+			"--"+storageEncryptionStoresFlagName, "hubrouter_other")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid storage-encryption-stores hubrouter_other")
+	})
+
+	t.Run("stores without key", func(t *testing.T) {
+		_, err := parse("--"+storageEncryptionStoresFlagName, "hubrouter")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "storage-encryption-stores requires storage-encryption-key-file")
+	})
+
+	t.Run("invalid key file", func(t *testing.T) {
+		_, err := parse("--"+storageEncryptionKeyFileFlagName, writeConfigFile(t, "invalid.key", "invalid"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode storage encryption key")
+	})
+}
+
+func TestInitEncryptedStores(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, startCmd.ParseFlags([]string{
+		"--" + storageEncryptionKeyFileFlagName, writeStorageEncryptionKey(t),
+		"--" + storageEncryptionStoresFlagName, "hubrouter_aries",
+	}))
+
+	encryption, err := getStorageEncryptionParams(startCmd)
+	require.NoError(t, err)
+
+	params := &datasourceParams{persistentURL: "mem://tests", transientURL: "mem://tests", encryption: encryption}
+
+	persistent, transient, err := initStores(params, "_aries", "_ariesps")
+	require.NoError(t, err)
+	require.IsType(t, &enumerable.Provider{}, persistent)
+	require.IsType(t, &encrypted.Provider{}, persistent.(*enumerable.Provider).Unwrap())
+
+	_, isEncrypted := transient.(*enumerable.Provider).Unwrap().(*encrypted.Provider)
+	require.False(t, isEncrypted)
+
+	store, err := persistent.OpenStore("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Put("key", []byte("value")))
+
+	value, err := store.Get("key")
+	require.NoError(t, err)
+	require.Equal(t, "value", string(value))
+}
@@ -0,0 +1,192 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	arieshttp "github.com/hyperledger/aries-framework-go/pkg/didcomm/transport/http"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+// certificates serves the TLS certificate of the mediator, which is swapped on reload without restarting the servers.
+type certificates struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertificates(certFile, keyFile string) (*certificates, error) {
+	c := &certificates{}
+
+	err := c.load(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load loads the certificate from its files. The served certificate is unchanged if they are invalid.
+func (c *certificates) load(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate : %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cert = &cert
+
+	return nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+func (c *certificates) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+func (c *certificates) tlsConfig() *tls.Config {
+	return &tls.Config{GetCertificate: c.GetCertificate, MinVersion: tls.VersionTLS12}
+}
+
+// listenTLS listens on addr for a TLS server.
+func listenTLS(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen tls : %w", err)
+	}
+
+	return ln, nil
+}
+
+// httpsInbound is the HTTP inbound transport of the aries framework served with TLS on a listener, so that the
+// certificate can be swapped on reload.
+type httpsInbound struct {
+	ln           net.Listener
+	externalAddr string
+	certs        *certificates
+	sd           *shutdown
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+func newHTTPSInbound(ln net.Listener, externalAddr string, certs *certificates, sd *shutdown) *httpsInbound {
+	return &httpsInbound{ln: ln, externalAddr: externalAddr, certs: certs, sd: sd}
+}
+
+// Start serves the inbound messages.
+func (i *httpsInbound) Start(prov transport.Provider) error {
+	handler, err := arieshttp.NewInboundHandler(prov)
+	if err != nil {
+		return fmt.Errorf("https inbound transport : %w", err)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.server = &http.Server{
+		Handler:           handler,
+		TLSConfig:         i.certs.tlsConfig(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func(server *http.Server) {
+		if err := server.ServeTLS(i.ln, "", ""); !errors.Is(err, http.ErrServerClosed) {
+			i.sd.fail(fmt.Errorf("https inbound transport on %s : %w", i.ln.Addr(), err))
+		}
+	}(i.server)
+
+	logger.Infof("serving the https inbound transport on %s", i.ln.Addr())
+
+	return nil
+}
+
+// Stop stops the server, which waits for the in-flight requests.
+func (i *httpsInbound) Stop() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.server == nil {
+		// not started
+		return i.ln.Close()
+	}
+
+	if err := i.server.Shutdown(context.Background()); err != nil {
+		return fmt.Errorf("https inbound transport shutdown : %w", err)
+	}
+
+	return nil
+}
+
+// Endpoint returns the external address.
+func (i *httpsInbound) Endpoint() string {
+	return i.externalAddr
+}
+
+// startTLSFrontend serves TLS on ln with the certificates and forwards the requests, including WebSocket upgrades, to
+// the plain HTTP backend. The client address is forwarded in the X-Forwarded-For header.
+//
+// The WebSocket inbound transport of the aries framework loads its certificate once when it starts and can't be given
+// a listener: with TLS, it listens on a loopback address behind a front end so that the certificate can be swapped on
+// reload. A failure of the front end shuts the mediator down.
+func startTLSFrontend(ln net.Listener, backend string, certs *certificates, sd *shutdown) *http.Server {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: backend})
+	director := proxy.Director
+
+	proxy.Director = func(r *http.Request) {
+		director(r)
+
+		// the proxy appends the client address to X-Forwarded-For
+		r.Header.Set("X-Forwarded-Proto", "https")
+	}
+
+	server := &http.Server{
+		Handler:           proxy,
+		TLSConfig:         certs.tlsConfig(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		if err := server.ServeTLS(ln, "", ""); !errors.Is(err, http.ErrServerClosed) {
+			sd.fail(fmt.Errorf("tls front end on %s : %w", ln.Addr(), err))
+		}
+	}()
+
+	logger.Infof("serving tls on %s for %s", ln.Addr(), backend)
+
+	return server
+}
+
+// loopbackAddr returns a free loopback address for the backend of a TLS front end. The port is released for the
+// backend to listen on it: if another process takes it in between, the backend fails to listen and the mediator
+// exits.
+func loopbackAddr() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("get loopback address : %w", err)
+	}
+
+	defer ln.Close() // nolint:errcheck,gosec // only picks the port
+
+	return ln.Addr().String(), nil
+}
@@ -0,0 +1,274 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
+	mockpackager "github.com/hyperledger/aries-framework-go/pkg/mock/didcomm/packager"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self-signed certificate for the host and its key. Returns the file paths.
+func writeTestCertificate(t *testing.T, host string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		0o600))
+
+	return certFile, keyFile
+}
+
+func servedCertificate(t *testing.T, certs *certificates) *x509.Certificate {
+	t.Helper()
+
+	cert, err := certs.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf
+}
+
+func TestCertificates(t *testing.T) {
+	t.Run("load and reload", func(t *testing.T) {
+		certs, err := newCertificates(writeTestCertificate(t, "a.example.com"))
+		require.NoError(t, err)
+		require.Equal(t, "a.example.com", servedCertificate(t, certs).Subject.CommonName)
+
+		require.NoError(t, certs.load(writeTestCertificate(t, "b.example.com")))
+		require.Equal(t, "b.example.com", servedCertificate(t, certs).Subject.CommonName)
+	})
+
+	t.Run("invalid files keep the certificate", func(t *testing.T) {
+		certFile, keyFile := writeTestCertificate(t, "a.example.com")
+
+		certs, err := newCertificates(certFile, keyFile)
+		require.NoError(t, err)
+
+		err = certs.load(certFile, certFile)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "load TLS certificate")
+		require.Equal(t, "a.example.com", servedCertificate(t, certs).Subject.CommonName)
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := newCertificates("/missing/cert.pem", "/missing/key.pem")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "load TLS certificate")
+	})
+}
+
+func tlsGet(t *testing.T, url string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}, // nolint:gosec // test
+	}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+
+	defer resp.Body.Close() // nolint:errcheck // test
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body), resp.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+func TestTLSFrontend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// nolint:errcheck,gosec // test server
+		w.Write([]byte(fmt.Sprintf("backend %s %s %s", r.URL.Path, r.Header.Get("X-Forwarded-For"),
+			r.Header.Get("X-Forwarded-Proto"))))
+	}))
+	defer backend.Close()
+
+	certs, err := newCertificates(writeTestCertificate(t, "a.example.com"))
+	require.NoError(t, err)
+
+	addr := randomURL(t)
+
+	ln, err := listenTLS(addr)
+	require.NoError(t, err)
+
+	sd := newShutdown(time.Second)
+
+	frontend := startTLSFrontend(ln, backend.Listener.Addr().String(), certs, sd)
+
+	defer frontend.Close() // nolint:errcheck // test
+
+	body, served := tlsGet(t, "https://"+addr+"/didcomm")
+	require.Equal(t, "backend /didcomm 127.0.0.1 https", body)
+	require.Equal(t, "a.example.com", served)
+
+	require.NoError(t, certs.load(writeTestCertificate(t, "b.example.com")))
+
+	_, served = tlsGet(t, "https://"+addr+"/didcomm")
+	require.Equal(t, "b.example.com", served)
+
+	t.Run("address in use", func(t *testing.T) {
+		_, err := listenTLS(addr)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "listen tls")
+	})
+
+	t.Run("failure reported to the shutdown", func(t *testing.T) {
+		ln, err := listenTLS(randomURL(t))
+		require.NoError(t, err)
+		require.NoError(t, ln.Close())
+
+		sd := newShutdown(time.Second)
+
+		startTLSFrontend(ln, backend.Listener.Addr().String(), certs, sd)
+
+		err = <-sd.failed
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "tls front end on")
+	})
+}
+
+func TestHTTPSInbound(t *testing.T) {
+	certs, err := newCertificates(writeTestCertificate(t, "a.example.com"))
+	require.NoError(t, err)
+
+	messages := make(chan string, 1)
+
+	prov := &mockTransportProvider{
+		handler: func(envelope *transport.Envelope) error {
+			messages <- string(envelope.Message)
+
+			return nil
+		},
+		packager: &mockpackager.Packager{UnpackValue: &transport.Envelope{Message: []byte(`{"id":"msg1"}`)}},
+	}
+
+	t.Run("serves the inbound messages with the current certificate", func(t *testing.T) {
+		addr := randomURL(t)
+
+		ln, err := listenTLS(addr)
+		require.NoError(t, err)
+
+		inbound := newHTTPSInbound(ln, "https://example.com", certs, newShutdown(time.Second))
+		require.Equal(t, "https://example.com", inbound.Endpoint())
+		require.NoError(t, inbound.Start(prov))
+
+		defer inbound.Stop() // nolint:errcheck // test
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}, // nolint:gosec // test
+		}}
+		defer client.CloseIdleConnections()
+
+		resp, err := client.Post("https://"+addr, "application/didcomm-envelope-enc", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		require.Equal(t, `{"id":"msg1"}`, <-messages)
+		require.Equal(t, "a.example.com", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+		require.NoError(t, inbound.Stop())
+	})
+
+	t.Run("stopped before it's started", func(t *testing.T) {
+		addr := randomURL(t)
+
+		ln, err := listenTLS(addr)
+		require.NoError(t, err)
+
+		inbound := newHTTPSInbound(ln, addr, certs, newShutdown(time.Second))
+		require.NoError(t, inbound.Stop())
+
+		// the address is released
+		ln, err = listenTLS(addr)
+		require.NoError(t, err)
+		require.NoError(t, ln.Close())
+	})
+
+	t.Run("no message handler", func(t *testing.T) {
+		ln, err := listenTLS(randomURL(t))
+		require.NoError(t, err)
+
+		inbound := newHTTPSInbound(ln, "", certs, newShutdown(time.Second))
+
+		err = inbound.Start(&mockTransportProvider{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "https inbound transport")
+		require.NoError(t, inbound.Stop())
+	})
+
+	t.Run("failure reported to the shutdown", func(t *testing.T) {
+		ln, err := listenTLS(randomURL(t))
+		require.NoError(t, err)
+		require.NoError(t, ln.Close())
+
+		sd := newShutdown(time.Second)
+
+		require.NoError(t, newHTTPSInbound(ln, "", certs, sd).Start(prov))
+
+		err = <-sd.failed
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "https inbound transport on")
+	})
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	addr, err := loopbackAddr()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(addr, "127.0.0.1:"))
+
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
@@ -0,0 +1,151 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
+)
+
+// GetConfigCmd returns the Cobra config command, which checks the start options without starting the mediator.
+func GetConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check the configuration of the mediator",
+		Long:  "Check the configuration of the mediator",
+	}
+
+	cmd.AddCommand(createValidateCmd())
+
+	return cmd
+}
+
+func createValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the start options",
+		Long: "Read the start options from the command line flags, the environment and the config file like the start" +
+			" command does, and check that the storage is reachable, the TLS files are valid and the URLs are" +
+			" well-formed, without starting the mediator",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := applyConfigFile(cmd)
+			if err != nil {
+				return err
+			}
+
+			params, err := getHubRouterParameters(cmd)
+			if err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
+
+			problems := validateParameters(params)
+			if len(problems) > 0 {
+				return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+			}
+
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+
+			return err
+		},
+	}
+
+	createFlags(cmd)
+
+	return cmd
+}
+
+// validateParameters checks what the start command checks when it uses the parameters. Returns the problems found.
+func validateParameters(params *hubRouterParameters) []string {
+	const numPartsResolverOption = 2
+
+	var problems []string
+
+	problems = append(problems, validateTLS(params.tlsParams)...)
+	problems = append(problems, validateStorage(params.datasourceParams)...)
+
+	for _, resolver := range params.didCommParameters.didResolvers {
+		r := strings.Split(resolver, "@")
+		if len(r) != numPartsResolverOption {
+			problems = append(problems, fmt.Sprintf("http resolver '%s': expected <method>@<url>", resolver))
+
+			continue
+		}
+
+		if err := validateURL(r[1]); err != nil {
+			problems = append(problems, fmt.Sprintf("http resolver '%s': %s", resolver, err))
+		}
+	}
+
+	if params.kmsParameters.remoteURL != "" {
+		if err := validateURL(params.kmsParameters.remoteURL); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", kmsURLFlagName, err))
+		}
+	}
+
+	return problems
+}
+
+func validateTLS(params *tlsParameters) []string {
+	var problems []string
+
+	_, err := tlsutils.GetCertPool(params.systemCertPool, params.caCerts)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("get root CAs : %s", err))
+	}
+
+	err = checkServeCertPaths(params)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	if params.serveCertPath != "" {
+		// fails if the key doesn't match the certificate
+		_, err = newCertificates(params.serveCertPath, params.serveKeyPath)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems
+}
+
+func validateStorage(params *datasourceParams) []string {
+	var problems []string
+
+	for _, dbURL := range []string{params.persistentURL, params.transientURL} {
+		store, err := initStore(dbURL, storagePrefix, params.timeout)
+		if err != nil {
+			problems = append(problems, err.Error())
+
+			continue
+		}
+
+		err = store.Close()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("close storage : %s", err))
+		}
+	}
+
+	return problems
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL '%s': expected an http or https URL", rawURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCmd(t *testing.T) {
+	execute := func(args ...string) (string, error) {
+		cmd := GetConfigCmd()
+		out := &bytes.Buffer{}
+
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"validate",
+			"--" + hostURLFlagName, "localhost:8080",
+			"--" + didCommHTTPHostFlagName, "localhost:8081",
+			"--" + didCommWSHostFlagName, "localhost:8082",
+			"--" + orbDomainsFlagName, "orb.example.com",
+		}, args...))
+
+		err := cmd.Execute()
+
+		return out.String(), err
+	}
+
+	certFile, keyFile := writeTestCertificate(t, "localhost")
+	_, otherKeyFile := writeTestCertificate(t, "localhost")
+
+	t.Run("valid", func(t *testing.T) {
+		out, err := execute(
+			"--"+datasourcePersistentFlagName, "mem://tests",
+			"--"+datasourceTransientFlagName, "mem://tests",
+			"--"+tlsServeCertPathFlagName, certFile,
+			"--"+tlsServeKeyPathFlagName, keyFile,
+			"--"+agentHTTPResolverFlagName, "orb@https://resolver.example.com/1.0/identifiers",
+		)
+		require.NoError(t, err)
+		require.Equal(t, "configuration is valid\n", out)
+	})
+
+	t.Run("invalid option", func(t *testing.T) {
+		_, err := execute(
+			"--"+datasourcePersistentFlagName, "mem://tests",
+			"--"+datasourceTransientFlagName, "mem://tests",
+			"--"+keyTypeFlagName, "rsa",
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid configuration: invalid key-type 'rsa'")
+	})
+
+	t.Run("all problems reported", func(t *testing.T) {
+		_, err := execute(
+			"--"+datasourcePersistentFlagName, "mem://tests",
+			"--"+datasourceTransientFlagName, "other://tests",
+			"--"+tlsServeCertPathFlagName, certFile,
+			"--"+tlsServeKeyPathFlagName, otherKeyFile,
+			"--"+tlsCACertsFlagName, "missing.pem",
+			"--"+agentHTTPResolverFlagName, "orb",
+			"--"+agentHTTPResolverFlagName, "orb@resolver.example.com",
+			"--"+kmsURLFlagName, "kms.example.com",
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get root CAs")
+		require.Contains(t, err.Error(), "load TLS certificate")
+		require.Contains(t, err.Error(), "unsupported storage driver: other")
+		require.Contains(t, err.Error(), "http resolver 'orb': expected <method>@<url>")
+		require.Contains(t, err.Error(),
+			"http resolver 'orb@resolver.example.com': invalid URL 'resolver.example.com'")
+		require.Contains(t, err.Error(), "kms-url: invalid URL 'kms.example.com'")
+	})
+
+	t.Run("missing key path", func(t *testing.T) {
+		_, err := execute(
+			"--"+datasourcePersistentFlagName, "mem://tests",
+			"--"+datasourceTransientFlagName, "mem://tests",
+			"--"+tlsServeCertPathFlagName, certFile,
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing key path")
+	})
+}
@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versioncmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const ariesModulePrefix = "github.com/hyperledger/aries-framework-go"
+
+// Set at build time with -ldflags "-X github.com/trustbloc/mediator/cmd/mediator/versioncmd.version=<version>".
+// nolint:gochecknoglobals // set by the linker
+var (
+	version = "dev"
+	commit  string
+)
+
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"goVersion"`
+	// AriesFramework are the versions of the aries-framework-go modules, by module path.
+	AriesFramework map[string]string `json:"ariesFramework,omitempty"`
+}
+
+// GetVersionCmd returns the Cobra version command, which prints the build information of the mediator.
+func GetVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the mediator",
+		Long:  "Print the version of the mediator, the Go version and the aries-framework-go versions it's built with, as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := json.MarshalIndent(getBuildInfo(debug.ReadBuildInfo()), "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal build info : %w", err)
+			}
+
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+
+			return err
+		},
+	}
+}
+
+func getBuildInfo(info *debug.BuildInfo, ok bool) *buildInfo {
+	b := &buildInfo{Version: version, Commit: commit, GoVersion: runtime.Version()}
+
+	if !ok {
+		return b
+	}
+
+	for _, dep := range info.Deps {
+		if !strings.HasPrefix(dep.Path, ariesModulePrefix) {
+			continue
+		}
+
+		if b.AriesFramework == nil {
+			b.AriesFramework = map[string]string{}
+		}
+
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		b.AriesFramework[dep.Path] = dep.Version
+	}
+
+	return b
+}
@@ -0,0 +1,55 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versioncmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionCmd(t *testing.T) {
+	cmd := GetVersionCmd()
+	out := &bytes.Buffer{}
+
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+
+	info := &buildInfo{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), info))
+	require.Equal(t, "dev", info.Version)
+	require.Equal(t, runtime.Version(), info.GoVersion)
+}
+
+func TestGetBuildInfo(t *testing.T) {
+	t.Run("aries framework modules", func(t *testing.T) {
+		info := getBuildInfo(&debug.BuildInfo{Deps: []*debug.Module{
+			{Path: "github.com/hyperledger/aries-framework-go", Version: "v0.1.9"},
+			{
+				Path: "github.com/hyperledger/aries-framework-go/spi", Version: "v0.0.1",
+				Replace: &debug.Module{Path: "github.com/hyperledger/aries-framework-go/spi", Version: "v0.0.2"},
+			},
+			{Path: "github.com/spf13/cobra", Version: "v1.1.3"},
+		}}, true)
+
+		require.Equal(t, map[string]string{
+			"github.com/hyperledger/aries-framework-go":     "v0.1.9",
+			"github.com/hyperledger/aries-framework-go/spi": "v0.0.2",
+		}, info.AriesFramework)
+	})
+
+	t.Run("no build info", func(t *testing.T) {
+		info := getBuildInfo(nil, false)
+		require.Equal(t, "dev", info.Version)
+		require.Nil(t, info.AriesFramework)
+	})
+}
@@ -0,0 +1,233 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/mediator"
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+// routeKeyPrefix is the prefix of the keys the mediator service saves the routed recipient keys with, in its store.
+const routeKeyPrefix = "route-"
+
+// Mediation is the mediation granted over a connection: the messages forwarded to the recipient keys are routed to
+// the other party of the connection.
+type Mediation struct {
+	ConnectionID  string   `json:"connectionID,omitempty"`
+	TheirDID      string   `json:"theirDID"`
+	RecipientKeys []string `json:"recipientKeys"`
+}
+
+// Connections manages the DIDComm connections of the router, and the mediation granted over them, in the aries
+// storage. It works on the storage of a running router as well as on the storage of a stopped one.
+type Connections struct {
+	recorder   *connection.Recorder
+	routeStore storage.Store
+}
+
+type connectionStorage struct {
+	persistent    storage.Provider
+	protocolState storage.Provider
+}
+
+func (s *connectionStorage) StorageProvider() storage.Provider {
+	return s.persistent
+}
+
+func (s *connectionStorage) ProtocolStateStorageProvider() storage.Provider {
+	return s.protocolState
+}
+
+// NewConnections returns the Connections of the aries persistent and protocol state storage. Listing the mediation
+// requires the persistent storage to be an enumerable.Provider.
+func NewConnections(persistent, protocolState storage.Provider) (*Connections, error) {
+	recorder, err := connection.NewRecorder(&connectionStorage{persistent: persistent, protocolState: protocolState})
+	if err != nil {
+		return nil, fmt.Errorf("open connection store : %w", err)
+	}
+
+	routeStore, err := persistent.OpenStore(mediator.Coordination)
+	if err != nil {
+		return nil, fmt.Errorf("open route store : %w", err)
+	}
+
+	return &Connections{recorder: recorder, routeStore: routeStore}, nil
+}
+
+// List returns the connection records, sorted by connection ID.
+func (c *Connections) List() ([]*connection.Record, error) {
+	records, err := c.recorder.QueryConnectionRecords()
+	if err != nil {
+		return nil, fmt.Errorf("query connections : %w", err)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ConnectionID < records[j].ConnectionID
+	})
+
+	return records, nil
+}
+
+// Get returns the connection record with the given ID. The error wraps storage.ErrDataNotFound if there's no such
+// connection.
+func (c *Connections) Get(connectionID string) (*connection.Record, error) {
+	record, err := c.recorder.GetConnectionRecord(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("get connection %s : %w", connectionID, err)
+	}
+
+	return record, nil
+}
+
+// Remove removes the connection record with the given ID. The mediation granted over the connection isn't revoked.
+func (c *Connections) Remove(connectionID string) error {
+	record, err := c.Get(connectionID)
+	if err != nil {
+		return err
+	}
+
+	if record.ThreadID == "" {
+		// the connections created for blinded routing have no DID exchange thread, so no thread ID mapping, but the
+		// recorder removes the mapping of the thread ID of the record
+		record.ThreadID = record.ConnectionID
+
+		err = c.recorder.SaveConnectionRecord(record)
+		if err != nil {
+			return fmt.Errorf("remove connection %s : %w", connectionID, err)
+		}
+	}
+
+	err = c.recorder.RemoveConnection(connectionID)
+	if err != nil {
+		return fmt.Errorf("remove connection %s : %w", connectionID, err)
+	}
+
+	return nil
+}
+
+// Mediations returns the mediation granted by the router, sorted by DID of the other party. The connection ID is
+// empty if the connection was removed.
+func (c *Connections) Mediations() ([]*Mediation, error) {
+	routes, err := c.routes()
+	if err != nil {
+		return nil, err
+	}
+
+	byDID := map[string]*Mediation{}
+
+	for recKey, theirDID := range routes {
+		m, ok := byDID[theirDID]
+		if !ok {
+			m = &Mediation{TheirDID: theirDID}
+			byDID[theirDID] = m
+		}
+
+		m.RecipientKeys = append(m.RecipientKeys, recKey)
+	}
+
+	mediations := make([]*Mediation, 0, len(byDID))
+
+	for _, m := range byDID {
+		record, e := c.recorder.GetConnectionRecordByTheirDID(m.TheirDID)
+
+		switch {
+		case e == nil:
+			m.ConnectionID = record.ConnectionID
+		case !errors.Is(e, storage.ErrDataNotFound):
+			return nil, fmt.Errorf("get connection of %s : %w", m.TheirDID, e)
+		}
+
+		sort.Strings(m.RecipientKeys)
+
+		mediations = append(mediations, m)
+	}
+
+	sort.Slice(mediations, func(i, j int) bool {
+		return mediations[i].TheirDID < mediations[j].TheirDID
+	})
+
+	return mediations, nil
+}
+
+// RevokeMediation stops routing the messages forwarded to the recipient keys of the connection with the given ID.
+// Returns the number of recipient keys removed.
+func (c *Connections) RevokeMediation(connectionID string) (int, error) {
+	record, err := c.Get(connectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	routes, err := c.routes()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for recKey, theirDID := range routes {
+		if theirDID != record.TheirDID {
+			continue
+		}
+
+		err = c.routeStore.Delete(routeKeyPrefix + recKey)
+		if err != nil {
+			return count, fmt.Errorf("delete route of %s : %w", recKey, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// routes returns the DIDs the messages forwarded to the recipient keys are routed to, by recipient key.
+func (c *Connections) routes() (map[string]string, error) {
+	it, err := enumerable.All(c.routeStore)
+	if err != nil {
+		return nil, fmt.Errorf("list routes : %w", err)
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	routes := map[string]string{}
+
+	for {
+		ok, e := it.Next()
+		if e != nil {
+			return nil, fmt.Errorf("list routes : %w", e)
+		}
+
+		if !ok {
+			return routes, nil
+		}
+
+		key, e := it.Key()
+		if e != nil {
+			return nil, fmt.Errorf("list routes : %w", e)
+		}
+
+		if !strings.HasPrefix(key, routeKeyPrefix) {
+			// grants and configs of the mediator client
+			continue
+		}
+
+		value, e := it.Value()
+		if e != nil {
+			return nil, fmt.Errorf("list routes : %w", e)
+		}
+
+		routes[strings.TrimPrefix(key, routeKeyPrefix)] = string(value)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/mediator"
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+func TestConnections(t *testing.T) {
+	persistent := enumerable.NewProvider(mem.NewProvider())
+	protocolState := enumerable.NewProvider(mem.NewProvider())
+
+	recorder, err := connection.NewRecorder(&connectionStorage{persistent: persistent, protocolState: protocolState})
+	require.NoError(t, err)
+
+	for _, record := range []*connection.Record{
+		{ConnectionID: "conn2", State: connection.StateNameCompleted, MyDID: "did:peer:my2", TheirDID: "did:peer:2"},
+		{ConnectionID: "conn1", State: connection.StateNameCompleted, MyDID: "did:peer:my1", TheirDID: "did:peer:1"},
+	} {
+		require.NoError(t, recorder.SaveConnectionRecord(record))
+	}
+
+	routeStore, err := persistent.OpenStore(mediator.Coordination)
+	require.NoError(t, err)
+
+	require.NoError(t, routeStore.Put("route-key1", []byte("did:peer:1")))
+	require.NoError(t, routeStore.Put("route-key2", []byte("did:peer:1")))
+	require.NoError(t, routeStore.Put("route-key3", []byte("did:peer:2")))
+	require.NoError(t, routeStore.Put("route-key4", []byte("did:peer:removed")))
+	require.NoError(t, routeStore.Put("grant_id", []byte("{}")))
+
+	c, err := NewConnections(persistent, protocolState)
+	require.NoError(t, err)
+
+	t.Run("list", func(t *testing.T) {
+		records, err := c.List()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "conn1", records[0].ConnectionID)
+		require.Equal(t, "conn2", records[1].ConnectionID)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		record, err := c.Get("conn1")
+		require.NoError(t, err)
+		require.Equal(t, "did:peer:1", record.TheirDID)
+
+		_, err = c.Get("unknown")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("mediation", func(t *testing.T) {
+		mediations, err := c.Mediations()
+		require.NoError(t, err)
+		require.Equal(t, []*Mediation{
+			{ConnectionID: "conn1", TheirDID: "did:peer:1", RecipientKeys: []string{"key1", "key2"}},
+			{ConnectionID: "conn2", TheirDID: "did:peer:2", RecipientKeys: []string{"key3"}},
+			{TheirDID: "did:peer:removed", RecipientKeys: []string{"key4"}},
+		}, mediations)
+	})
+
+	t.Run("revoke mediation", func(t *testing.T) {
+		count, err := c.RevokeMediation("conn1")
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		_, err = routeStore.Get("route-key1")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		_, err = routeStore.Get("route-key3")
+		require.NoError(t, err)
+
+		count, err = c.RevokeMediation("conn1")
+		require.NoError(t, err)
+		require.Equal(t, 0, count)
+
+		_, err = c.RevokeMediation("unknown")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.NoError(t, c.Remove("conn2"))
+
+		_, err := c.Get("conn2")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		err = c.Remove("conn2")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		// the mediation isn't revoked
+		mediations, err := c.Mediations()
+		require.NoError(t, err)
+		require.Len(t, mediations, 2)
+		require.Empty(t, mediations[0].ConnectionID)
+		require.Equal(t, "did:peer:2", mediations[0].TheirDID)
+	})
+}
@@ -0,0 +1,156 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// DIDCommEndpoint is an endpoint the router receives DIDComm messages at (ex: its HTTP or WebSocket inbound
+// transport), advertised in its DIDs and invitations.
+type DIDCommEndpoint struct {
+	URI string `json:"uri"`
+	// Accept are the media type profiles accepted at the endpoint. DIDComm V1 services default to the profiles of the
+	// invitation.
+	Accept []string `json:"accept,omitempty"`
+	// Priority orders the endpoints, the lowest first.
+	Priority uint `json:"priority"`
+}
+
+// SortedEndpoints returns the endpoints by priority. Endpoints with the same priority keep their order.
+func SortedEndpoints(endpoints []DIDCommEndpoint) []DIDCommEndpoint {
+	sorted := append([]DIDCommEndpoint{}, endpoints...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return sorted
+}
+
+// DIDCommV2Services returns a DIDCommMessaging service for each endpoint, with its priority. The endpoints aren't
+// listed in a single service, as the aries framework only keeps the first endpoint of a service.
+func DIDCommV2Services(endpoints []DIDCommEndpoint) []did.Service {
+	services := make([]did.Service, 0, len(endpoints))
+
+	for _, e := range SortedEndpoints(endpoints) {
+		services = append(services, did.Service{
+			ID:              uuid.New().String(),
+			Type:            vdrapi.DIDCommV2ServiceType,
+			Priority:        e.Priority,
+			ServiceEndpoint: model.NewDIDCommV2Endpoint(toDIDCommV2Endpoints([]DIDCommEndpoint{e})),
+		})
+	}
+
+	return services
+}
+
+// DIDCommV1Services returns a did-communication service for each endpoint, with its priority, for the given recipient
+// keys. The endpoints without accept list accept the given media type profiles.
+func DIDCommV1Services(endpoints []DIDCommEndpoint, recipientKeys, accept []string) []did.Service {
+	services := make([]did.Service, 0, len(endpoints))
+
+	for _, e := range SortedEndpoints(endpoints) {
+		svcAccept := e.Accept
+		if len(svcAccept) == 0 {
+			svcAccept = accept
+		}
+
+		services = append(services, did.Service{
+			ID:              uuid.New().String(),
+			Type:            vdrapi.DIDCommServiceType,
+			Priority:        e.Priority,
+			RecipientKeys:   recipientKeys,
+			ServiceEndpoint: model.NewDIDCommV1Endpoint(e.URI),
+			Accept:          svcAccept,
+		})
+	}
+
+	return services
+}
+
+// didCommV2Endpoints returns all the endpoints of a DIDCommMessaging service endpoint, which the aries framework only
+// gives the first of.
+func didCommV2Endpoints(endpoint *model.Endpoint) ([]model.DIDCommV2Endpoint, error) {
+	if endpoint.Type() != model.DIDCommV2 {
+		return nil, errors.New("not a DIDComm V2 endpoint")
+	}
+
+	b, err := endpoint.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshal endpoint : %w", err)
+	}
+
+	var endpoints []model.DIDCommV2Endpoint
+
+	err = json.Unmarshal(b, &endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal endpoint : %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// docEndpoints returns the endpoints of the DIDComm services of a did doc, in order.
+func docEndpoints(doc *did.Doc) []model.DIDCommV2Endpoint {
+	var endpoints []model.DIDCommV2Endpoint
+
+	for i := range doc.Service {
+		svc := &doc.Service[i]
+
+		if v2, err := didCommV2Endpoints(&svc.ServiceEndpoint); err == nil {
+			endpoints = append(endpoints, v2...)
+
+			continue
+		}
+
+		if uri, err := svc.ServiceEndpoint.URI(); err == nil {
+			endpoints = append(endpoints, model.DIDCommV2Endpoint{URI: uri, Accept: svc.Accept})
+		}
+	}
+
+	return endpoints
+}
+
+func toDIDCommV2Endpoints(endpoints []DIDCommEndpoint) []model.DIDCommV2Endpoint {
+	v2 := make([]model.DIDCommV2Endpoint, len(endpoints))
+
+	for i, e := range endpoints {
+		v2[i] = model.DIDCommV2Endpoint{URI: e.URI, Accept: e.Accept}
+	}
+
+	return v2
+}
+
+// sameEndpoints compares the URIs and accept lists of endpoints.
+func sameEndpoints(a, b []model.DIDCommV2Endpoint) bool {
+	return formatEndpoints(a) == formatEndpoints(b)
+}
+
+// formatEndpoints formats endpoints for logs and errors, ex: wss://example.com (accept didcomm/v2), https://example.com.
+func formatEndpoints(endpoints []model.DIDCommV2Endpoint) string {
+	formatted := make([]string, len(endpoints))
+
+	for i, e := range endpoints {
+		formatted[i] = e.URI
+
+		if len(e.Accept) > 0 {
+			formatted[i] += " (accept " + strings.Join(e.Accept, " ") + ")"
+		}
+	}
+
+	return strings.Join(formatted, ", ")
+}
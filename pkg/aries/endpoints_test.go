@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDCommEndpoints(t *testing.T) {
+	endpoints := []DIDCommEndpoint{
+		{URI: "https://example.com", Priority: 1},
+		{URI: "wss://example.com", Accept: []string{"didcomm/v2"}, Priority: 0},
+		{URI: "https://other.example.com", Priority: 1},
+	}
+
+	t.Run("sorted by priority", func(t *testing.T) {
+		sorted := SortedEndpoints(endpoints)
+		require.Equal(t, "wss://example.com", sorted[0].URI)
+		require.Equal(t, "https://example.com", sorted[1].URI)
+		require.Equal(t, "https://other.example.com", sorted[2].URI)
+		require.Equal(t, "https://example.com", endpoints[0].URI)
+	})
+
+	t.Run("DIDComm V2 services", func(t *testing.T) {
+		services := DIDCommV2Services(endpoints)
+		require.Len(t, services, 3)
+		require.Equal(t, vdrapi.DIDCommV2ServiceType, services[0].Type)
+		require.Equal(t, uint(0), services[0].Priority)
+		require.Equal(t, uint(1), services[2].Priority)
+		require.Equal(t, "wss://example.com (accept didcomm/v2), https://example.com, https://other.example.com",
+			formatEndpoints(docEndpoints(&did.Doc{Service: services})))
+
+		v1 := model.NewDIDCommV1Endpoint("https://example.com")
+
+		_, err := didCommV2Endpoints(&v1)
+		require.Error(t, err)
+	})
+
+	t.Run("DIDComm V1 services", func(t *testing.T) {
+		services := DIDCommV1Services(endpoints, []string{"did:key:z6Mk"}, []string{"didcomm/aip1"})
+		require.Len(t, services, 3)
+
+		require.Equal(t, vdrapi.DIDCommServiceType, services[0].Type)
+		require.Equal(t, uint(0), services[0].Priority)
+		require.Equal(t, []string{"didcomm/v2"}, services[0].Accept)
+		require.Equal(t, []string{"did:key:z6Mk"}, services[0].RecipientKeys)
+
+		require.Equal(t, uint(1), services[1].Priority)
+		require.Equal(t, []string{"didcomm/aip1"}, services[1].Accept)
+		require.NotEqual(t, services[1].ID, services[2].ID)
+
+		uri, err := services[1].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", uri)
+	})
+
+	t.Run("doc endpoints", func(t *testing.T) {
+		doc := &did.Doc{Service: []did.Service{
+			{ServiceEndpoint: model.NewDIDCommV2Endpoint(toDIDCommV2Endpoints(endpoints[:2]))},
+			{ServiceEndpoint: model.NewDIDCommV1Endpoint("https://v1.example.com"), Accept: []string{"didcomm/aip1"}},
+		}}
+
+		docEps := docEndpoints(doc)
+		require.Equal(t, "https://example.com, wss://example.com (accept didcomm/v2),"+
+			" https://v1.example.com (accept didcomm/aip1)", formatEndpoints(docEps))
+		require.True(t, sameEndpoints(docEps[:2], toDIDCommV2Endpoints(endpoints[:2])))
+		require.False(t, sameEndpoints(docEps[:2], toDIDCommV2Endpoints(SortedEndpoints(endpoints[:2]))))
+	})
+}
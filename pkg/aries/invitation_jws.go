@@ -0,0 +1,220 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofbandv2"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/jwkkid"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+// invitationJWSType is the typ header of signed invitations, which are JSON Web Messages.
+const invitationJWSType = "JWM"
+
+// SignInvitation signs the OOB v2 invitation with the authentication key of the public DID. Returns a compact JWS with
+// the invitation as payload and the public DID key as kid, so that wallets can check the invitation comes from the
+// router.
+func SignInvitation(ctx Ctx, inv *outofbandv2.Invitation) (string, error) {
+	docBytes, err := GetPublicDIDDoc(ctx.StorageProvider())
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := did.ParseDocument(docBytes)
+	if err != nil {
+		return "", fmt.Errorf("parse public DID doc: %w", err)
+	}
+
+	if inv.From != doc.ID {
+		return "", fmt.Errorf("invitation from %s isn't from the public DID %s", inv.From, doc.ID)
+	}
+
+	if strings.HasSuffix(string(ctx.KeyType()), "DER") {
+		return "", fmt.Errorf("signing invitations with %s keys isn't supported", ctx.KeyType())
+	}
+
+	if len(doc.Authentication) == 0 {
+		return "", fmt.Errorf("public DID %s has no authentication key", doc.ID)
+	}
+
+	vm := &doc.Authentication[0].VerificationMethod
+
+	kt, pubKey, sigVerifier, err := invitationKey(vm)
+	if err != nil {
+		return "", err
+	}
+
+	kid, err := publicDIDKeyID(ctx.StorageProvider(), pubKey.Value)
+	if err != nil {
+		return "", err
+	}
+
+	if kid == "" {
+		// the key ID wasn't saved, it's the one derived by the local KMS
+		kid, err = jwkkid.CreateKID(pubKey.Value, kt)
+		if err != nil {
+			return "", fmt.Errorf("public DID key id: %w", err)
+		}
+	}
+
+	kh, err := ctx.KMS().Get(kid)
+	if err != nil {
+		return "", fmt.Errorf("get public DID key: %w", err)
+	}
+
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return "", fmt.Errorf("marshal invitation: %w", err)
+	}
+
+	jws, err := jose.NewJWS(jose.Headers{
+		jose.HeaderKeyID: absoluteKeyID(doc.ID, vm.ID),
+		jose.HeaderType:  invitationJWSType,
+	}, nil, payload, &jwsSigner{crypto: ctx.Crypto(), kh: kh, alg: sigVerifier.Algorithm()})
+	if err != nil {
+		return "", fmt.Errorf("sign invitation: %w", err)
+	}
+
+	return jws.SerializeCompact(false)
+}
+
+// VerifyInvitation verifies an invitation signed by SignInvitation, resolving the signing DID with the given registry.
+// Returns the invitation if the signature is valid and made with an authentication key of the invitation sender.
+func VerifyInvitation(vdr vdrapi.Registry, signedInvitation string) (*outofbandv2.Invitation, error) {
+	inv := &outofbandv2.Invitation{}
+
+	_, err := jose.ParseJWS(signedInvitation, jose.SignatureVerifierFunc(
+		func(headers jose.Headers, payload, signingInput, signature []byte) error {
+			err := json.Unmarshal(payload, inv)
+			if err != nil {
+				return fmt.Errorf("unmarshal invitation: %w", err)
+			}
+
+			kid, ok := headers.KeyID()
+			if !ok {
+				return errors.New("missing kid header")
+			}
+
+			didID := strings.Split(kid, "#")[0]
+			if inv.From != didID {
+				return fmt.Errorf("invitation from %s signed by %s", inv.From, didID)
+			}
+
+			docRes, err := vdr.Resolve(didID)
+			if err != nil {
+				return fmt.Errorf("resolve %s: %w", didID, err)
+			}
+
+			vm := authenticationKey(docRes.DIDDocument, kid)
+			if vm == nil {
+				return fmt.Errorf("%s isn't an authentication key of %s", kid, didID)
+			}
+
+			_, pubKey, sigVerifier, err := invitationKey(vm)
+			if err != nil {
+				return err
+			}
+
+			if alg, _ := headers.Algorithm(); alg != sigVerifier.Algorithm() { // nolint:errcheck // checked by value
+				return fmt.Errorf("alg '%s' doesn't match the %s key", alg, kid)
+			}
+
+			return sigVerifier.Verify(pubKey, signingInput, signature)
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("verify signed invitation: %w", err)
+	}
+
+	return inv, nil
+}
+
+// invitationKey returns the key type, the public key and the signature verifier of an authentication key.
+func invitationKey(vm *did.VerificationMethod) (kms.KeyType, *verifier.PublicKey, verifier.SignatureVerifier, error) {
+	var (
+		kt  kms.KeyType
+		err error
+	)
+
+	if vm.Type == jsonWebKey2020 && vm.JSONWebKey() == nil && len(vm.Value) == ed25519.PublicKeySize {
+		// the did:key vdr creates JsonWebKey2020 verification methods with raw Ed25519 keys
+		kt = kms.ED25519Type
+	} else {
+		kt, err = KeyTypeFor(vm, did.Authentication)
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	var sigVerifier verifier.SignatureVerifier
+
+	switch kt { // nolint:exhaustive // only signing key types
+	case kms.ED25519Type:
+		sigVerifier = verifier.NewEd25519SignatureVerifier()
+	case kms.ECDSAP256TypeIEEEP1363:
+		sigVerifier = verifier.NewECDSAES256SignatureVerifier()
+	case kms.ECDSAP384TypeIEEEP1363:
+		sigVerifier = verifier.NewECDSAES384SignatureVerifier()
+	case kms.ECDSAP521TypeIEEEP1363:
+		sigVerifier = verifier.NewECDSAES521SignatureVerifier()
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported invitation signing key type %s", kt)
+	}
+
+	value, err := publicKeyBytes(vm)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return kt, &verifier.PublicKey{Type: vm.Type, Value: value, JWK: vm.JSONWebKey()}, sigVerifier, nil
+}
+
+// authenticationKey returns the authentication key of the doc with the given absolute id.
+func authenticationKey(doc *did.Doc, kid string) *did.VerificationMethod {
+	for i := range doc.Authentication {
+		vm := &doc.Authentication[i].VerificationMethod
+
+		if absoluteKeyID(doc.ID, vm.ID) == kid {
+			return vm
+		}
+	}
+
+	return nil
+}
+
+func absoluteKeyID(didID, vmID string) string {
+	if strings.HasPrefix(vmID, "#") {
+		return didID + vmID
+	}
+
+	return vmID
+}
+
+// jwsSigner signs JWS with a KMS key.
+type jwsSigner struct {
+	crypto crypto.Crypto
+	kh     interface{}
+	alg    string
+}
+
+func (s *jwsSigner) Sign(data []byte) ([]byte, error) {
+	return s.crypto.Sign(data, s.kh)
+}
+
+func (s *jwsSigner) Headers() jose.Headers {
+	return jose.Headers{jose.HeaderAlgorithm: s.alg}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofbandv2"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	mockvdr "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignInvitation(t *testing.T) {
+	newPublicDID := func(t *testing.T, method string, kt kms.KeyType) (Ctx, vdrapi.Registry, string) {
+		t.Helper()
+
+		ctx := ariesMockProvider()
+		ctx.StorageProviderValue = mem.NewProvider()
+		ctx = addRealKMS(t, ctx)
+		addRealCrypto(t, ctx)
+		ctx.KeyTypeValue = kt
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		publicDID, err := GetPublicDID(ctx, &PublicDIDConfig{
+			Method:          method,
+			WebDomain:       "example.com",
+			DIDCommEndPoint: "https://example.com/didcomm",
+		})
+		require.NoError(t, err)
+
+		if method == PublicDIDMethodKey {
+			return ctx, vdr.New(vdr.WithVDR(key.New())), publicDID
+		}
+
+		docBytes, err := GetPublicDIDDoc(ctx.StorageProvider())
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(docBytes)
+		require.NoError(t, err)
+
+		return ctx, &mockvdr.MockVDRegistry{ResolveValue: doc}, publicDID
+	}
+
+	for _, tc := range []struct {
+		method string
+		kt     kms.KeyType
+	}{
+		{PublicDIDMethodWeb, kms.ED25519Type},
+		{PublicDIDMethodWeb, kms.ECDSAP256TypeIEEEP1363},
+		{PublicDIDMethodWeb, kms.ECDSAP384TypeIEEEP1363},
+		{PublicDIDMethodKey, kms.ED25519Type},
+	} {
+		tc := tc
+
+		t.Run("success: "+tc.method+" "+string(tc.kt), func(t *testing.T) {
+			ctx, registry, publicDID := newPublicDID(t, tc.method, tc.kt)
+
+			signed, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+			require.NoError(t, err)
+
+			inv, err := VerifyInvitation(registry, signed)
+			require.NoError(t, err)
+			require.Equal(t, "inv-1", inv.ID)
+			require.Equal(t, publicDID, inv.From)
+		})
+	}
+
+	t.Run("success: KMS key IDs not derived from the keys", func(t *testing.T) {
+		ctx := ariesMockProvider()
+		ctx.StorageProviderValue = mem.NewProvider()
+		ctx = addRealKMS(t, ctx)
+		addRealCrypto(t, ctx)
+		ctx.KMSValue = &opaqueKIDKMS{KeyManager: ctx.KMSValue, kids: map[string]string{}}
+		ctx.KeyTypeValue = kms.ECDSAP256TypeIEEEP1363
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		publicDID, err := GetPublicDID(ctx, &PublicDIDConfig{
+			Method:          PublicDIDMethodWeb,
+			WebDomain:       "example.com",
+			DIDCommEndPoint: "https://example.com/didcomm",
+		})
+		require.NoError(t, err)
+
+		docBytes, err := GetPublicDIDDoc(ctx.StorageProvider())
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(docBytes)
+		require.NoError(t, err)
+
+		signed, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+		require.NoError(t, err)
+
+		_, err = VerifyInvitation(&mockvdr.MockVDRegistry{ResolveValue: doc}, signed)
+		require.NoError(t, err)
+	})
+
+	t.Run("success: key ID not saved", func(t *testing.T) {
+		ctx, registry, publicDID := newPublicDID(t, PublicDIDMethodWeb, kms.ED25519Type)
+
+		doc, err := registry.Resolve(publicDID)
+		require.NoError(t, err)
+
+		pubKey, err := publicKeyBytes(&doc.DIDDocument.Authentication[0].VerificationMethod)
+		require.NoError(t, err)
+
+		store, err := ctx.StorageProvider().OpenStore(storeName)
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(storeKeyIDPrefix+base64.RawURLEncoding.EncodeToString(pubKey)))
+
+		signed, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+		require.NoError(t, err)
+
+		_, err = VerifyInvitation(registry, signed)
+		require.NoError(t, err)
+	})
+
+	t.Run("fail: tampered invitation", func(t *testing.T) {
+		ctx, registry, publicDID := newPublicDID(t, PublicDIDMethodWeb, kms.ED25519Type)
+
+		signed, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+		require.NoError(t, err)
+
+		parts := strings.Split(signed, ".")
+
+		other, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-2", From: publicDID})
+		require.NoError(t, err)
+
+		_, err = VerifyInvitation(registry, parts[0]+"."+strings.Split(other, ".")[1]+"."+parts[2])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify signed invitation")
+	})
+
+	t.Run("fail: not from the public DID", func(t *testing.T) {
+		ctx, _, _ := newPublicDID(t, PublicDIDMethodWeb, kms.ED25519Type)
+
+		_, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: "did:web:phishing.example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't from the public DID")
+	})
+
+	t.Run("fail: DER key type", func(t *testing.T) {
+		ctx, _, publicDID := newPublicDID(t, PublicDIDMethodWeb, kms.ECDSAP256TypeDER)
+
+		_, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "signing invitations with ECDSAP256DER keys isn't supported")
+	})
+
+	t.Run("fail: no public DID", func(t *testing.T) {
+		ctx := ariesMockProvider()
+		ctx.StorageProviderValue = mem.NewProvider()
+
+		_, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: "did:web:example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get public DID doc")
+	})
+
+	t.Run("fail: signed by another DID", func(t *testing.T) {
+		ctx, _, publicDID := newPublicDID(t, PublicDIDMethodWeb, kms.ED25519Type)
+
+		signed, err := SignInvitation(ctx, &outofbandv2.Invitation{ID: "inv-1", From: publicDID})
+		require.NoError(t, err)
+
+		otherDoc := &did.Doc{ID: "did:web:example.com", Context: []string{did.ContextV1}}
+
+		_, err = VerifyInvitation(&mockvdr.MockVDRegistry{ResolveValue: otherDoc}, signed)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't an authentication key of did:web:example.com")
+	})
+
+	t.Run("fail: not a JWS", func(t *testing.T) {
+		_, err := VerifyInvitation(&mockvdr.MockVDRegistry{}, "not a JWS")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify signed invitation")
+	})
+}
+
+// opaqueKIDKMS is a KMS with key IDs that aren't derived from the keys, like webkms.
+type opaqueKIDKMS struct {
+	kms.KeyManager
+	kids map[string]string
+}
+
+func (k *opaqueKIDKMS) CreateAndExportPubKeyBytes(kt kms.KeyType) (string, []byte, error) {
+	kid, pubKey, err := k.KeyManager.CreateAndExportPubKeyBytes(kt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opaque := fmt.Sprintf("key-%d", len(k.kids))
+	k.kids[opaque] = kid
+
+	return opaque, pubKey, nil
+}
+
+func (k *opaqueKIDKMS) Get(kid string) (interface{}, error) {
+	return k.KeyManager.Get(k.kids[kid])
+}
@@ -0,0 +1,112 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+// Verification method types.
+const (
+	ed25519VerificationKey2018 = "Ed25519VerificationKey2018"
+	ed25519VerificationKey2020 = "Ed25519VerificationKey2020"
+	x25519KeyAgreementKey2019  = "X25519KeyAgreementKey2019"
+	x25519KeyAgreementKey2020  = "X25519KeyAgreementKey2020"
+	bls12381G2Key2020          = "Bls12381G2Key2020"
+	jsonWebKey2020             = "JsonWebKey2020"
+	jsonWebKey2020Alt          = "JSONWebKey2020" // spelling used by some aries mocks and wallets.
+)
+
+// JWK curves.
+const (
+	crvEd25519    = "Ed25519"
+	crvX25519     = "X25519"
+	crvP256       = "P-256"
+	crvP384       = "P-384"
+	crvP521       = "P-521"
+	crvBLS12381G2 = "BLS12381_G2"
+)
+
+// nolint:gochecknoglobals // curve to key type translation tables
+var (
+	signingCurves = map[string]kms.KeyType{
+		crvEd25519:    kms.ED25519Type,
+		crvP256:       kms.ECDSAP256TypeIEEEP1363,
+		crvP384:       kms.ECDSAP384TypeIEEEP1363,
+		crvP521:       kms.ECDSAP521TypeIEEEP1363,
+		crvBLS12381G2: kms.BLS12381G2Type,
+	}
+
+	keyAgreementCurves = map[string]kms.KeyType{
+		crvX25519: kms.X25519ECDHKWType,
+		crvP256:   kms.NISTP256ECDHKWType,
+		crvP384:   kms.NISTP384ECDHKWType,
+		crvP521:   kms.NISTP521ECDHKWType,
+	}
+)
+
+// KeyTypeFor returns the kms key type the router needs to create to match the given verification method, when used
+// for the given relationship. Returns an error if the router can't create a matching key.
+func KeyTypeFor(vm *did.VerificationMethod, relationship did.VerificationRelationship) (kms.KeyType, error) {
+	isKeyAgreement := relationship == did.KeyAgreement
+
+	switch vm.Type {
+	case ed25519VerificationKey2018, ed25519VerificationKey2020:
+		if !isKeyAgreement {
+			return kms.ED25519Type, nil
+		}
+	case x25519KeyAgreementKey2019, x25519KeyAgreementKey2020:
+		if isKeyAgreement {
+			return kms.X25519ECDHKWType, nil
+		}
+	case bls12381G2Key2020:
+		if !isKeyAgreement {
+			return kms.BLS12381G2Type, nil
+		}
+	case jsonWebKey2020, jsonWebKey2020Alt:
+		return keyTypeForJWK(vm, isKeyAgreement)
+	}
+
+	return "", fmt.Errorf("unsupported %s key type '%s'", relationshipName(isKeyAgreement), vm.Type)
+}
+
+func keyTypeForJWK(vm *did.VerificationMethod, isKeyAgreement bool) (kms.KeyType, error) {
+	j := vm.JSONWebKey()
+	if j == nil {
+		// the jwk may only be present as the raw value of the verification method
+		j = &jwk.JWK{}
+
+		err := j.UnmarshalJSON(vm.Value)
+		if err != nil {
+			return "", fmt.Errorf("missing jwk in %s verification method '%s'", relationshipName(isKeyAgreement), vm.ID)
+		}
+	}
+
+	curves := signingCurves
+	if isKeyAgreement {
+		curves = keyAgreementCurves
+	}
+
+	kt, ok := curves[j.Crv]
+	if !ok {
+		return "", fmt.Errorf("unsupported %s jwk curve '%s'", relationshipName(isKeyAgreement), j.Crv)
+	}
+
+	return kt, nil
+}
+
+func relationshipName(isKeyAgreement bool) string {
+	if isKeyAgreement {
+		return "key agreement"
+	}
+
+	return "verification"
+}
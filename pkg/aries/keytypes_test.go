@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyTypeFor(t *testing.T) {
+	t.Run("verification method types", func(t *testing.T) {
+		tests := []struct {
+			vmType   string
+			rel      did.VerificationRelationship
+			expected kms.KeyType
+			errMsg   string
+		}{
+			{vmType: "Ed25519VerificationKey2018", rel: did.Authentication, expected: kms.ED25519Type},
+			{vmType: "Ed25519VerificationKey2020", rel: did.VerificationRelationshipGeneral, expected: kms.ED25519Type},
+			{vmType: "X25519KeyAgreementKey2019", rel: did.KeyAgreement, expected: kms.X25519ECDHKWType},
+			{vmType: "X25519KeyAgreementKey2020", rel: did.KeyAgreement, expected: kms.X25519ECDHKWType},
+			{vmType: "Bls12381G2Key2020", rel: did.AssertionMethod, expected: kms.BLS12381G2Type},
+			{vmType: "Bls12381G2Key2020", rel: did.KeyAgreement, errMsg: "unsupported key agreement key type"},
+			{vmType: "Ed25519VerificationKey2018", rel: did.KeyAgreement, errMsg: "unsupported key agreement key type"},
+			{vmType: "X25519KeyAgreementKey2019", rel: did.Authentication, errMsg: "unsupported verification key type"},
+			{vmType: "Secp256k1VerificationKey2018", rel: did.Authentication, errMsg: "unsupported verification key type"},
+			{vmType: "JsonWebKey2020", rel: did.Authentication, errMsg: "missing jwk"},
+		}
+
+		for _, tc := range tests {
+			kt, err := KeyTypeFor(&did.VerificationMethod{ID: "#key-1", Type: tc.vmType}, tc.rel)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+
+				continue
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, kt)
+		}
+	})
+
+	t.Run("jwk curves", func(t *testing.T) {
+		tests := []struct {
+			key      interface{}
+			rel      did.VerificationRelationship
+			expected kms.KeyType
+			errMsg   string
+		}{
+			{key: ecPubKey(t, elliptic.P256()), rel: did.Authentication, expected: kms.ECDSAP256TypeIEEEP1363},
+			{key: ecPubKey(t, elliptic.P384()), rel: did.Authentication, expected: kms.ECDSAP384TypeIEEEP1363},
+			{key: ecPubKey(t, elliptic.P521()), rel: did.Authentication, expected: kms.ECDSAP521TypeIEEEP1363},
+			{key: edPubKey(t), rel: did.Authentication, expected: kms.ED25519Type},
+			{key: ecPubKey(t, elliptic.P256()), rel: did.KeyAgreement, expected: kms.NISTP256ECDHKWType},
+			{key: ecPubKey(t, elliptic.P384()), rel: did.KeyAgreement, expected: kms.NISTP384ECDHKWType},
+			{key: ecPubKey(t, elliptic.P521()), rel: did.KeyAgreement, expected: kms.NISTP521ECDHKWType},
+			{key: edPubKey(t), rel: did.KeyAgreement, errMsg: "unsupported key agreement jwk curve 'Ed25519'"},
+		}
+
+		for _, tc := range tests {
+			j, err := jwksupport.JWKFromKey(tc.key)
+			require.NoError(t, err)
+
+			vm, err := did.NewVerificationMethodFromJWK("#key-1", "JsonWebKey2020", "", j)
+			require.NoError(t, err)
+
+			kt, err := KeyTypeFor(vm, tc.rel)
+			if tc.errMsg != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+
+				continue
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, kt)
+		}
+	})
+
+	t.Run("X25519 jwk", func(t *testing.T) {
+		j, err := jwksupport.JWKFromX25519Key(make([]byte, 32))
+		require.NoError(t, err)
+
+		vm, err := did.NewVerificationMethodFromJWK("#key-1", "JsonWebKey2020", "", j)
+		require.NoError(t, err)
+
+		kt, err := KeyTypeFor(vm, did.KeyAgreement)
+		require.NoError(t, err)
+		require.Equal(t, kms.X25519ECDHKWType, kt)
+
+		_, err = KeyTypeFor(vm, did.Authentication)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported verification jwk curve 'X25519'")
+	})
+}
+
+func ecPubKey(t *testing.T, curve elliptic.Curve) *ecdsa.PublicKey {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	require.NoError(t, err)
+
+	return &priv.PublicKey
+}
+
+func edPubKey(t *testing.T) ed25519.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return pub
+}
@@ -8,15 +8,22 @@ package aries
 
 import "github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 
+// InboundMsg inbound didcomm msg along with the DIDs of the connection it was received on.
+type InboundMsg struct {
+	service.DIDCommMsg
+	MyDID    string
+	TheirDID string
+}
+
 // MsgService msg service implementation.
 type MsgService struct {
 	svcName string
 	msgType string
-	msgCh   chan service.DIDCommMsg
+	msgCh   chan InboundMsg
 }
 
 // NewMsgSvc new msg service.
-func NewMsgSvc(name, msgType string, msgCh chan service.DIDCommMsg) *MsgService {
+func NewMsgSvc(name, msgType string, msgCh chan InboundMsg) *MsgService {
 	return &MsgService{
 		svcName: name,
 		msgType: msgType,
@@ -35,9 +42,16 @@ func (m *MsgService) Accept(msgType string, _ []string) bool {
 }
 
 // HandleInbound handles inbound didcomm msg.
-func (m *MsgService) HandleInbound(msg service.DIDCommMsg, _ service.DIDCommContext) (string, error) {
+func (m *MsgService) HandleInbound(msg service.DIDCommMsg, ctx service.DIDCommContext) (string, error) {
+	inbound := InboundMsg{DIDCommMsg: msg}
+
+	if ctx != nil {
+		inbound.MyDID = ctx.MyDID()
+		inbound.TheirDID = ctx.TheirDID()
+	}
+
 	go func() {
-		m.msgCh <- msg
+		m.msgCh <- inbound
 	}()
 
 	return "", nil
@@ -17,7 +17,7 @@ import (
 func TestNewMsgSvc(t *testing.T) {
 	name := "msg-123"
 	msgType := "http://example.com/message/test"
-	msgCh := make(chan service.DIDCommMsg)
+	msgCh := make(chan InboundMsg)
 
 	msgSvc := NewMsgSvc(name, msgType, msgCh)
 	require.Equal(t, name, msgSvc.Name())
@@ -44,4 +44,18 @@ func TestNewMsgSvc(t *testing.T) {
 	case <-time.After(5 * time.Second):
 		require.Fail(t, "tests are not validated due to timeout")
 	}
+
+	t.Run("forwards connection DIDs", func(t *testing.T) {
+		_, err = msgSvc.HandleInbound(msg, service.NewDIDCommContext("did:example:me", "did:example:them", nil))
+		require.NoError(t, err)
+
+		select {
+		case inbound := <-msgCh:
+			require.Equal(t, "did:example:me", inbound.MyDID)
+			require.Equal(t, "did:example:them", inbound.TheirDID)
+			require.Equal(t, msgType, inbound.Type())
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "tests are not validated due to timeout")
+		}
+	})
 }
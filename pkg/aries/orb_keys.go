@@ -0,0 +1,276 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/sidetree/api"
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/sidetree-core-go/pkg/commitment"
+	"github.com/trustbloc/sidetree-core-go/pkg/jws"
+	"github.com/trustbloc/sidetree-core-go/pkg/util/pubkey"
+)
+
+const (
+	storeUpdateKeyIDKey       = "update-key-id"
+	storeRecoveryKeyIDKey     = "recovery-key-id"
+	storeNextUpdateKeyIDKey   = "next-update-key-id"
+	storeNextRecoveryKeyIDKey = "next-recovery-key-id"
+
+	edDSAAlg = "EdDSA"
+	// orbMultihashCode is the multihash code of the commitments of the orb VDR, sha2-256.
+	orbMultihashCode = 18
+)
+
+// orbKeys manages the update and recovery keys of the public orb DID. The keys are ed25519 keys kept in the KMS, with
+// their key IDs saved alongside the public DID.
+//
+// The next keys of an operation are saved before the operation is submitted, and become the current ones once it
+// succeeds. If the outcome of the operation is unknown, ex: it timed out, the next keys are kept until the following
+// operation, which checks whether they're committed by the DID.
+type orbKeys struct {
+	km     kms.KeyManager
+	crypto ariescrypto.Crypto
+	store  storage.Store
+
+	mu sync.Mutex
+}
+
+func newOrbKeys(ctx Ctx, store storage.Store) *orbKeys {
+	return &orbKeys{
+		km:     ctx.KMS(),
+		crypto: ctx.Crypto(),
+		store:  store,
+	}
+}
+
+// newKey creates a new ed25519 key in the KMS.
+func (k *orbKeys) newKey() (string, ed25519.PublicKey, error) {
+	kid, pubKey, err := k.km.CreateAndExportPubKeyBytes(kms.ED25519Type)
+	if err != nil {
+		return "", nil, fmt.Errorf("create orb key : %w", err)
+	}
+
+	return kid, pubKey, nil
+}
+
+// save saves the key IDs of the current update and recovery keys.
+func (k *orbKeys) save(updateKID, recoveryKID string) error {
+	if updateKID != "" {
+		err := k.store.Put(storeUpdateKeyIDKey, []byte(updateKID))
+		if err != nil {
+			return fmt.Errorf("save orb update key id : %w", err)
+		}
+	}
+
+	if recoveryKID != "" {
+		err := k.store.Put(storeRecoveryKeyIDKey, []byte(recoveryKID))
+		if err != nil {
+			return fmt.Errorf("save orb recovery key id : %w", err)
+		}
+	}
+
+	return nil
+}
+
+// commit makes the next keys created for the completed operation the current ones.
+func (k *orbKeys) commit(ot orb.OperationType) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	err := k.promote(storeNextUpdateKeyIDKey, storeUpdateKeyIDKey)
+	if err != nil {
+		return err
+	}
+
+	if ot != orb.Recover {
+		return nil
+	}
+
+	return k.promote(storeNextRecoveryKeyIDKey, storeRecoveryKeyIDKey)
+}
+
+// reconcile makes the next keys of a previous operation the current ones if the DID commits to them, ex: when the
+// operation succeeded but its response was lost.
+func (k *orbKeys) reconcile(meta *did.MethodMetadata) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, rotation := range []struct {
+		next, current, commitment string
+	}{
+		{storeNextUpdateKeyIDKey, storeUpdateKeyIDKey, meta.UpdateCommitment},
+		{storeNextRecoveryKeyIDKey, storeRecoveryKeyIDKey, meta.RecoveryCommitment},
+	} {
+		kid, err := k.keyID(rotation.next)
+		if err != nil {
+			return err
+		}
+
+		if kid == "" || rotation.commitment == "" {
+			continue
+		}
+
+		c, err := k.commitment(kid)
+		if err != nil {
+			return err
+		}
+
+		if c != rotation.commitment {
+			continue
+		}
+
+		logger.Infof("orb key %s was committed by a previous operation, making it the %s", kid, rotation.current)
+
+		err = k.promote(rotation.next, rotation.current)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// promote makes the saved next key the current one.
+func (k *orbKeys) promote(nextStoreKey, storeKey string) error {
+	kid, err := k.keyID(nextStoreKey)
+	if err != nil || kid == "" {
+		return err
+	}
+
+	err = k.store.Put(storeKey, []byte(kid))
+	if err != nil {
+		return fmt.Errorf("save orb key id '%s' : %w", storeKey, err)
+	}
+
+	err = k.store.Delete(nextStoreKey)
+	if err != nil {
+		return fmt.Errorf("delete orb key id '%s' : %w", nextStoreKey, err)
+	}
+
+	return nil
+}
+
+// keyID returns the saved key ID, or an empty one if not saved.
+func (k *orbKeys) keyID(storeKey string) (string, error) {
+	kid, err := k.store.Get(storeKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("get orb key id '%s' : %w", storeKey, err)
+	}
+
+	return string(kid), nil
+}
+
+// commitment returns the sidetree commitment of the key.
+func (k *orbKeys) commitment(kid string) (string, error) {
+	pubKey, _, err := k.km.ExportPubKeyBytes(kid)
+	if err != nil {
+		return "", fmt.Errorf("export orb key '%s' : %w", kid, err)
+	}
+
+	pubJWK, err := pubkey.GetPublicKeyJWK(ed25519.PublicKey(pubKey))
+	if err != nil {
+		return "", fmt.Errorf("orb key '%s' jwk : %w", kid, err)
+	}
+
+	c, err := commitment.GetCommitment(pubJWK, orbMultihashCode)
+	if err != nil {
+		return "", fmt.Errorf("orb key '%s' commitment : %w", kid, err)
+	}
+
+	return c, nil
+}
+
+// GetNextUpdatePublicKey creates the next update key of the DID.
+func (k *orbKeys) GetNextUpdatePublicKey(_, _ string) (crypto.PublicKey, error) {
+	return k.next(storeNextUpdateKeyIDKey)
+}
+
+// GetNextRecoveryPublicKey creates the next recovery key of the DID.
+func (k *orbKeys) GetNextRecoveryPublicKey(_, _ string) (crypto.PublicKey, error) {
+	return k.next(storeNextRecoveryKeyIDKey)
+}
+
+// next creates a next key and saves its key ID before the operation is submitted.
+func (k *orbKeys) next(nextStoreKey string) (crypto.PublicKey, error) {
+	kid, pubKey, err := k.newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	err = k.store.Put(nextStoreKey, []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("save orb key id '%s' : %w", nextStoreKey, err)
+	}
+
+	return pubKey, nil
+}
+
+// GetSigner returns the signer of the current update key for updates, or of the current recovery key otherwise.
+func (k *orbKeys) GetSigner(_ string, ot orb.OperationType, _ string) (api.Signer, error) {
+	storeKey := storeRecoveryKeyIDKey
+	if ot == orb.Update {
+		storeKey = storeUpdateKeyIDKey
+	}
+
+	kid, err := k.store.Get(storeKey)
+	if err != nil {
+		return nil, fmt.Errorf("get orb key id '%s' : %w", storeKey, err)
+	}
+
+	kh, err := k.km.Get(string(kid))
+	if err != nil {
+		return nil, fmt.Errorf("get orb key '%s' : %w", kid, err)
+	}
+
+	pubKey, _, err := k.km.ExportPubKeyBytes(string(kid))
+	if err != nil {
+		return nil, fmt.Errorf("export orb key '%s' : %w", kid, err)
+	}
+
+	pubJWK, err := pubkey.GetPublicKeyJWK(ed25519.PublicKey(pubKey))
+	if err != nil {
+		return nil, fmt.Errorf("orb key '%s' jwk : %w", kid, err)
+	}
+
+	return &kmsSigner{crypto: k.crypto, kh: kh, pubJWK: pubJWK}, nil
+}
+
+// kmsSigner signs sidetree operations with a KMS key.
+type kmsSigner struct {
+	crypto ariescrypto.Crypto
+	kh     interface{}
+	pubJWK *jws.JWK
+}
+
+func (s *kmsSigner) Sign(data []byte) ([]byte, error) {
+	return s.crypto.Sign(data, s.kh)
+}
+
+func (s *kmsSigner) Headers() jws.Headers {
+	return jws.Headers{jws.HeaderAlgorithm: edDSAAlg}
+}
+
+func (s *kmsSigner) PublicKeyJWK() *jws.JWK {
+	return s.pubJWK
+}
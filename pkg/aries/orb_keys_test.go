@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	mockprovider "github.com/hyperledger/aries-framework-go/pkg/mock/provider"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/jws"
+)
+
+func TestOrbKeys(t *testing.T) {
+	newKeys := func(t *testing.T) (*orbKeys, *mockstore.MockStore) {
+		t.Helper()
+
+		ctx := addRealKMS(t, ariesMockProvider())
+		addRealCrypto(t, ctx)
+
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+
+		return newOrbKeys(ctx, store), store
+	}
+
+	t.Run("signers use the saved keys", func(t *testing.T) {
+		keys, _ := newKeys(t)
+
+		updateKID, updateKey, err := keys.newKey()
+		require.NoError(t, err)
+
+		recoveryKID, recoveryKey, err := keys.newKey()
+		require.NoError(t, err)
+
+		require.NoError(t, keys.save(updateKID, recoveryKID))
+
+		for ot, pubKey := range map[orb.OperationType]ed25519.PublicKey{orb.Update: updateKey, orb.Recover: recoveryKey} {
+			signer, err := keys.GetSigner("did:orb:foo", ot, "")
+			require.NoError(t, err)
+			require.Equal(t, edDSAAlg, signer.Headers()[jws.HeaderAlgorithm])
+			require.NotNil(t, signer.PublicKeyJWK())
+
+			sig, err := signer.Sign([]byte("data"))
+			require.NoError(t, err)
+			require.True(t, ed25519.Verify(pubKey, []byte("data"), sig))
+		}
+	})
+
+	t.Run("commit makes the next keys current", func(t *testing.T) {
+		keys, store := newKeys(t)
+
+		require.NoError(t, keys.save("update-1", "recovery-1"))
+
+		_, err := keys.GetNextUpdatePublicKey("did:orb:foo", "")
+		require.NoError(t, err)
+
+		// saved before the operation is submitted
+		nextUpdateKID := string(store.Store[storeNextUpdateKeyIDKey].Value)
+		require.NotEmpty(t, nextUpdateKID)
+
+		require.NoError(t, keys.commit(orb.Update))
+		require.Equal(t, nextUpdateKID, string(store.Store[storeUpdateKeyIDKey].Value))
+		require.Equal(t, "recovery-1", string(store.Store[storeRecoveryKeyIDKey].Value))
+		require.NotContains(t, store.Store, storeNextUpdateKeyIDKey)
+
+		_, err = keys.GetNextUpdatePublicKey("did:orb:foo", "")
+		require.NoError(t, err)
+
+		_, err = keys.GetNextRecoveryPublicKey("did:orb:foo", "")
+		require.NoError(t, err)
+
+		nextUpdateKID = string(store.Store[storeNextUpdateKeyIDKey].Value)
+		nextRecoveryKID := string(store.Store[storeNextRecoveryKeyIDKey].Value)
+
+		require.NoError(t, keys.commit(orb.Recover))
+		require.Equal(t, nextUpdateKID, string(store.Store[storeUpdateKeyIDKey].Value))
+		require.Equal(t, nextRecoveryKID, string(store.Store[storeRecoveryKeyIDKey].Value))
+		require.NotContains(t, store.Store, storeNextRecoveryKeyIDKey)
+	})
+
+	t.Run("reconcile makes the committed next keys current", func(t *testing.T) {
+		keys, store := newKeys(t)
+
+		require.NoError(t, keys.save("update-1", "recovery-1"))
+
+		_, err := keys.GetNextUpdatePublicKey("did:orb:foo", "")
+		require.NoError(t, err)
+
+		_, err = keys.GetNextRecoveryPublicKey("did:orb:foo", "")
+		require.NoError(t, err)
+
+		nextUpdateKID := string(store.Store[storeNextUpdateKeyIDKey].Value)
+		nextRecoveryKID := string(store.Store[storeNextRecoveryKeyIDKey].Value)
+
+		updateCommitment, err := keys.commitment(nextUpdateKID)
+		require.NoError(t, err)
+
+		// the outcome of the operation was unknown, the DID doesn't commit to the next recovery key
+		require.NoError(t, keys.reconcile(&did.MethodMetadata{
+			UpdateCommitment:   updateCommitment,
+			RecoveryCommitment: "other",
+		}))
+		require.Equal(t, nextUpdateKID, string(store.Store[storeUpdateKeyIDKey].Value))
+		require.NotContains(t, store.Store, storeNextUpdateKeyIDKey)
+		require.Equal(t, "recovery-1", string(store.Store[storeRecoveryKeyIDKey].Value))
+		require.Equal(t, nextRecoveryKID, string(store.Store[storeNextRecoveryKeyIDKey].Value))
+
+		// nothing to reconcile
+		require.NoError(t, keys.reconcile(&did.MethodMetadata{}))
+	})
+
+	t.Run("fail: reconcile errors", func(t *testing.T) {
+		keys, store := newKeys(t)
+
+		require.NoError(t, store.Put(storeNextUpdateKeyIDKey, []byte("unknown")))
+
+		err := keys.reconcile(&did.MethodMetadata{UpdateCommitment: "commitment"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "export orb key 'unknown'")
+
+		store.ErrGet = errors.New("get error")
+
+		err = keys.reconcile(&did.MethodMetadata{UpdateCommitment: "commitment"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get orb key id 'next-update-key-id'")
+	})
+
+	t.Run("fail: save next key", func(t *testing.T) {
+		keys, store := newKeys(t)
+
+		store.ErrPut = errors.New("put error")
+
+		_, err := keys.GetNextRecoveryPublicKey("did:orb:foo", "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "save orb key id 'next-recovery-key-id'")
+	})
+
+	t.Run("fail: no saved key", func(t *testing.T) {
+		keys, _ := newKeys(t)
+
+		_, err := keys.GetSigner("did:orb:foo", orb.Update, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get orb key id 'update-key-id'")
+	})
+
+	t.Run("fail: key not in KMS", func(t *testing.T) {
+		keys, _ := newKeys(t)
+
+		require.NoError(t, keys.save("", "unknown"))
+
+		_, err := keys.GetSigner("did:orb:foo", orb.Recover, "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get orb key 'unknown'")
+	})
+}
+
+func addRealCrypto(t *testing.T, ctx *mockprovider.Provider) {
+	t.Helper()
+
+	var err error
+
+	ctx.CryptoValue, err = tinkcrypto.New()
+	require.NoError(t, err)
+}
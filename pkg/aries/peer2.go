@@ -0,0 +1,303 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/peer"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// did:peer numalgo 2, see https://identity.foundation/peer-did-method-spec/#method-2-multiple-inception-key-without-doc
+const (
+	peer2Prefix = "did:peer:2"
+
+	peer2KeyAgreement   = 'E'
+	peer2Authentication = 'V'
+	peer2ServicePurpose = 'S'
+
+	peer2DIDCommMessagingAbbr = "dm"
+)
+
+// peer2Service is the abbreviated service encoding of did:peer numalgo 2.
+type peer2Service struct {
+	Type        string   `json:"t"`
+	Endpoint    string   `json:"s"`
+	RoutingKeys []string `json:"r,omitempty"`
+	Accept      []string `json:"a,omitempty"`
+}
+
+// PeerVDR is the did:peer VDR of the router: it resolves numalgo 2 DIDs by decoding them, and delegates everything
+// else to the aries did:peer VDR.
+type PeerVDR struct {
+	*peer.VDR
+}
+
+// NewPeerVDR returns a new PeerVDR using the given storage provider for the aries did:peer VDR.
+func NewPeerVDR(provider storage.Provider) (*PeerVDR, error) {
+	v, err := peer.New(provider)
+	if err != nil {
+		return nil, fmt.Errorf("create peer vdr : %w", err)
+	}
+
+	return &PeerVDR{VDR: v}, nil
+}
+
+// Read resolves the did:peer DID.
+func (v *PeerVDR) Read(didID string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	if !strings.HasPrefix(didID, peer2Prefix) {
+		return v.VDR.Read(didID, opts...)
+	}
+
+	doc, err := resolvePeer2(didID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s : %w", didID, err)
+	}
+
+	return &did.DocResolution{DIDDocument: doc}, nil
+}
+
+// createPeer2 creates a did:peer numalgo 2 DID from the authentication and key agreement keys and the DIDComm V2
+// services of the given did doc template.
+func createPeer2(doc *did.Doc) (*did.DocResolution, error) {
+	var sb strings.Builder
+
+	sb.WriteString(peer2Prefix)
+
+	for _, ka := range doc.KeyAgreement {
+		fp, err := vmFingerprint(&ka.VerificationMethod)
+		if err != nil {
+			return nil, fmt.Errorf("key agreement : %w", err)
+		}
+
+		sb.WriteString("." + string(peer2KeyAgreement) + fp)
+	}
+
+	for _, auth := range doc.Authentication {
+		fp, err := vmFingerprint(&auth.VerificationMethod)
+		if err != nil {
+			return nil, fmt.Errorf("authentication : %w", err)
+		}
+
+		sb.WriteString("." + string(peer2Authentication) + fp)
+	}
+
+	for i := range doc.Service {
+		encoded, err := encodePeer2Service(&doc.Service[i])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range encoded {
+			sb.WriteString("." + string(peer2ServicePurpose) + e)
+		}
+	}
+
+	resolved, err := resolvePeer2(sb.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &did.DocResolution{DIDDocument: resolved}, nil
+}
+
+func vmFingerprint(vm *did.VerificationMethod) (string, error) {
+	var didKey string
+
+	switch {
+	case vm.JSONWebKey() != nil:
+		var err error
+
+		didKey, _, err = fingerprint.CreateDIDKeyByJwk(vm.JSONWebKey())
+		if err != nil {
+			return "", fmt.Errorf("fingerprint of '%s' : %w", vm.ID, err)
+		}
+	case vm.Type == ed25519VerificationKey2018:
+		didKey, _ = fingerprint.CreateDIDKeyByCode(fingerprint.ED25519PubKeyMultiCodec, vm.Value)
+	case vm.Type == x25519KeyAgreementKey2019:
+		didKey, _ = fingerprint.CreateDIDKeyByCode(fingerprint.X25519PubKeyMultiCodec, vm.Value)
+	default:
+		return "", fmt.Errorf("unsupported verification method type '%s'", vm.Type)
+	}
+
+	return strings.TrimPrefix(didKey, "did:key:"), nil
+}
+
+// encodePeer2Service encodes a service, as a service per endpoint for a DIDCommMessaging service with several
+// endpoints, since the abbreviated encoding has a single endpoint.
+func encodePeer2Service(svc *did.Service) ([]string, error) {
+	if svc.Type != vdrapi.DIDCommV2ServiceType {
+		uri, err := svc.ServiceEndpoint.URI()
+		if err != nil {
+			return nil, fmt.Errorf("service endpoint : %w", err)
+		}
+
+		encoded, err := encodePeer2(&peer2Service{Type: svc.Type, Endpoint: uri})
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{encoded}, nil
+	}
+
+	endpoints, err := didCommV2Endpoints(&svc.ServiceEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("service endpoint : %w", err)
+	}
+
+	var services []string
+
+	for _, e := range endpoints {
+		encoded, err := encodePeer2(&peer2Service{
+			Type:        peer2DIDCommMessagingAbbr,
+			Endpoint:    e.URI,
+			RoutingKeys: e.RoutingKeys,
+			Accept:      e.Accept,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		services = append(services, encoded)
+	}
+
+	return services, nil
+}
+
+func encodePeer2(ps *peer2Service) (string, error) {
+	b, err := json.Marshal(ps)
+	if err != nil {
+		return "", fmt.Errorf("marshal service : %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func resolvePeer2(didID string) (*did.Doc, error) {
+	elements := strings.Split(strings.TrimPrefix(didID, peer2Prefix), ".")
+	if len(elements) < 2 || elements[0] != "" {
+		return nil, errors.New("invalid did:peer numalgo 2 DID")
+	}
+
+	doc := &did.Doc{Context: []string{did.ContextV1}, ID: didID}
+
+	for _, element := range elements[1:] {
+		if element == "" {
+			return nil, errors.New("empty did:peer numalgo 2 element")
+		}
+
+		switch element[0] {
+		case peer2KeyAgreement, peer2Authentication:
+			vm, err := peer2VerificationMethod(didID, len(doc.VerificationMethod)+1, element[1:])
+			if err != nil {
+				return nil, err
+			}
+
+			doc.VerificationMethod = append(doc.VerificationMethod, *vm)
+
+			if element[0] == peer2KeyAgreement {
+				doc.KeyAgreement = append(doc.KeyAgreement, *did.NewReferencedVerification(vm, did.KeyAgreement))
+			} else {
+				doc.Authentication = append(doc.Authentication, *did.NewReferencedVerification(vm, did.Authentication))
+			}
+		case peer2ServicePurpose:
+			svc, err := peer2DIDService(didID, len(doc.Service), element[1:])
+			if err != nil {
+				return nil, err
+			}
+
+			doc.Service = append(doc.Service, *svc)
+		default:
+			return nil, fmt.Errorf("unsupported did:peer numalgo 2 purpose '%c'", element[0])
+		}
+	}
+
+	return doc, nil
+}
+
+func peer2VerificationMethod(didID string, index int, fp string) (*did.VerificationMethod, error) {
+	pubKey, code, err := fingerprint.PubKeyFromFingerprint(fp)
+	if err != nil {
+		return nil, fmt.Errorf("parse key '%s' : %w", fp, err)
+	}
+
+	id := fmt.Sprintf("%s#key-%d", didID, index)
+
+	var curve elliptic.Curve
+
+	switch code {
+	case fingerprint.ED25519PubKeyMultiCodec:
+		return did.NewVerificationMethodFromBytes(id, ed25519VerificationKey2018, didID, pubKey), nil
+	case fingerprint.X25519PubKeyMultiCodec:
+		return did.NewVerificationMethodFromBytes(id, x25519KeyAgreementKey2019, didID, pubKey), nil
+	case fingerprint.P256PubKeyMultiCodec:
+		curve = elliptic.P256()
+	case fingerprint.P384PubKeyMultiCodec:
+		curve = elliptic.P384()
+	case fingerprint.P521PubKeyMultiCodec:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported key multicodec 0x%x", code)
+	}
+
+	x, y := elliptic.UnmarshalCompressed(curve, pubKey)
+	if x == nil {
+		return nil, fmt.Errorf("invalid compressed %s key", curve.Params().Name)
+	}
+
+	j, err := jwksupport.JWKFromKey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y})
+	if err != nil {
+		return nil, fmt.Errorf("create jwk : %w", err)
+	}
+
+	return did.NewVerificationMethodFromJWK(id, jsonWebKey2020, didID, j)
+}
+
+func peer2DIDService(didID string, index int, encoded string) (*did.Service, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode service : %w", err)
+	}
+
+	ps := peer2Service{}
+
+	err = json.Unmarshal(b, &ps)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal service : %w", err)
+	}
+
+	id := didID + "#service"
+	if index > 0 {
+		id = fmt.Sprintf("%s-%d", id, index)
+	}
+
+	if ps.Type != peer2DIDCommMessagingAbbr {
+		return &did.Service{ID: id, Type: ps.Type, ServiceEndpoint: model.NewDIDCommV1Endpoint(ps.Endpoint)}, nil
+	}
+
+	return &did.Service{
+		ID:   id,
+		Type: vdrapi.DIDCommV2ServiceType,
+		ServiceEndpoint: model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{{
+			URI:         ps.Endpoint,
+			Accept:      ps.Accept,
+			RoutingKeys: ps.RoutingKeys,
+		}}),
+	}, nil
+}
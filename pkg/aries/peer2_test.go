@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeer2(t *testing.T) {
+	keyTypes := []struct {
+		name             string
+		keyType          kms.KeyType
+		keyAgreementType kms.KeyType
+	}{
+		{name: "ed25519/x25519", keyType: kms.ED25519Type, keyAgreementType: kms.X25519ECDHKWType},
+		{name: "P-256", keyType: kms.ECDSAP256TypeIEEEP1363, keyAgreementType: kms.NISTP256ECDHKWType},
+		{name: "P-384", keyType: kms.ECDSAP384TypeIEEEP1363, keyAgreementType: kms.NISTP384ECDHKWType},
+	}
+
+	for _, tc := range keyTypes {
+		t.Run("create and resolve "+tc.name, func(t *testing.T) {
+			ctx := addRealKMS(t, ariesMockProvider())
+
+			auth, err := CreateVerification(ctx.KMS(), "#key-1", tc.keyType, did.Authentication)
+			require.NoError(t, err)
+
+			ka, err := CreateVerification(ctx.KMS(), "#key-2", tc.keyAgreementType, did.KeyAgreement)
+			require.NoError(t, err)
+
+			docRes, err := createPeer2(&did.Doc{
+				Authentication: []did.Verification{*auth},
+				KeyAgreement:   []did.Verification{*ka},
+				Service: []did.Service{{
+					ID:   "service",
+					Type: vdrapi.DIDCommV2ServiceType,
+					ServiceEndpoint: model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{{
+						URI:    "https://example.com/didcomm",
+						Accept: []string{"didcomm/v2"},
+					}}),
+				}},
+			})
+			require.NoError(t, err)
+
+			doc := docRes.DIDDocument
+			require.True(t, strings.HasPrefix(doc.ID, "did:peer:2.E"))
+			require.Len(t, doc.VerificationMethod, 2)
+			require.Len(t, doc.KeyAgreement, 1)
+			require.Len(t, doc.Authentication, 1)
+			require.Equal(t, doc.ID+"#service", doc.Service[0].ID)
+
+			kt, err := KeyTypeFor(&doc.KeyAgreement[0].VerificationMethod, did.KeyAgreement)
+			require.NoError(t, err)
+			require.Equal(t, tc.keyAgreementType, kt)
+
+			kt, err = KeyTypeFor(&doc.Authentication[0].VerificationMethod, did.Authentication)
+			require.NoError(t, err)
+			require.Equal(t, tc.keyType, kt)
+
+			uri, err := doc.Service[0].ServiceEndpoint.URI()
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/didcomm", uri)
+
+			v, err := NewPeerVDR(mockstore.NewMockStoreProvider())
+			require.NoError(t, err)
+
+			resolved, err := v.Read(doc.ID)
+			require.NoError(t, err)
+			require.Equal(t, doc.ID, resolved.DIDDocument.ID)
+		})
+	}
+
+	t.Run("a service per endpoint", func(t *testing.T) {
+		docRes, err := createPeer2(&did.Doc{
+			Service: []did.Service{{
+				ID:   "service",
+				Type: vdrapi.DIDCommV2ServiceType,
+				ServiceEndpoint: model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{
+					{URI: "https://example.com/didcomm"},
+					{URI: "wss://example.com/didcomm", Accept: []string{"didcomm/v2"}},
+				}),
+			}},
+		})
+		require.NoError(t, err)
+
+		doc := docRes.DIDDocument
+		require.Len(t, doc.Service, 2)
+		require.Equal(t, doc.ID+"#service", doc.Service[0].ID)
+		require.Equal(t, doc.ID+"#service-1", doc.Service[1].ID)
+		require.Equal(t, "https://example.com/didcomm, wss://example.com/didcomm (accept didcomm/v2)",
+			formatEndpoints(docEndpoints(doc)))
+	})
+
+	t.Run("fail: invalid DIDs", func(t *testing.T) {
+		for _, didID := range []string{"did:peer:2", "did:peer:2.", "did:peer:2.Xfoo", "did:peer:2.Ez6bad", "did:peer:2.S!!"} {
+			_, err := resolvePeer2(didID)
+			require.Error(t, err, didID)
+		}
+	})
+
+	t.Run("fail: unsupported verification method", func(t *testing.T) {
+		_, err := createPeer2(&did.Doc{
+			KeyAgreement: []did.Verification{{VerificationMethod: did.VerificationMethod{ID: "#key-1", Type: "foo"}}},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported verification method type")
+	})
+
+	t.Run("read delegates other did:peer DIDs", func(t *testing.T) {
+		v, err := NewPeerVDR(mockstore.NewMockStoreProvider())
+		require.NoError(t, err)
+
+		_, err = v.Read("did:peer:1zQmNotFound")
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "numalgo 2")
+	})
+}
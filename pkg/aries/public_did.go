@@ -7,16 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package aries
 
 import (
-	"crypto/ed25519"
-	"crypto/rand"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
-	"github.com/hyperledger/aries-framework-go/pkg/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
@@ -24,16 +26,66 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util/jwkkid"
 	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/mediator/pkg/lease"
+)
+
+// Public DID methods.
+const (
+	PublicDIDMethodOrb  = "orb"
+	PublicDIDMethodWeb  = "web"
+	PublicDIDMethodKey  = "key"
+	PublicDIDMethodPeer = "peer"
+)
+
+const (
+	storeName      = "router-invitation-did"
+	storeDIDKey    = "did-value"
+	storeDIDDocKey = "did-doc"
+	// storeKeyIDPrefix prefixes the KMS key IDs of the public DID keys, saved by public key.
+	storeKeyIDPrefix = "key-id-"
+)
+
+const (
+	publicDIDLease = "public-did"
+	// publicDIDLeaseTTL is how long the instance creating the public DID can go without renewing its lease before another
+	// instance may take over.
+	publicDIDLeaseTTL = 2 * time.Minute
+	// publicDIDLeaseWait is how long an instance waits for another one to create the public DID.
+	publicDIDLeaseWait = 2 * publicDIDLeaseTTL
 )
 
 const (
-	storeName   = "router-invitation-did"
-	storeDIDKey = "did-value"
+	resolveInitialInterval = time.Second
+	resolveMaxInterval     = 30 * time.Second
 )
 
+// DefaultWebDIDDocPath is the URL path of the doc of a did:web DID without path.
+const DefaultWebDIDDocPath = "/.well-known/did.json"
+
+var logger = log.New("mediator/aries")
+
 type orbVDR interface {
 	Create(did *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+	Read(did string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+	Update(did *did.Doc, opts ...vdrapi.DIDMethodOption) error
+	Deactivate(did string, opts ...vdrapi.DIDMethodOption) error
+}
+
+// didCreator creates the public DID of a given method from the did doc template.
+type didCreator interface {
+	Create(did *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+}
+
+// didManager is implemented by the creators of DID methods supporting updates, recovery and deactivation.
+type didManager interface {
+	Read(didID string) (*did.DocResolution, error)
+	Update(doc *did.Doc) error
+	Recover(doc *did.Doc) error
+	Deactivate(didID string) error
 }
 
 // PublicDIDGetter initializes and provides the public DID this router will use.
@@ -41,20 +93,44 @@ type PublicDIDGetter struct {
 	ctx        Ctx
 	httpClient *http.Client
 	store      storage.Store
-	orbVDR     orbVDR
+	method     string
+	creator    didCreator
+	endpoint   string
+	endpoints  []DIDCommEndpoint
+	locker     lease.Locker
+
+	regenerateOnDrift bool
+
+	// opMu serializes the operations on the public DID, which rotate its keys.
+	opMu sync.Mutex
 }
 
-// PublicDIDConfig contains parameters for Orb public DID creation.
+// PublicDIDConfig contains parameters for public DID creation.
 type PublicDIDConfig struct {
-	TLSConfig       *tls.Config
+	// Method is the public DID method, one of [orb, web, key, peer]. Defaults to orb.
+	Method    string
+	TLSConfig *tls.Config
+	// HTTPClient sends the HTTP requests of the public DID, ex: through a proxy. Defaults to a client with TLSConfig.
+	HTTPClient      *http.Client
 	OrbDomains      []string
+	WebDomain       string
 	DIDCommEndPoint string
-	Token           string
+	// DIDCommEndpoints are the endpoints of the DIDCommMessaging service of the public DID. Defaults to
+	// DIDCommEndPoint, which defaults to the URI of the first endpoint by priority.
+	DIDCommEndpoints []DIDCommEndpoint
+	Token            string
+	// TokenProvider returns the current sidetree request token, if the token is refreshed at runtime. Overrides Token.
+	TokenProvider func() string
+	// Locker serializes the public DID creation among the router instances sharing the storage. Optional.
+	Locker lease.Locker
+	// RegenerateOnDrift creates a new did:key or did:peer public DID if the DIDComm endpoints or the key types of the
+	// persisted one don't match the configuration, since these DIDs can't be updated.
+	RegenerateOnDrift bool
 }
 
 // GetPublicDID gets the public DID that this router will use for OOBv2 invitations.
 func GetPublicDID(ctx Ctx, cfg *PublicDIDConfig) (string, error) {
-	pdg, err := newPublicDIDGetter(ctx, cfg.TLSConfig, cfg.OrbDomains, cfg.Token)
+	pdg, err := NewPublicDIDGetter(ctx, cfg)
 	if err != nil {
 		return "", err
 	}
@@ -62,45 +138,102 @@ func GetPublicDID(ctx Ctx, cfg *PublicDIDConfig) (string, error) {
 	return pdg.Initialize(cfg.DIDCommEndPoint)
 }
 
-// newPublicDIDGetter returns a new PublicDIDGetter.
-func newPublicDIDGetter(ctx Ctx, tlsConfig *tls.Config, orbDomains []string, token string) (*PublicDIDGetter, error) {
+// NewPublicDIDGetter returns a new PublicDIDGetter.
+func NewPublicDIDGetter(ctx Ctx, cfg *PublicDIDConfig) (*PublicDIDGetter, error) {
 	store, err := ctx.StorageProvider().OpenStore(storeName)
 	if err != nil {
 		return nil, fmt.Errorf("open invitation DID store: %w", err)
 	}
 
-	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
-
-	orbOpts := []orb.Option{
-		orb.WithHTTPClient(httpClient),
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: cfg.TLSConfig}}
 	}
 
-	for _, domain := range orbDomains {
-		orbOpts = append(orbOpts, orb.WithDomain(domain))
+	method := cfg.Method
+	if method == "" {
+		method = PublicDIDMethodOrb
 	}
 
-	if token != "" {
-		orbOpts = append(orbOpts, orb.WithAuthToken(token))
+	var creator didCreator
+
+	switch method {
+	case PublicDIDMethodOrb:
+		token := cfg.TokenProvider
+		if token == nil {
+			token = func() string { return cfg.Token }
+		}
+
+		creator, err = newOrbCreator(httpClient, cfg.OrbDomains, token, newOrbKeys(ctx, store))
+		if err != nil {
+			return nil, err
+		}
+	case PublicDIDMethodWeb:
+		if cfg.WebDomain == "" {
+			return nil, errors.New("did:web public DID requires a domain")
+		}
+
+		creator = &webCreator{domain: cfg.WebDomain}
+	case PublicDIDMethodKey:
+		creator = &keyCreator{vdr: key.New()}
+	case PublicDIDMethodPeer:
+		creator = &peer2Creator{}
+	default:
+		return nil, fmt.Errorf("unsupported public DID method: %s", method)
 	}
 
-	vdr, err := orb.New(nil, orbOpts...)
-	if err != nil {
-		return nil, err
+	endpoints := SortedEndpoints(cfg.DIDCommEndpoints)
+
+	endpoint := cfg.DIDCommEndPoint
+	if endpoint == "" && len(endpoints) > 0 {
+		endpoint = endpoints[0].URI
 	}
 
 	return &PublicDIDGetter{
 		ctx:        ctx,
 		store:      store,
 		httpClient: httpClient,
-		orbVDR:     vdr,
+		method:     method,
+		creator:    creator,
+		endpoint:   endpoint,
+		endpoints:  endpoints,
+		locker:     cfg.Locker,
+
+		regenerateOnDrift: cfg.RegenerateOnDrift,
 	}, nil
 }
 
-// Initialize initializes the PublicDIDGetter by creating a public orb DID.
+// Initialize initializes the PublicDIDGetter by creating a public DID. With a Locker, only one router instance creates
+// the public DID while the others wait for it and reuse it.
 func (g *PublicDIDGetter) Initialize(didcommEndPoint string) (string, error) {
+	if g.locker == nil {
+		return g.initialize(didcommEndPoint, func() error { return nil })
+	}
+
+	held, err := lease.Acquire(g.locker, publicDIDLease, publicDIDLeaseTTL, publicDIDLeaseWait)
+	if err != nil {
+		return "", fmt.Errorf("public DID creation: %w", err)
+	}
+
+	defer func() {
+		if e := held.Release(); e != nil {
+			logger.Warnf("failed to release public DID lease: %s", e)
+		}
+	}()
+
+	return g.initialize(didcommEndPoint, held.Err)
+}
+
+// initialize creates the public DID unless reusable. leaseErr reports the loss of the lease, in which case another
+// instance may be creating the public DID: the creation is aborted before it's created and before it's saved.
+func (g *PublicDIDGetter) initialize(didcommEndPoint string, leaseErr func() error) (string, error) {
 	res, err := g.store.Get(storeDIDKey)
-	if err == nil {
+	if err == nil && g.reusable(string(res), didcommEndPoint) {
 		// another router instance has created the public DID and saved to a shared/persistent store.
+		if m, ok := g.creator.(didManager); ok {
+			g.updateOnDrift(m, string(res), didcommEndPoint)
+		}
+
 		return string(res), nil
 	}
 
@@ -109,9 +242,22 @@ func (g *PublicDIDGetter) Initialize(didcommEndPoint string) (string, error) {
 		return "", err
 	}
 
-	docRes, err := g.requestOrbCreate(didDoc)
+	if err = leaseErr(); err != nil {
+		return "", fmt.Errorf("public DID creation: %w", err)
+	}
+
+	docRes, err := g.creator.Create(didDoc)
 	if err != nil {
-		return "", fmt.Errorf("creating public orb DID: %w", err)
+		return "", fmt.Errorf("creating public %s DID: %w", g.method, err)
+	}
+
+	if err = leaseErr(); err != nil {
+		return "", fmt.Errorf("public DID creation, created %s: %w", docRes.DIDDocument.ID, err)
+	}
+
+	err = g.saveDoc(docRes.DIDDocument)
+	if err != nil {
+		return "", err
 	}
 
 	err = g.store.Put(storeDIDKey, []byte(docRes.DIDDocument.ID))
@@ -122,6 +268,419 @@ func (g *PublicDIDGetter) Initialize(didcommEndPoint string) (string, error) {
 	return docRes.DIDDocument.ID, nil
 }
 
+// WaitResolvable polls the VDR registry until the public DID resolves with the given DIDComm endpoint, since DIDs like
+// orb DIDs aren't resolvable until anchored. Gives up after maxWait, or never if zero.
+func (g *PublicDIDGetter) WaitResolvable(didID, didcommEndPoint string, maxWait time.Duration) error {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = resolveInitialInterval
+	b.MaxInterval = resolveMaxInterval
+	b.MaxElapsedTime = maxWait
+
+	err := backoff.RetryNotify(func() error {
+		return g.checkResolution(didID, didcommEndPoint)
+	}, b, func(err error, next time.Duration) {
+		logger.Infof("public DID %s not resolvable yet, retrying in %s : %s", didID, next, err)
+	})
+	if err != nil {
+		return fmt.Errorf("public DID %s not resolvable: %w", didID, err)
+	}
+
+	logger.Infof("public DID %s resolved", didID)
+
+	return nil
+}
+
+func (g *PublicDIDGetter) checkResolution(didID, didcommEndPoint string) error {
+	docRes, err := g.ctx.VDRegistry().Resolve(didID)
+	if err != nil {
+		return fmt.Errorf("resolve: %w", err)
+	}
+
+	if g.method == PublicDIDMethodKey {
+		// did:key docs have no service
+		return nil
+	}
+
+	resolved, expected := docEndpoints(docRes.DIDDocument), toDIDCommV2Endpoints(g.didCommEndpoints(didcommEndPoint))
+
+	if !sameEndpoints(resolved, expected) {
+		return fmt.Errorf("resolved with DIDComm endpoint '%s', expected '%s'", formatEndpoints(resolved),
+			formatEndpoints(expected))
+	}
+
+	return nil
+}
+
+// Get returns the public DID doc, as last created, updated or recovered by the router.
+func (g *PublicDIDGetter) Get() (*did.Doc, error) {
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	didID, err := g.store.Get(storeDIDKey)
+	if err != nil {
+		return nil, fmt.Errorf("get public DID: %w", err)
+	}
+
+	return g.storedDoc(string(didID))
+}
+
+// Update updates the public DID doc with the given DIDComm endpoint, or the configured one if empty. The router keys
+// are kept, unless rotateKeys is set or the configured key types changed.
+func (g *PublicDIDGetter) Update(didcommEndPoint string, rotateKeys bool) (*did.Doc, error) {
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	m, didID, err := g.manager()
+	if err != nil {
+		return nil, err
+	}
+
+	docRes, err := m.Read(didID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve public DID %s: %w", didID, err)
+	}
+
+	doc, err := g.updatedDoc(docRes.DIDDocument, g.endpointOrDefault(didcommEndPoint), rotateKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Update(doc)
+	if err != nil {
+		return nil, fmt.Errorf("update public DID %s: %w", didID, err)
+	}
+
+	logger.Infof("updated public DID %s", didID)
+
+	return doc, g.saveDoc(doc)
+}
+
+// Recover recovers the public DID with new router keys and the given DIDComm endpoint, or the configured one if empty.
+func (g *PublicDIDGetter) Recover(didcommEndPoint string) (*did.Doc, error) {
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	m, didID, err := g.manager()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := g.docTemplate(g.endpointOrDefault(didcommEndPoint))
+	if err != nil {
+		return nil, err
+	}
+
+	doc.ID = didID
+
+	err = m.Recover(doc)
+	if err != nil {
+		return nil, fmt.Errorf("recover public DID %s: %w", didID, err)
+	}
+
+	logger.Infof("recovered public DID %s", didID)
+
+	return doc, g.saveDoc(doc)
+}
+
+// Deactivate deactivates the public DID. A new public DID is created on the next Initialize.
+func (g *PublicDIDGetter) Deactivate() (string, error) {
+	g.opMu.Lock()
+	defer g.opMu.Unlock()
+
+	m, didID, err := g.manager()
+	if err != nil {
+		return "", err
+	}
+
+	err = m.Deactivate(didID)
+	if err != nil {
+		return "", fmt.Errorf("deactivate public DID %s: %w", didID, err)
+	}
+
+	logger.Infof("deactivated public DID %s", didID)
+
+	for _, key := range []string{storeDIDKey, storeDIDDocKey} {
+		err = g.store.Delete(key)
+		if err != nil {
+			return "", fmt.Errorf("delete deactivated public DID: %w", err)
+		}
+	}
+
+	return didID, nil
+}
+
+func (g *PublicDIDGetter) manager() (didManager, string, error) {
+	m, ok := g.creator.(didManager)
+	if !ok {
+		return nil, "", fmt.Errorf("public DID method %s doesn't support updates", g.method)
+	}
+
+	didID, err := g.store.Get(storeDIDKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("get public DID: %w", err)
+	}
+
+	return m, string(didID), nil
+}
+
+func (g *PublicDIDGetter) endpointOrDefault(didcommEndPoint string) string {
+	if didcommEndPoint == "" {
+		return g.endpoint
+	}
+
+	return didcommEndPoint
+}
+
+// didCommEndpoints returns the endpoints of the public DID doc with the given DIDComm endpoint: the configured
+// endpoints if it's the first one, else the given endpoint alone, ex: set with the public DID admin endpoints.
+func (g *PublicDIDGetter) didCommEndpoints(didcommEndPoint string) []DIDCommEndpoint {
+	if len(g.endpoints) > 0 && (didcommEndPoint == "" || didcommEndPoint == g.endpoints[0].URI) {
+		return g.endpoints
+	}
+
+	return []DIDCommEndpoint{{URI: didcommEndPoint}}
+}
+
+// updatedDoc returns the current public DID doc with the given DIDComm endpoint. New router keys are only created if
+// rotateKeys is set, or for the keys that don't match the configured key types.
+func (g *PublicDIDGetter) updatedDoc(current *did.Doc, didcommEndPoint string, rotateKeys bool) (*did.Doc, error) {
+	doc := &did.Doc{
+		ID:      current.ID,
+		Context: current.Context,
+		Service: DIDCommV2Services(g.didCommEndpoints(didcommEndPoint)),
+	}
+
+	auth, err := g.keptOrNewVerification(current.Authentication, "#key-1", g.ctx.KeyType(), did.Authentication,
+		rotateKeys)
+	if err != nil {
+		return nil, fmt.Errorf("creating did doc Authentication: %w", err)
+	}
+
+	kagr, err := g.keptOrNewVerification(current.KeyAgreement, "#key-2", g.ctx.KeyAgreementType(), did.KeyAgreement,
+		rotateKeys)
+	if err != nil {
+		return nil, fmt.Errorf("creating did doc KeyAgreement: %w", err)
+	}
+
+	doc.Authentication = []did.Verification{*auth}
+	doc.KeyAgreement = []did.Verification{*kagr}
+
+	for i := range doc.Service {
+		if i < len(current.Service) {
+			doc.Service[i].ID = current.Service[i].ID
+		}
+	}
+
+	return doc, nil
+}
+
+// keptOrNewVerification returns the first of the current verifications if it has the given key type, else a new one.
+func (g *PublicDIDGetter) keptOrNewVerification(current []did.Verification, id string, kt kms.KeyType,
+	relationship did.VerificationRelationship, rotate bool) (*did.Verification, error) {
+	if !rotate && len(current) > 0 {
+		currentKT, err := KeyTypeFor(&current[0].VerificationMethod, relationship)
+		if err == nil && sameKeyType(currentKT, kt) {
+			return &current[0], nil
+		}
+	}
+
+	return g.createVerification(id, kt, relationship)
+}
+
+func (g *PublicDIDGetter) saveDoc(doc *did.Doc) error {
+	docBytes, err := doc.JSONBytes()
+	if err != nil {
+		return fmt.Errorf("marshal public DID doc: %w", err)
+	}
+
+	err = g.store.Put(storeDIDDocKey, docBytes)
+	if err != nil {
+		return fmt.Errorf("error saving public DID doc: %w", err)
+	}
+
+	return nil
+}
+
+// reusable reports whether the persisted public DID can be used as it is. did:web docs are served by the router, so
+// they're recreated whenever the domain, the DIDComm endpoint or the key types change. did:key and did:peer DIDs can't
+// be updated: they're recreated on such changes only if RegenerateOnDrift is set, else the changes are logged.
+func (g *PublicDIDGetter) reusable(storedDID, didcommEndPoint string) bool {
+	if !strings.HasPrefix(storedDID, "did:"+g.method+":") {
+		logger.Warnf("public DID %s doesn't use the configured method %s, creating a new one", storedDID, g.method)
+
+		return false
+	}
+
+	switch c := g.creator.(type) {
+	case *webCreator:
+		if storedDID != WebDID(c.domain) {
+			logger.Infof("public DID %s doesn't match the did:web domain %s, creating a new one", storedDID, c.domain)
+
+			return false
+		}
+	case *keyCreator, *peer2Creator:
+		// checked against the persisted doc below
+	default:
+		// orb DIDs are updated in place, see updateOnDrift
+		return true
+	}
+
+	_, web := g.creator.(*webCreator)
+
+	doc, err := g.storedDoc(storedDID)
+	if err != nil {
+		logger.Warnf("%s", err)
+
+		// the did:web doc is served by the router, the other DIDs are still valid without their stored doc
+		return !web
+	}
+
+	changes := g.docChanges(doc, didcommEndPoint)
+
+	if _, ok := g.creator.(*keyCreator); ok {
+		// did:key docs have no service
+		changes = g.keyTypeChanges(doc)
+	}
+
+	if web && len(changes) > 0 {
+		logger.Infof("updating public DID doc %s : %s", storedDID, strings.Join(changes, ", "))
+
+		return false
+	}
+
+	if len(changes) == 0 {
+		return true
+	}
+
+	if g.regenerateOnDrift {
+		logger.Infof("public DID %s is outdated (%s), creating a new one", storedDID, strings.Join(changes, ", "))
+
+		return false
+	}
+
+	logger.Warnf("public DID %s is outdated and can't be updated, it's still used : %s", storedDID,
+		strings.Join(changes, ", "))
+
+	return true
+}
+
+func (g *PublicDIDGetter) storedDoc(storedDID string) (*did.Doc, error) {
+	docBytes, err := g.store.Get(storeDIDDocKey)
+	if err != nil {
+		return nil, fmt.Errorf("get public DID doc %s : %w", storedDID, err)
+	}
+
+	doc, err := did.ParseDocument(docBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public DID doc %s : %w", storedDID, err)
+	}
+
+	return doc, nil
+}
+
+// updateOnDrift updates the public DID if its resolved doc no longer matches the DIDComm endpoint and key types of the
+// router. Failures are only logged, the router keeps using the public DID as it is.
+func (g *PublicDIDGetter) updateOnDrift(m didManager, didID, didcommEndPoint string) {
+	docRes, err := m.Read(didID)
+	if err != nil {
+		logger.Warnf("resolve public DID %s to check for changes : %s", didID, err)
+
+		return
+	}
+
+	changes := g.docChanges(docRes.DIDDocument, didcommEndPoint)
+	if len(changes) == 0 {
+		return
+	}
+
+	logger.Infof("updating public DID %s : %s", didID, strings.Join(changes, ", "))
+
+	_, err = g.Update(didcommEndPoint, false)
+	if err != nil {
+		logger.Errorf("public DID %s is outdated (%s) : %s", didID, strings.Join(changes, ", "), err)
+	}
+}
+
+// docChanges lists the differences between the public DID doc and the DIDComm endpoint and key types of the router.
+func (g *PublicDIDGetter) docChanges(doc *did.Doc, didcommEndPoint string) []string {
+	var changes []string
+
+	current, wanted := docEndpoints(doc), toDIDCommV2Endpoints(g.didCommEndpoints(didcommEndPoint))
+
+	if !sameEndpoints(current, wanted) {
+		changes = append(changes, fmt.Sprintf("DIDComm endpoint '%s' -> '%s'", formatEndpoints(current),
+			formatEndpoints(wanted)))
+	}
+
+	return append(changes, g.keyTypeChanges(doc)...)
+}
+
+// keyTypeChanges lists the differences between the key types of the public DID doc and the router.
+func (g *PublicDIDGetter) keyTypeChanges(doc *did.Doc) []string {
+	var changes []string
+
+	keyTypeChange := func(verifications []did.Verification, rel did.VerificationRelationship, want kms.KeyType) {
+		var kt kms.KeyType
+
+		if len(verifications) > 0 {
+			var err error
+
+			kt, err = KeyTypeFor(&verifications[0].VerificationMethod, rel)
+			if err != nil {
+				// ex: the base58 keys of did:key docs, their type isn't in the doc
+				logger.Debugf("public DID %s key type unknown : %s", relationshipName(rel == did.KeyAgreement), err)
+
+				return
+			}
+		}
+
+		if !sameKeyType(kt, want) {
+			changes = append(changes, fmt.Sprintf("%s key type '%s' -> '%s'",
+				relationshipName(rel == did.KeyAgreement), kt, want))
+		}
+	}
+
+	keyTypeChange(doc.Authentication, did.Authentication, g.ctx.KeyType())
+	keyTypeChange(doc.KeyAgreement, did.KeyAgreement, g.ctx.KeyAgreementType())
+
+	return changes
+}
+
+// sameKeyType compares key types regardless of the ECDSA signature encoding, which doesn't show in did docs.
+func sameKeyType(a, b kms.KeyType) bool {
+	trim := func(kt kms.KeyType) string {
+		return strings.TrimSuffix(strings.TrimSuffix(string(kt), "IEEEP1363"), "DER")
+	}
+
+	return trim(a) == trim(b)
+}
+
+// GetPublicDIDDoc returns the doc of the public DID, as saved in the given (aries) storage provider.
+func GetPublicDIDDoc(provider storage.Provider) ([]byte, error) {
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open invitation DID store: %w", err)
+	}
+
+	docBytes, err := store.Get(storeDIDDocKey)
+	if err != nil {
+		return nil, fmt.Errorf("get public DID doc: %w", err)
+	}
+
+	return docBytes, nil
+}
+
+// WebDIDDocPath returns the URL path the doc of the given did:web DID is resolved from, ex: /.well-known/did.json for
+// did:web:example.com and /user/alice/did.json for did:web:example.com:user:alice.
+func WebDIDDocPath(didID string) string {
+	parts := strings.Split(strings.TrimPrefix(didID, "did:web:"), ":")
+	if len(parts) == 1 {
+		return DefaultWebDIDDocPath
+	}
+
+	return "/" + strings.Join(parts[1:], "/") + "/did.json"
+}
+
 func (g *PublicDIDGetter) docTemplate(didcommEndPoint string) (*did.Doc, error) {
 	didDoc := did.Doc{}
 
@@ -139,22 +698,61 @@ func (g *PublicDIDGetter) docTemplate(didcommEndPoint string) (*did.Doc, error)
 
 	didDoc.KeyAgreement = append(didDoc.KeyAgreement, *kagr)
 
-	didDoc.Service = []did.Service{{
-		ID:              uuid.New().String(),
-		ServiceEndpoint: model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{{URI: didcommEndPoint}}),
-		Type:            "DIDCommMessaging",
-	}}
+	didDoc.Service = DIDCommV2Services(g.didCommEndpoints(didcommEndPoint))
 
 	return &didDoc, nil
 }
 
-func (g *PublicDIDGetter) requestOrbCreate(doc *did.Doc) (*did.DocResolution, error) {
-	publicKeyRecovery, _, err := ed25519.GenerateKey(rand.Reader)
+// orbCreator creates and manages orb DIDs.
+type orbCreator struct {
+	vdr  orbVDR
+	keys *orbKeys
+}
+
+func newOrbCreator(httpClient *http.Client, orbDomains []string, token func() string,
+	keys *orbKeys) (*orbCreator, error) {
+	// the token is added by the transport rather than with orb.WithAuthToken, so that it can be refreshed
+	orbOpts := []orb.Option{
+		orb.WithHTTPClient(&http.Client{
+			Transport: &bearerTransport{base: httpClient.Transport, token: token},
+			Timeout:   httpClient.Timeout,
+		}),
+	}
+
+	for _, domain := range orbDomains {
+		orbOpts = append(orbOpts, orb.WithDomain(domain))
+	}
+
+	vdr, err := orb.New(keys, orbOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	publicKeyUpdate, _, err := ed25519.GenerateKey(rand.Reader)
+	return &orbCreator{vdr: vdr, keys: keys}, nil
+}
+
+// bearerTransport adds the current request token to the requests that don't have an Authorization header.
+type bearerTransport struct {
+	base  http.RoundTripper
+	token func() string
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if token := t.token(); token != "" && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+func (c *orbCreator) Create(doc *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	recoveryKID, publicKeyRecovery, err := c.keys.newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	updateKID, publicKeyUpdate, err := c.keys.newKey()
 	if err != nil {
 		return nil, err
 	}
@@ -164,7 +762,162 @@ func (g *PublicDIDGetter) requestOrbCreate(doc *did.Doc) (*did.DocResolution, er
 		vdrapi.WithOption(orb.RecoveryPublicKeyOpt, publicKeyRecovery),
 	}
 
-	return g.orbVDR.Create(doc, createOpts...)
+	docRes, err := c.vdr.Create(doc, createOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.keys.save(updateKID, recoveryKID)
+	if err != nil {
+		return nil, err
+	}
+
+	return docRes, nil
+}
+
+func (c *orbCreator) Read(didID string) (*did.DocResolution, error) {
+	return c.vdr.Read(didID)
+}
+
+func (c *orbCreator) Update(doc *did.Doc) error {
+	err := c.reconcileKeys(doc.ID)
+	if err != nil {
+		return err
+	}
+
+	err = c.vdr.Update(doc)
+	if err != nil {
+		return err
+	}
+
+	return c.keys.commit(orb.Update)
+}
+
+func (c *orbCreator) Recover(doc *did.Doc) error {
+	err := c.reconcileKeys(doc.ID)
+	if err != nil {
+		return err
+	}
+
+	err = c.vdr.Update(doc, vdrapi.WithOption(orb.RecoverOpt, true))
+	if err != nil {
+		return err
+	}
+
+	return c.keys.commit(orb.Recover)
+}
+
+func (c *orbCreator) Deactivate(didID string) error {
+	err := c.reconcileKeys(didID)
+	if err != nil {
+		return err
+	}
+
+	return c.vdr.Deactivate(didID)
+}
+
+// reconcileKeys makes the keys committed by the DID the current ones, in case the outcome of the previous operation
+// was unknown.
+func (c *orbCreator) reconcileKeys(didID string) error {
+	docRes, err := c.vdr.Read(didID)
+	if err != nil {
+		return fmt.Errorf("resolve orb DID %s : %w", didID, err)
+	}
+
+	if docRes.DocumentMetadata == nil || docRes.DocumentMetadata.Method == nil {
+		return nil
+	}
+
+	return c.keys.reconcile(docRes.DocumentMetadata.Method)
+}
+
+// webCreator creates the did:web DID of a domain (host[:port][/path]) served by the router.
+type webCreator struct {
+	domain string
+}
+
+func (c *webCreator) Create(doc *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	return &did.DocResolution{DIDDocument: withDID(doc, WebDID(c.domain))}, nil
+}
+
+// WebDID returns the did:web DID of the domain, ex: example.com:8443/mediator -> did:web:example.com%3A8443:mediator.
+func WebDID(domain string) string {
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://")
+	domain = strings.Trim(domain, "/")
+
+	parts := strings.Split(domain, "/")
+	parts[0] = strings.ReplaceAll(parts[0], ":", "%3A")
+
+	return "did:web:" + strings.Join(parts, ":")
+}
+
+// keyCreator creates a did:key DID from the authentication key of the template, along with its key agreement key.
+// Note: did:key docs have no service, so the DIDComm endpoint is not part of the DID.
+type keyCreator struct {
+	vdr vdrapi.VDR
+}
+
+func (c *keyCreator) Create(doc *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	if len(doc.Authentication) == 0 || len(doc.KeyAgreement) == 0 {
+		return nil, errors.New("did:key requires an authentication and a key agreement key")
+	}
+
+	ka := doc.KeyAgreement[0].VerificationMethod
+
+	docRes, err := c.vdr.Create(&did.Doc{
+		VerificationMethod: []did.VerificationMethod{doc.Authentication[0].VerificationMethod},
+	}, vdrapi.WithOption(key.EncryptionKey, &ka))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range docRes.DIDDocument.KeyAgreement {
+		vm := &docRes.DIDDocument.KeyAgreement[i].VerificationMethod
+		vm.ID = docRes.DIDDocument.ID + vm.ID
+		vm.Controller = docRes.DIDDocument.ID
+	}
+
+	return docRes, nil
+}
+
+// peer2Creator creates did:peer numalgo 2 DIDs.
+type peer2Creator struct{}
+
+func (c *peer2Creator) Create(doc *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	return createPeer2(doc)
+}
+
+// withDID returns the did doc template with the given DID, and its verification methods listed with absolute IDs.
+func withDID(doc *did.Doc, didID string) *did.Doc {
+	newDoc := &did.Doc{
+		Context: []string{did.ContextV1},
+		ID:      didID,
+	}
+
+	add := func(verifications []did.Verification, rel did.VerificationRelationship) []did.Verification {
+		var result []did.Verification
+
+		for i := range verifications {
+			vm := verifications[i].VerificationMethod
+			vm.ID = didID + vm.ID
+			vm.Controller = didID
+
+			newDoc.VerificationMethod = append(newDoc.VerificationMethod, vm)
+			result = append(result, *did.NewReferencedVerification(&vm, rel))
+		}
+
+		return result
+	}
+
+	newDoc.Authentication = add(doc.Authentication, did.Authentication)
+	newDoc.KeyAgreement = add(doc.KeyAgreement, did.KeyAgreement)
+
+	for _, svc := range doc.Service {
+		svc.ID = didID + "#" + svc.ID
+		newDoc.Service = append(newDoc.Service, svc)
+	}
+
+	return newDoc
 }
 
 func (g *PublicDIDGetter) createVerification(id string, kt kms.KeyType, relationship did.VerificationRelationship,
@@ -182,6 +935,13 @@ func (g *PublicDIDGetter) createVerification(id string, kt kms.KeyType, relation
 		if err != nil {
 			return nil, fmt.Errorf("creating jwk from Ed25519 key: %w", err)
 		}
+	case kms.BLS12381G2Type:
+		j, err = jwksupport.PubKeyBytesToJWK(pkBytes, kms.BLS12381G2Type)
+		if err != nil {
+			return nil, fmt.Errorf("creating jwk from BLS12-381 G2 key: %w", err)
+		}
+
+		j.KeyID = kid
 	default:
 		j, err = jwkkid.BuildJWK(pkBytes, kt)
 		if err != nil {
@@ -196,9 +956,64 @@ func (g *PublicDIDGetter) createVerification(id string, kt kms.KeyType, relation
 		return nil, fmt.Errorf("creating verification method: %w", err)
 	}
 
+	err = g.saveKeyID(vm, kid)
+	if err != nil {
+		return nil, err
+	}
+
 	return did.NewReferencedVerification(vm, relationship), nil
 }
 
+// saveKeyID saves the KMS key ID of the key of the verification method, since KMSs like webkms don't derive their key
+// IDs from the keys.
+func (g *PublicDIDGetter) saveKeyID(vm *did.VerificationMethod, kid string) error {
+	pubKey, err := publicKeyBytes(vm)
+	if err != nil {
+		return err
+	}
+
+	err = g.store.Put(storeKeyIDPrefix+base64.RawURLEncoding.EncodeToString(pubKey), []byte(kid))
+	if err != nil {
+		return fmt.Errorf("save public DID key id: %w", err)
+	}
+
+	return nil
+}
+
+// publicDIDKeyID returns the KMS key ID of a public DID key, as saved in the given (aries) storage provider. Returns
+// an empty key ID for the keys of public DIDs created before the key IDs were saved.
+func publicDIDKeyID(provider storage.Provider, pubKey []byte) (string, error) {
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return "", fmt.Errorf("open invitation DID store: %w", err)
+	}
+
+	kid, err := store.Get(storeKeyIDPrefix + base64.RawURLEncoding.EncodeToString(pubKey))
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("get public DID key id: %w", err)
+	}
+
+	return string(kid), nil
+}
+
+// publicKeyBytes returns the public key of the verification method, from its JWK if any.
+func publicKeyBytes(vm *did.VerificationMethod) ([]byte, error) {
+	if vm.JSONWebKey() == nil {
+		return vm.Value, nil
+	}
+
+	pubKey, err := vm.JSONWebKey().PublicKeyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("public key bytes of %s: %w", vm.ID, err)
+	}
+
+	return pubKey, nil
+}
+
 // CreateVerification creates a did.Verification with a referenced did.VerificationMethod, with a new key of type kt.
 func CreateVerification(keyManager kms.KeyManager, id string, kt kms.KeyType, relationship did.VerificationRelationship,
 ) (*did.Verification, error) {
@@ -212,6 +1027,8 @@ func CreateVerification(keyManager kms.KeyManager, id string, kt kms.KeyType, re
 	switch kt { // nolint:exhaustive // most cases can use the default.
 	case kms.ED25519Type:
 		vm = did.NewVerificationMethodFromBytes(id, "Ed25519VerificationKey2018", "", pkBytes)
+	case kms.BLS12381G2Type:
+		vm = did.NewVerificationMethodFromBytes(id, bls12381G2Key2020, "", pkBytes)
 	case kms.X25519ECDHKWType:
 		key := &crypto.PublicKey{}
 
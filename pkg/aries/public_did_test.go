@@ -7,9 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package aries
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/orb"
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
@@ -17,13 +27,17 @@ import (
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
 	mockprovider "github.com/hyperledger/aries-framework-go/pkg/mock/provider"
 	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	mockvdr "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/lease"
 )
 
 func TestGetPublicDID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
-		didValue := "did:foo:bar"
+		didValue := "did:orb:bar"
 
 		store := mockstore.MockStore{Store: map[string]mockstore.DBEntry{
 			storeDIDKey: {Value: []byte(didValue)},
@@ -52,7 +66,7 @@ func TestNewPublicDIDGetter(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		ctx := getAriesCtx()
 
-		_, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		_, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 	})
 
@@ -62,15 +76,57 @@ func TestNewPublicDIDGetter(t *testing.T) {
 		ctx := ariesMockProvider()
 		ctx.StorageProviderValue = &mockstore.MockStoreProvider{ErrOpenStoreHandle: expectErr}
 
-		_, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		_, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.Error(t, err)
 		require.ErrorIs(t, err, expectErr)
 	})
 }
 
+func TestBearerTransport(t *testing.T) {
+	var auth []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	token := "t1"
+
+	client := &http.Client{Transport: &bearerTransport{
+		base:  http.DefaultTransport,
+		token: func() string { return token },
+	}}
+
+	send := func(header string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	send("")
+
+	token = "t2"
+
+	send("")
+	send("Bearer other")
+
+	token = ""
+
+	send("")
+
+	require.Equal(t, []string{"Bearer t1", "Bearer t2", "Bearer other", ""}, auth)
+}
+
 func TestPublicDIDGetter_Initialize(t *testing.T) {
 	t.Run("success - DID already created", func(t *testing.T) {
-		didValue := "did:foo:bar"
+		didValue := "did:orb:bar"
 
 		store := mockstore.MockStore{Store: map[string]mockstore.DBEntry{
 			storeDIDKey: {Value: []byte(didValue)},
@@ -79,7 +135,7 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx := ariesMockProvider()
 		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(&store)
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.Initialize("")
@@ -93,7 +149,7 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx.KeyTypeValue = "oopsie-woopsie-not-a-key-type"
 		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.Initialize("")
@@ -108,7 +164,7 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx.KeyTypeValue = kms.ECDSAP256IEEEP1363
 		ctx.KeyAgreementTypeValue = "oopsie-woopsie-not-a-key-type"
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.Initialize("")
@@ -124,7 +180,7 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx.KeyTypeValue = kms.ECDSAP256IEEEP1363
 		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.Initialize("")
@@ -138,15 +194,19 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx = addRealKMS(t, ctx)
 
 		expectErr := fmt.Errorf("expected error")
-		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(&mockstore.MockStore{ErrPut: expectErr})
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(store)
 
 		ctx.KeyTypeValue = kms.ECDSAP256IEEEP1363
 		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
-		pdg.orbVDR = &mockVDR{createFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+		pdg.creator = &mockVDR{createFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			// the storage fails once the DID is created
+			store.ErrPut = expectErr
+
 			return &did.DocResolution{
 				DIDDocument: &did.Doc{ID: "did:orb:test", Context: []string{did.ContextV1}},
 			}, nil
@@ -166,12 +226,12 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		ctx.KeyTypeValue = kms.ECDSAP256IEEEP1363
 		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		testDID := "did:orb:test"
 
-		pdg.orbVDR = &mockVDR{createFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+		pdg.creator = &mockVDR{createFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
 			return &did.DocResolution{
 				DIDDocument: &did.Doc{ID: testDID, Context: []string{did.ContextV1}},
 			}, nil
@@ -181,6 +241,818 @@ func TestPublicDIDGetter_Initialize(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, testDID, res)
 	})
+
+	t.Run("success: instances sharing the storage create a single DID", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		var (
+			mu      sync.Mutex
+			created int
+		)
+
+		creator := &mockVDR{createFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			mu.Lock()
+			created++
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			return &did.DocResolution{
+				DIDDocument: &did.Doc{ID: "did:orb:test", Context: []string{did.ContextV1}},
+			}, nil
+		}}
+
+		const instances = 3
+
+		var wg sync.WaitGroup
+
+		results := make(chan string, instances)
+
+		for i := 0; i < instances; i++ {
+			ctx := ariesMockProvider()
+			ctx.StorageProviderValue = provider
+			ctx = addRealKMS(t, ctx)
+			ctx.KeyTypeValue = kms.ED25519Type
+			ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+			locker, err := lease.New(provider, fmt.Sprintf("instance-%d", i))
+			require.NoError(t, err)
+
+			pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1", Locker: locker})
+			require.NoError(t, err)
+
+			pdg.creator = creator
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				res, e := pdg.Initialize("")
+				require.NoError(t, e)
+
+				results <- res
+			}()
+		}
+
+		wg.Wait()
+		close(results)
+
+		for res := range results {
+			require.Equal(t, "did:orb:test", res)
+		}
+
+		require.Equal(t, 1, created)
+	})
+
+	t.Run("fail: lease error", func(t *testing.T) {
+		ctx := ariesMockProvider()
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1", Locker: &mockLocker{
+			tryAcquireErr: fmt.Errorf("expected error"),
+		}})
+		require.NoError(t, err)
+
+		_, err = pdg.Initialize("")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "public DID creation")
+	})
+
+	t.Run("fail: lease lost", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ED25519Type
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
+		require.NoError(t, err)
+
+		created := false
+
+		pdg.creator = &mockVDR{createFunc: func(*did.Doc, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			created = true
+
+			return &did.DocResolution{DIDDocument: &did.Doc{ID: "did:orb:test"}}, nil
+		}}
+
+		_, err = pdg.initialize("", func() error { return lease.ErrLost })
+		require.ErrorIs(t, err, lease.ErrLost)
+		require.False(t, created)
+
+		_, err = pdg.store.Get(storeDIDKey)
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+}
+
+func TestPublicDIDMethods(t *testing.T) {
+	t.Run("fail: unsupported method", func(t *testing.T) {
+		_, err := NewPublicDIDGetter(ariesMockProvider(), &PublicDIDConfig{Method: "foo"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported public DID method: foo")
+	})
+
+	t.Run("fail: did:web without domain", func(t *testing.T) {
+		_, err := NewPublicDIDGetter(ariesMockProvider(), &PublicDIDConfig{Method: PublicDIDMethodWeb})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "requires a domain")
+	})
+
+	t.Run("success: did:web", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ED25519Type
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(store)
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{
+			Method:    PublicDIDMethodWeb,
+			WebDomain: "https://example.com:8443/mediator/",
+		})
+		require.NoError(t, err)
+
+		didID, err := pdg.Initialize("https://example.com:8443/mediator/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, "did:web:example.com%3A8443:mediator", didID)
+
+		doc, err := did.ParseDocument(store.Store[storeDIDDocKey].Value)
+		require.NoError(t, err)
+		require.Equal(t, didID, doc.ID)
+		require.Len(t, doc.VerificationMethod, 2)
+		require.Equal(t, didID+"#key-1", doc.Authentication[0].VerificationMethod.ID)
+		require.Equal(t, didID+"#key-2", doc.KeyAgreement[0].VerificationMethod.ID)
+		require.Len(t, doc.Service, 1)
+
+		uri, err := doc.Service[0].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com:8443/mediator/didcomm", uri)
+	})
+
+	t.Run("success: did:key", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ED25519Type
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: PublicDIDMethodKey})
+		require.NoError(t, err)
+
+		didID, err := pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(didID, "did:key:z6Mk"), didID)
+	})
+
+	t.Run("fail: did:key without key agreement", func(t *testing.T) {
+		_, err := (&keyCreator{}).Create(&did.Doc{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did:key requires")
+	})
+
+	t.Run("success: did:peer numalgo 2", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ECDSAP256IEEEP1363
+		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: PublicDIDMethodPeer})
+		require.NoError(t, err)
+
+		didID, err := pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(didID, "did:peer:2.E"), didID)
+	})
+
+	t.Run("success: persisted DID of another method is replaced", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ED25519Type
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{
+			storeDIDKey: {Value: []byte("did:orb:bar")},
+		}}
+		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(store)
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: PublicDIDMethodWeb, WebDomain: "example.com"})
+		require.NoError(t, err)
+
+		didID, err := pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, "did:web:example.com", didID)
+		require.Equal(t, didID, string(store.Store[storeDIDKey].Value))
+
+		didID, err = pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, "did:web:example.com", didID)
+	})
+}
+
+func TestWebDIDDocUpdates(t *testing.T) {
+	ctx := addRealKMS(t, ariesMockProvider())
+	ctx.KeyTypeValue = kms.ED25519Type
+	ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+	store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}}
+	ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(store)
+
+	initialize := func(domain, endpoint string) string {
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: PublicDIDMethodWeb, WebDomain: domain})
+		require.NoError(t, err)
+
+		didID, err := pdg.Initialize(endpoint)
+		require.NoError(t, err)
+
+		return didID
+	}
+
+	didID := initialize("example.com", "https://example.com/didcomm")
+	docBytes := store.Store[storeDIDDocKey].Value
+
+	t.Run("unchanged doc is reused", func(t *testing.T) {
+		require.Equal(t, didID, initialize("example.com", "https://example.com/didcomm"))
+		require.Equal(t, docBytes, store.Store[storeDIDDocKey].Value)
+	})
+
+	t.Run("endpoint change updates the doc", func(t *testing.T) {
+		require.Equal(t, didID, initialize("example.com", "https://example.com/didcomm2"))
+
+		served, err := GetPublicDIDDoc(ctx.StorageProvider())
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(served)
+		require.NoError(t, err)
+
+		uri, err := doc.Service[0].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/didcomm2", uri)
+	})
+
+	t.Run("key type change updates the doc", func(t *testing.T) {
+		docBytes = store.Store[storeDIDDocKey].Value
+		ctx.KeyTypeValue = kms.ECDSAP256TypeDER
+
+		require.Equal(t, didID, initialize("example.com", "https://example.com/didcomm2"))
+		require.NotEqual(t, docBytes, store.Store[storeDIDDocKey].Value)
+
+		docBytes = store.Store[storeDIDDocKey].Value
+		ctx.KeyTypeValue = kms.ECDSAP256TypeIEEEP1363
+
+		require.Equal(t, didID, initialize("example.com", "https://example.com/didcomm2"))
+		require.Equal(t, docBytes, store.Store[storeDIDDocKey].Value)
+	})
+
+	t.Run("configured endpoints are all advertised", func(t *testing.T) {
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{
+			Method:    PublicDIDMethodWeb,
+			WebDomain: "example.com",
+			DIDCommEndpoints: []DIDCommEndpoint{
+				{URI: "https://example.com/didcomm", Priority: 1},
+				{URI: "wss://example.com/didcomm", Accept: []string{"didcomm/v2"}},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = pdg.Initialize("")
+		require.NoError(t, err)
+
+		served, err := GetPublicDIDDoc(ctx.StorageProvider())
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(served)
+		require.NoError(t, err)
+		require.Len(t, doc.Service, 2)
+		require.Equal(t, "wss://example.com/didcomm (accept didcomm/v2), https://example.com/didcomm",
+			formatEndpoints(docEndpoints(doc)))
+
+		docBytes = store.Store[storeDIDDocKey].Value
+
+		_, err = pdg.Initialize("wss://example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, docBytes, store.Store[storeDIDDocKey].Value)
+	})
+
+	t.Run("domain change creates a new DID", func(t *testing.T) {
+		require.Equal(t, "did:web:mediator.example.com", initialize("mediator.example.com", "https://example.com/didcomm"))
+	})
+
+	t.Run("unreadable doc is recreated", func(t *testing.T) {
+		store.Store[storeDIDDocKey] = mockstore.DBEntry{Value: []byte("{")}
+
+		require.Equal(t, "did:web:mediator.example.com", initialize("mediator.example.com", "https://example.com/didcomm"))
+		require.NotEqual(t, []byte("{"), store.Store[storeDIDDocKey].Value)
+	})
+}
+
+func TestKeyAndPeerDIDDrift(t *testing.T) {
+	newInitialize := func(t *testing.T, method string) (*mockprovider.Provider, func(string, bool) string) {
+		t.Helper()
+
+		ctx := addRealKMS(t, ariesMockProvider())
+		ctx.KeyTypeValue = kms.ED25519Type
+		ctx.KeyAgreementTypeValue = kms.X25519ECDHKWType
+		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(
+			&mockstore.MockStore{Store: map[string]mockstore.DBEntry{}})
+
+		return ctx, func(endpoint string, regenerate bool) string {
+			pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: method, RegenerateOnDrift: regenerate})
+			require.NoError(t, err)
+
+			didID, err := pdg.Initialize(endpoint)
+			require.NoError(t, err)
+
+			return didID
+		}
+	}
+
+	t.Run("did:peer with a stale endpoint", func(t *testing.T) {
+		ctx, initialize := newInitialize(t, PublicDIDMethodPeer)
+
+		didID := initialize("https://example.com/didcomm", true)
+		require.Equal(t, didID, initialize("https://example.com/didcomm", true))
+
+		// kept, with a warning, unless regenerated
+		require.Equal(t, didID, initialize("https://new.example.com/didcomm", false))
+
+		newDID := initialize("https://new.example.com/didcomm", true)
+		require.NotEqual(t, didID, newDID)
+		require.Equal(t, newDID, initialize("https://new.example.com/didcomm", true))
+
+		served, err := GetPublicDIDDoc(ctx.StorageProvider())
+		require.NoError(t, err)
+
+		doc, err := did.ParseDocument(served)
+		require.NoError(t, err)
+		require.Equal(t, "https://new.example.com/didcomm", formatEndpoints(docEndpoints(doc)))
+	})
+
+	t.Run("did:key with a stale key type", func(t *testing.T) {
+		ctx, initialize := newInitialize(t, PublicDIDMethodKey)
+
+		didID := initialize("https://example.com/didcomm", true)
+
+		// did:key docs have no service
+		require.Equal(t, didID, initialize("https://new.example.com/didcomm", true))
+
+		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
+		require.Equal(t, didID, initialize("https://new.example.com/didcomm", false))
+
+		newDID := initialize("https://new.example.com/didcomm", true)
+		require.NotEqual(t, didID, newDID)
+		require.Equal(t, newDID, initialize("https://new.example.com/didcomm", true))
+	})
+}
+
+func TestPublicDIDGetter_WaitResolvable(t *testing.T) {
+	const (
+		didID    = "did:orb:uAAA:test"
+		endpoint = "https://example.com/didcomm"
+	)
+
+	docWithEndpoint := func(uri string) *did.DocResolution {
+		return &did.DocResolution{DIDDocument: &did.Doc{
+			ID:      didID,
+			Service: []did.Service{{ServiceEndpoint: model.NewDIDCommV1Endpoint(uri)}},
+		}}
+	}
+
+	newGetter := func(t *testing.T, method string, resolve func(string) (*did.DocResolution, error)) *PublicDIDGetter {
+		t.Helper()
+
+		ctx := ariesMockProvider()
+		ctx.VDRegistryValue = &mockvdr.MockVDRegistry{
+			ResolveFunc: func(didID string, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				return resolve(didID)
+			},
+		}
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Method: method, Token: "t1"})
+		require.NoError(t, err)
+
+		return pdg
+	}
+
+	t.Run("success once anchored", func(t *testing.T) {
+		attempts := 0
+
+		pdg := newGetter(t, PublicDIDMethodOrb, func(string) (*did.DocResolution, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, vdrapi.ErrNotFound
+			}
+
+			return docWithEndpoint(endpoint), nil
+		})
+
+		require.NoError(t, pdg.WaitResolvable(didID, endpoint, time.Minute))
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("success: did:key has no service", func(t *testing.T) {
+		pdg := newGetter(t, PublicDIDMethodKey, func(string) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: &did.Doc{ID: "did:key:z6Mk"}}, nil
+		})
+
+		require.NoError(t, pdg.WaitResolvable("did:key:z6Mk", endpoint, time.Minute))
+	})
+
+	t.Run("fail: not resolvable", func(t *testing.T) {
+		pdg := newGetter(t, PublicDIDMethodOrb, func(string) (*did.DocResolution, error) {
+			return nil, vdrapi.ErrNotFound
+		})
+
+		err := pdg.WaitResolvable(didID, endpoint, time.Millisecond)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not resolvable")
+		require.ErrorIs(t, err, vdrapi.ErrNotFound)
+	})
+
+	t.Run("fail: stale endpoint", func(t *testing.T) {
+		pdg := newGetter(t, PublicDIDMethodOrb, func(string) (*did.DocResolution, error) {
+			return docWithEndpoint("https://old.example.com"), nil
+		})
+
+		err := pdg.WaitResolvable(didID, endpoint, time.Millisecond)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolved with DIDComm endpoint 'https://old.example.com'")
+	})
+}
+
+func TestGetPublicDIDDoc(t *testing.T) {
+	t.Run("fail: open store", func(t *testing.T) {
+		expectErr := fmt.Errorf("expected error")
+
+		_, err := GetPublicDIDDoc(&mockstore.MockStoreProvider{ErrOpenStoreHandle: expectErr})
+		require.ErrorIs(t, err, expectErr)
+	})
+
+	t.Run("fail: no public DID doc", func(t *testing.T) {
+		_, err := GetPublicDIDDoc(mockstore.NewMockStoreProvider())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get public DID doc")
+	})
+}
+
+func TestWebDIDDocPath(t *testing.T) {
+	require.Equal(t, DefaultWebDIDDocPath, WebDIDDocPath("did:web:example.com"))
+	require.Equal(t, DefaultWebDIDDocPath, WebDIDDocPath("did:web:localhost%3A8080"))
+	require.Equal(t, "/user/alice/did.json", WebDIDDocPath("did:web:example.com:user:alice"))
+}
+
+func TestPublicDIDGetter_Manage(t *testing.T) {
+	const didID = "did:orb:uAAA:test"
+
+	newGetter := func(t *testing.T, vdr *mockVDR) (*PublicDIDGetter, *mockstore.MockStore) {
+		t.Helper()
+
+		ctx := addRealKMS(t, ariesMockProvider())
+		addRealCrypto(t, ctx)
+		ctx.KeyTypeValue = kms.ECDSAP256TypeIEEEP1363
+		ctx.KeyAgreementTypeValue = kms.NISTP256ECDHKWType
+
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{
+			storeDIDKey: {Value: []byte(didID)},
+		}}
+		ctx.StorageProviderValue = mockstore.NewCustomMockStoreProvider(store)
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1", DIDCommEndPoint: "https://example.com/didcomm"})
+		require.NoError(t, err)
+
+		pdg.creator = &orbCreator{vdr: vdr, keys: newOrbKeys(ctx, store)}
+
+		if vdr.readFunc == nil {
+			vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				return &did.DocResolution{DIDDocument: &did.Doc{ID: didID}}, nil
+			}
+		}
+
+		return pdg, store
+	}
+
+	currentDoc := func(t *testing.T, pdg *PublicDIDGetter) *did.Doc {
+		t.Helper()
+
+		doc, err := pdg.docTemplate("https://old.example.com/didcomm")
+		require.NoError(t, err)
+
+		doc.ID = didID
+
+		return doc
+	}
+
+	t.Run("update keeps the keys and sets the endpoint", func(t *testing.T) {
+		var updated *did.Doc
+
+		vdr := &mockVDR{updateFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) error {
+			updated = didDoc
+
+			return nil
+		}}
+
+		pdg, store := newGetter(t, vdr)
+		current := currentDoc(t, pdg)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: current}, nil
+		}
+
+		ctx := pdg.ctx.(*mockprovider.Provider)
+		keys := &countingKMS{KeyManager: ctx.KMSValue}
+		ctx.KMSValue = keys
+
+		doc, err := pdg.Update("", false)
+		require.NoError(t, err)
+		require.Zero(t, keys.created)
+		require.Equal(t, updated, doc)
+		require.Equal(t, didID, doc.ID)
+		require.Equal(t, current.Authentication, doc.Authentication)
+		require.Equal(t, current.KeyAgreement, doc.KeyAgreement)
+		require.Equal(t, current.Service[0].ID, doc.Service[0].ID)
+
+		uri, err := doc.Service[0].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/didcomm", uri)
+		require.NotEmpty(t, store.Store[storeDIDDocKey].Value)
+	})
+
+	t.Run("update replaces keys of other types", func(t *testing.T) {
+		vdr := &mockVDR{}
+
+		pdg, _ := newGetter(t, vdr)
+		current := currentDoc(t, pdg)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: current}, nil
+		}
+
+		pdg.ctx.(*mockprovider.Provider).KeyTypeValue = kms.ED25519Type
+
+		doc, err := pdg.Update("https://new.example.com/didcomm", false)
+		require.NoError(t, err)
+		require.NotEqual(t, current.Authentication, doc.Authentication)
+		require.Equal(t, current.KeyAgreement, doc.KeyAgreement)
+	})
+
+	t.Run("update rotates the keys on request", func(t *testing.T) {
+		vdr := &mockVDR{}
+
+		pdg, _ := newGetter(t, vdr)
+		current := currentDoc(t, pdg)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: current}, nil
+		}
+
+		ctx := pdg.ctx.(*mockprovider.Provider)
+		keys := &countingKMS{KeyManager: ctx.KMSValue}
+		ctx.KMSValue = keys
+
+		doc, err := pdg.Update("", true)
+		require.NoError(t, err)
+		require.Equal(t, 2, keys.created)
+		require.NotEqual(t, current.Authentication, doc.Authentication)
+		require.NotEqual(t, current.KeyAgreement, doc.KeyAgreement)
+	})
+
+	t.Run("recover uses new keys", func(t *testing.T) {
+		var recoverOpt bool
+
+		vdr := &mockVDR{updateFunc: func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) error {
+			didOpts := &vdrapi.DIDMethodOpts{Values: map[string]interface{}{}}
+			for _, opt := range opts {
+				opt(didOpts)
+			}
+
+			recoverOpt = didOpts.Values[orb.RecoverOpt] == true
+
+			return nil
+		}}
+
+		pdg, _ := newGetter(t, vdr)
+
+		doc, err := pdg.Recover("https://new.example.com/didcomm")
+		require.NoError(t, err)
+		require.True(t, recoverOpt)
+		require.Equal(t, didID, doc.ID)
+	})
+
+	t.Run("get returns the saved doc", func(t *testing.T) {
+		pdg, store := newGetter(t, &mockVDR{})
+
+		_, err := pdg.Get()
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		require.NoError(t, pdg.saveDoc(&did.Doc{ID: didID, Context: []string{did.ContextV1}}))
+
+		doc, err := pdg.Get()
+		require.NoError(t, err)
+		require.Equal(t, didID, doc.ID)
+
+		delete(store.Store, storeDIDKey)
+
+		_, err = pdg.Get()
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("deactivate deletes the public DID", func(t *testing.T) {
+		var deactivated string
+
+		pdg, store := newGetter(t, &mockVDR{deactivateFunc: func(id string, _ ...vdrapi.DIDMethodOption) error {
+			deactivated = id
+
+			return nil
+		}})
+
+		res, err := pdg.Deactivate()
+		require.NoError(t, err)
+		require.Equal(t, didID, res)
+		require.Equal(t, didID, deactivated)
+		require.NotContains(t, store.Store, storeDIDKey)
+	})
+
+	t.Run("the next keys of an operation with an unknown outcome are reconciled", func(t *testing.T) {
+		vdr := &mockVDR{}
+
+		pdg, store := newGetter(t, vdr)
+		keys := pdg.creator.(*orbCreator).keys
+
+		require.NoError(t, keys.save("update-1", "recovery-1"))
+
+		// the update succeeded but its response was lost
+		_, err := keys.GetNextUpdatePublicKey(didID, "")
+		require.NoError(t, err)
+
+		nextUpdateKID := string(store.Store[storeNextUpdateKeyIDKey].Value)
+
+		updateCommitment, err := keys.commitment(nextUpdateKID)
+		require.NoError(t, err)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{
+				DIDDocument:      &did.Doc{ID: didID},
+				DocumentMetadata: &did.DocumentMetadata{Method: &did.MethodMetadata{UpdateCommitment: updateCommitment}},
+			}, nil
+		}
+
+		_, err = pdg.Deactivate()
+		require.NoError(t, err)
+		require.Equal(t, nextUpdateKID, string(store.Store[storeUpdateKeyIDKey].Value))
+		require.Equal(t, "recovery-1", string(store.Store[storeRecoveryKeyIDKey].Value))
+	})
+
+	t.Run("fail: resolve to reconcile the keys", func(t *testing.T) {
+		pdg, _ := newGetter(t, &mockVDR{readFunc: func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return nil, errors.New("resolve error")
+		}})
+
+		_, err := pdg.Recover("")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolve orb DID did:orb:uAAA:test")
+	})
+
+	t.Run("operations are serialized", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+
+		vdr := &mockVDR{updateFunc: func(*did.Doc, ...vdrapi.DIDMethodOption) error {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			return nil
+		}}
+
+		pdg, _ := newGetter(t, vdr)
+		current := currentDoc(t, pdg)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: current}, nil
+		}
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, e := pdg.Recover("")
+				require.NoError(t, e)
+			}()
+		}
+
+		wg.Wait()
+		require.Equal(t, int32(1), maxInFlight)
+	})
+
+	t.Run("initialize updates a drifted DID", func(t *testing.T) {
+		updates := 0
+
+		vdr := &mockVDR{updateFunc: func(*did.Doc, ...vdrapi.DIDMethodOption) error {
+			updates++
+
+			return nil
+		}}
+
+		pdg, _ := newGetter(t, vdr)
+		current := currentDoc(t, pdg)
+
+		vdr.readFunc = func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return &did.DocResolution{DIDDocument: current}, nil
+		}
+
+		res, err := pdg.Initialize("https://old.example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, didID, res)
+		require.Equal(t, 0, updates)
+
+		res, err = pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, didID, res)
+		require.Equal(t, 1, updates)
+	})
+
+	t.Run("doc changes", func(t *testing.T) {
+		pdg, _ := newGetter(t, &mockVDR{})
+		current := currentDoc(t, pdg)
+
+		require.Empty(t, pdg.docChanges(current, "https://old.example.com/didcomm"))
+
+		pdg.ctx.(*mockprovider.Provider).KeyAgreementTypeValue = kms.X25519ECDHKWType
+
+		require.Equal(t, []string{
+			"DIDComm endpoint 'https://old.example.com/didcomm' -> 'https://example.com/didcomm'",
+			"key agreement key type 'NISTP256ECDHKW' -> 'X25519ECDHKW'",
+		}, pdg.docChanges(current, "https://example.com/didcomm"))
+
+		require.Len(t, pdg.docChanges(&did.Doc{}, ""), 2)
+	})
+
+	t.Run("initialize keeps a DID that can't be resolved", func(t *testing.T) {
+		pdg, _ := newGetter(t, &mockVDR{readFunc: func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+			return nil, fmt.Errorf("not found")
+		}})
+
+		res, err := pdg.Initialize("https://example.com/didcomm")
+		require.NoError(t, err)
+		require.Equal(t, didID, res)
+	})
+
+	t.Run("fail: vdr errors", func(t *testing.T) {
+		expectErr := fmt.Errorf("expected error")
+
+		pdg, _ := newGetter(t, &mockVDR{
+			readFunc: func(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				return nil, expectErr
+			},
+			updateFunc: func(*did.Doc, ...vdrapi.DIDMethodOption) error {
+				return expectErr
+			},
+			deactivateFunc: func(string, ...vdrapi.DIDMethodOption) error {
+				return expectErr
+			},
+		})
+
+		_, err := pdg.Update("", false)
+		require.ErrorIs(t, err, expectErr)
+		require.Contains(t, err.Error(), "resolve public DID")
+
+		_, err = pdg.Recover("")
+		require.ErrorIs(t, err, expectErr)
+		require.Contains(t, err.Error(), "recover public DID")
+
+		_, err = pdg.Deactivate()
+		require.ErrorIs(t, err, expectErr)
+		require.Contains(t, err.Error(), "deactivate public DID")
+	})
+
+	t.Run("fail: no public DID", func(t *testing.T) {
+		pdg, store := newGetter(t, &mockVDR{})
+		delete(store.Store, storeDIDKey)
+
+		_, err := pdg.Update("", false)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get public DID")
+	})
+
+	t.Run("fail: method without updates", func(t *testing.T) {
+		pdg, err := NewPublicDIDGetter(ariesMockProvider(), &PublicDIDConfig{Method: PublicDIDMethodKey})
+		require.NoError(t, err)
+
+		_, err = pdg.Deactivate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "public DID method key doesn't support updates")
+	})
+}
+
+func TestWebDID(t *testing.T) {
+	require.Equal(t, "did:web:example.com", WebDID("example.com"))
+	require.Equal(t, "did:web:example.com", WebDID("https://example.com/"))
+	require.Equal(t, "did:web:localhost%3A8080", WebDID("localhost:8080"))
+	require.Equal(t, "did:web:example.com:user:alice", WebDID("example.com/user/alice"))
 }
 
 func TestPublicDIDGetter_createVerification(t *testing.T) {
@@ -189,7 +1061,7 @@ func TestPublicDIDGetter_createVerification(t *testing.T) {
 
 		ctx.KMSValue = &mockkms.KeyManager{CrAndExportPubKeyValue: []byte("foo bar baz")}
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.createVerification("foo", "foo", 0)
@@ -203,12 +1075,40 @@ func TestPublicDIDGetter_createVerification(t *testing.T) {
 		ctx.KMSValue = &mockkms.KeyManager{CrAndExportPubKeyValue: []byte("foo bar baz")}
 		ctx.KeyTypeValue = kms.ED25519Type
 
-		pdg, err := newPublicDIDGetter(ctx, nil, nil, "t1")
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
 		require.NoError(t, err)
 
 		_, err = pdg.createVerification("foo", kms.ED25519Type, 0)
 		require.NoError(t, err)
 	})
+
+	t.Run("success: BLS12-381 G2 key", func(t *testing.T) {
+		ctx := addRealKMS(t, ariesMockProvider())
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
+		require.NoError(t, err)
+
+		vm, err := pdg.createVerification("#key-1", kms.BLS12381G2Type, did.AssertionMethod)
+		require.NoError(t, err)
+		require.Equal(t, "BLS12381_G2", vm.VerificationMethod.JSONWebKey().Crv)
+
+		kt, err := KeyTypeFor(&vm.VerificationMethod, did.AssertionMethod)
+		require.NoError(t, err)
+		require.Equal(t, kms.BLS12381G2Type, kt)
+	})
+
+	t.Run("fail: invalid BLS12-381 G2 key", func(t *testing.T) {
+		ctx := ariesMockProvider()
+
+		ctx.KMSValue = &mockkms.KeyManager{CrAndExportPubKeyValue: []byte("foo bar baz")}
+
+		pdg, err := NewPublicDIDGetter(ctx, &PublicDIDConfig{Token: "t1"})
+		require.NoError(t, err)
+
+		_, err = pdg.createVerification("#key-1", kms.BLS12381G2Type, did.AssertionMethod)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "creating jwk from BLS12-381 G2 key")
+	})
 }
 
 func TestCreateVerification(t *testing.T) {
@@ -239,6 +1139,19 @@ func TestCreateVerification(t *testing.T) {
 		require.Equal(t, "X25519KeyAgreementKey2019", vm.VerificationMethod.Type)
 	})
 
+	t.Run("success: BLS12-381 G2 key", func(t *testing.T) {
+		ctx := ariesMockProvider()
+		ctx = addRealKMS(t, ctx)
+
+		vm, err := CreateVerification(ctx.KMS(), "#key-1", kms.BLS12381G2Type, did.AssertionMethod)
+		require.NoError(t, err)
+		require.Equal(t, "Bls12381G2Key2020", vm.VerificationMethod.Type)
+
+		kt, err := KeyTypeFor(&vm.VerificationMethod, did.AssertionMethod)
+		require.NoError(t, err)
+		require.Equal(t, kms.BLS12381G2Type, kt)
+	})
+
 	t.Run("fail: creating VM from bad data for X25519 key", func(t *testing.T) {
 		_, err := CreateVerification(&mockkms.KeyManager{CrAndExportPubKeyValue: []byte("uh oh")},
 			"#key-1", kms.X25519ECDHKWType, did.Authentication)
@@ -279,8 +1192,23 @@ func addRealKMS(t *testing.T, ctx *mockprovider.Provider) *mockprovider.Provider
 	return ctx
 }
 
+type mockLocker struct {
+	tryAcquireErr error
+}
+
+func (m *mockLocker) TryAcquire(string, time.Duration) (bool, error) {
+	return m.tryAcquireErr == nil, m.tryAcquireErr
+}
+
+func (m *mockLocker) Release(string) error {
+	return nil
+}
+
 type mockVDR struct {
-	createFunc func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+	createFunc     func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+	readFunc       func(didID string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+	updateFunc     func(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) error
+	deactivateFunc func(didID string, opts ...vdrapi.DIDMethodOption) error
 }
 
 func (m *mockVDR) Create(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
@@ -290,3 +1218,39 @@ func (m *mockVDR) Create(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) (*did.
 
 	return nil, nil
 }
+
+func (m *mockVDR) Read(didID string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	if m.readFunc != nil {
+		return m.readFunc(didID, opts...)
+	}
+
+	return nil, nil
+}
+
+func (m *mockVDR) Update(didDoc *did.Doc, opts ...vdrapi.DIDMethodOption) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(didDoc, opts...)
+	}
+
+	return nil
+}
+
+func (m *mockVDR) Deactivate(didID string, opts ...vdrapi.DIDMethodOption) error {
+	if m.deactivateFunc != nil {
+		return m.deactivateFunc(didID, opts...)
+	}
+
+	return nil
+}
+
+// countingKMS counts the keys created.
+type countingKMS struct {
+	kms.KeyManager
+	created int
+}
+
+func (k *countingKMS) CreateAndExportPubKeyBytes(kt kms.KeyType) (string, []byte, error) {
+	k.created++
+
+	return k.KeyManager.CreateAndExportPubKeyBytes(kt)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/tink"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+// MasterKeySize is the size of the master key of the local secret lock in bytes.
+const MasterKeySize = 32
+
+// masterKeyURI is the key URI the aries local KMS wraps its keys with, without its 'local-lock://' prefix.
+const masterKeyURI = "default/master/key/"
+
+// ParseMasterKey decodes a base64URL encoded master key of the local secret lock.
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(encoded), "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode master key : %w", err)
+	}
+
+	if len(key) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// ReadMasterKey reads a base64URL encoded master key of the local secret lock from a file.
+func ReadMasterKey(path string) ([]byte, error) {
+	encoded, err := ioutil.ReadFile(path) // nolint:gosec // path set by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read master key : %w", err)
+	}
+
+	key, err := ParseMasterKey(string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key file %s : %w", path, err)
+	}
+
+	return key, nil
+}
+
+// NewSecretLock returns the local secret lock encrypting the private keys of the KMS with the master key. Without a
+// master key, the private keys are stored unencrypted.
+func NewSecretLock(masterKey []byte) (secretlock.Service, error) {
+	if masterKey == nil {
+		return &noop.NoLock{}, nil
+	}
+
+	lock, err := local.NewService(strings.NewReader(base64.URLEncoding.EncodeToString(masterKey)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create secret lock : %w", err)
+	}
+
+	return lock, nil
+}
+
+// RewrapKeys re-encrypts the private keys of the local KMS stored in the provider, encrypted with the from secret
+// lock, with the to secret lock. Keys already encrypted with the to secret lock are skipped, so that an interrupted
+// run can be resumed. Returns the number of keys re-encrypted.
+func RewrapKeys(provider storage.Provider, from, to secretlock.Service) (int, error) {
+	store, err := provider.OpenStore(localkms.Namespace)
+	if err != nil {
+		return 0, fmt.Errorf("open kms store : %w", err)
+	}
+
+	keys, err := readAll(store)
+	if err != nil {
+		return 0, err
+	}
+
+	fromAEAD := aead.NewKMSEnvelopeAEAD2(aead.AES256GCMKeyTemplate(), &lockAEAD{lock: from})
+	toAEAD := aead.NewKMSEnvelopeAEAD2(aead.AES256GCMKeyTemplate(), &lockAEAD{lock: to})
+
+	count := 0
+
+	for _, entry := range keys {
+		handle, err := keyset.Read(keyset.NewJSONReader(bytes.NewReader(entry.value)), fromAEAD)
+		if err != nil {
+			_, toErr := keyset.Read(keyset.NewJSONReader(bytes.NewReader(entry.value)), toAEAD)
+			if toErr == nil {
+				continue
+			}
+
+			return count, fmt.Errorf("decrypt key %s : %w", entry.key, err)
+		}
+
+		buf := &bytes.Buffer{}
+
+		err = handle.Write(keyset.NewJSONWriter(buf), toAEAD)
+		if err != nil {
+			return count, fmt.Errorf("encrypt key %s : %w", entry.key, err)
+		}
+
+		err = store.Put(entry.key, buf.Bytes())
+		if err != nil {
+			return count, fmt.Errorf("store key %s : %w", entry.key, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+type entry struct {
+	key   string
+	value []byte
+}
+
+// readAll reads the store before it's updated, since some iterators don't support concurrent updates.
+func readAll(store storage.Store) ([]entry, error) {
+	it, err := enumerable.All(store)
+	if err != nil {
+		return nil, err
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	var entries []entry
+
+	for {
+		ok, err := it.Next()
+		if err != nil {
+			return nil, fmt.Errorf("list kms store : %w", err)
+		}
+
+		if !ok {
+			return entries, nil
+		}
+
+		key, err := it.Key()
+		if err != nil {
+			return nil, fmt.Errorf("list kms store : %w", err)
+		}
+
+		value, err := it.Value()
+		if err != nil {
+			return nil, fmt.Errorf("list kms store : %w", err)
+		}
+
+		entries = append(entries, entry{key: key, value: value})
+	}
+}
+
+// lockAEAD wraps the data encryption keys of the local KMS with a secret lock, like the KMS does.
+type lockAEAD struct {
+	lock secretlock.Service
+}
+
+var _ tink.AEAD = (*lockAEAD)(nil)
+
+func (a *lockAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	resp, err := a.lock.Encrypt(masterKeyURI, &secretlock.EncryptRequest{
+		Plaintext:                   base64.URLEncoding.EncodeToString(plaintext),
+		AdditionalAuthenticatedData: base64.URLEncoding.EncodeToString(additionalData),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.URLEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (a *lockAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	resp, err := a.lock.Decrypt(masterKeyURI, &secretlock.DecryptRequest{
+		Ciphertext:                  base64.URLEncoding.EncodeToString(ciphertext),
+		AdditionalAuthenticatedData: base64.URLEncoding.EncodeToString(additionalData),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.URLEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return nil, errors.New("invalid plaintext")
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package aries
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+type kmsProvider struct {
+	store storage.Provider
+	lock  secretlock.Service
+}
+
+func (p *kmsProvider) StorageProvider() storage.Provider {
+	return p.store
+}
+
+func (p *kmsProvider) SecretLock() secretlock.Service {
+	return p.lock
+}
+
+func newMasterKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, MasterKeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func newLock(t *testing.T, masterKey []byte) secretlock.Service {
+	t.Helper()
+
+	lock, err := NewSecretLock(masterKey)
+	require.NoError(t, err)
+
+	return lock
+}
+
+func newLocalKMS(t *testing.T, store storage.Provider, lock secretlock.Service) *localkms.LocalKMS {
+	t.Helper()
+
+	k, err := localkms.New("local-lock://"+masterKeyURI, &kmsProvider{store: store, lock: lock})
+	require.NoError(t, err)
+
+	return k
+}
+
+func TestParseMasterKey(t *testing.T) {
+	key := newMasterKey(t)
+
+	parsed, err := ParseMasterKey(base64.URLEncoding.EncodeToString(key) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+
+	parsed, err = ParseMasterKey(base64.RawURLEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+
+	_, err = ParseMasterKey("not base64!")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "decode master key")
+
+	_, err = ParseMasterKey(base64.URLEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "master key must be 32 bytes")
+}
+
+func TestReadMasterKey(t *testing.T) {
+	key := newMasterKey(t)
+	path := filepath.Join(t.TempDir(), "master.key")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.URLEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	read, err := ReadMasterKey(path)
+	require.NoError(t, err)
+	require.Equal(t, key, read)
+
+	_, err = ReadMasterKey(filepath.Join(t.TempDir(), "missing.key"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "read master key")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0o600))
+
+	_, err = ReadMasterKey(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "master key file "+path)
+}
+
+func TestNewSecretLock(t *testing.T) {
+	lock, err := NewSecretLock(nil)
+	require.NoError(t, err)
+	require.IsType(t, &noop.NoLock{}, lock)
+
+	_, err = NewSecretLock([]byte("short"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "create secret lock")
+}
+
+func TestRewrapKeys(t *testing.T) {
+	store := enumerable.NewProvider(mem.NewProvider())
+
+	unlocked := newLock(t, nil)
+	first := newLock(t, newMasterKey(t))
+	second := newLock(t, newMasterKey(t))
+
+	kid, _, err := newLocalKMS(t, store, unlocked).Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	t.Run("introduce a master key", func(t *testing.T) {
+		count, err := RewrapKeys(store, unlocked, first)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		_, err = newLocalKMS(t, store, first).Get(kid)
+		require.NoError(t, err)
+
+		_, err = newLocalKMS(t, store, unlocked).Get(kid)
+		require.Error(t, err)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		count, err := RewrapKeys(store, unlocked, first)
+		require.NoError(t, err)
+		require.Equal(t, 0, count)
+	})
+
+	t.Run("rotate the master key", func(t *testing.T) {
+		otherKID, _, err := newLocalKMS(t, store, first).Create(kms.NISTP256ECDHKWType)
+		require.NoError(t, err)
+
+		count, err := RewrapKeys(store, first, second)
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		for _, id := range []string{kid, otherKID} {
+			_, err = newLocalKMS(t, store, second).Get(id)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("wrong master key", func(t *testing.T) {
+		_, err := RewrapKeys(store, first, newLock(t, newMasterKey(t)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decrypt key")
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		_, err := RewrapKeys(&mockstore.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")}, first, second)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open kms store")
+	})
+}
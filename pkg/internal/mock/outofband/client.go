@@ -13,11 +13,12 @@ type MockClient struct {
 	CreateInvitationErr error
 }
 
-// CreateInvitation creates a mock outofband invitation.
-func (c *MockClient) CreateInvitation([]interface{}, ...outofband.MessageOption) (*outofband.Invitation, error) {
+// CreateInvitation creates a mock outofband invitation, with the given services.
+func (c *MockClient) CreateInvitation(services []interface{},
+	_ ...outofband.MessageOption) (*outofband.Invitation, error) {
 	if c.CreateInvitationErr != nil {
 		return nil, c.CreateInvitationErr
 	}
 
-	return &outofband.Invitation{}, nil
+	return &outofband.Invitation{Services: services}, nil
 }
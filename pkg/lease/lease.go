@@ -0,0 +1,202 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package lease provides named leases shared by the mediator instances using the same storage, so that one-off
+// tasks like the creation of the public DID run on a single instance.
+package lease
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hyperledger/aries-framework-go-ext/component/storage/mongodb"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	storeName = "lease"
+
+	maxPollInterval = 5 * time.Second
+	renewalsPerTTL  = 3
+)
+
+var logger = log.New("mediator/lease")
+
+// ErrTimeout is returned by Acquire when the lease couldn't be acquired in time.
+var ErrTimeout = errors.New("timed out waiting for lease")
+
+// Locker hands out named leases.
+type Locker interface {
+	// TryAcquire acquires the lease if it's free or expired, or renews it if already held by the owner of the Locker.
+	// Returns false if the lease is held by another owner.
+	TryAcquire(name string, ttl time.Duration) (bool, error)
+	// Release releases the lease if held by the owner of the Locker.
+	Release(name string) error
+}
+
+// record is the stored state of a lease.
+type record struct {
+	Owner string `json:"owner"`
+	// ExpiresAt is the lease expiry as a unix time in milliseconds.
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// unwrapper is a storage provider decorating another one, like an encrypting provider. Leases are kept in the
+// underlying provider since they hold no user data.
+type unwrapper interface {
+	Unwrap() storage.Provider
+}
+
+// New returns the Locker of the given owner for the storage provider. MongoDB providers get leases shared by all the
+// instances using the same database, using conditional writes; other providers get leases local to the process, so
+// the instances sharing a CouchDB, MySQL or PostgreSQL database aren't serialized.
+func New(provider storage.Provider, owner string) (Locker, error) {
+	for {
+		u, ok := provider.(unwrapper)
+		if !ok {
+			break
+		}
+
+		provider = u.Unwrap()
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open lease store : %w", err)
+	}
+
+	if mongoStore, ok := store.(*mongodb.Store); ok {
+		return &mongoLocker{store: mongoStore, owner: owner}, nil
+	}
+
+	return &memLocker{store: store, owner: owner}, nil
+}
+
+// ErrLost is returned by Lease.Err when the lease couldn't be renewed before it expired.
+var ErrLost = errors.New("lease lost")
+
+// Lease is an acquired lease. It's renewed every third of its ttl until released, so that it's held by tasks taking
+// longer than the ttl.
+type Lease struct {
+	locker Locker
+	name   string
+	ttl    time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Acquire waits until the lease is acquired, polling with exponential backoff for at most maxWait. The lease must be
+// released.
+func Acquire(l Locker, name string, ttl, maxWait time.Duration) (*Lease, error) {
+	b := backoff.NewExponentialBackOff()
+	b.MaxInterval = maxPollInterval
+	b.MaxElapsedTime = maxWait
+
+	err := backoff.Retry(func() error {
+		acquired, err := l.TryAcquire(name, ttl)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		if !acquired {
+			return ErrTimeout
+		}
+
+		return nil
+	}, b)
+	if err != nil {
+		return nil, fmt.Errorf("acquire lease '%s' : %w", name, err)
+	}
+
+	held := &Lease{
+		locker: l,
+		name:   name,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go held.renew(time.Now())
+
+	return held, nil
+}
+
+func (l *Lease) renew(renewed time.Time) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / renewalsPerTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+
+		acquired, err := l.locker.TryAcquire(l.name, l.ttl)
+
+		switch {
+		case err != nil:
+			logger.Warnf("renew lease '%s' : %s", l.name, err)
+
+			if now.Sub(renewed) < l.ttl {
+				// retried on the next tick
+				continue
+			}
+
+			l.lost(fmt.Errorf("%w : renew lease '%s' : %s", ErrLost, l.name, err))
+		case !acquired:
+			l.lost(fmt.Errorf("%w : lease '%s' held by another owner", ErrLost, l.name))
+		case now.Sub(renewed) >= l.ttl:
+			// reacquired after it expired, another owner may have held it meanwhile
+			l.lost(fmt.Errorf("%w : lease '%s' expired", ErrLost, l.name))
+		default:
+			renewed = now
+
+			continue
+		}
+
+		return
+	}
+}
+
+func (l *Lease) lost(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.err = err
+}
+
+// Err returns ErrLost if the lease couldn't be renewed, in which case another owner may hold it.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Release stops renewing the lease and releases it.
+func (l *Lease) Release() error {
+	close(l.stop)
+	<-l.done
+
+	return l.locker.Release(l.name)
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
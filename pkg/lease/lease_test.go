@@ -0,0 +1,297 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package lease
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("mem", func(t *testing.T) {
+		l, err := New(mem.NewProvider(), "a")
+		require.NoError(t, err)
+		require.IsType(t, &memLocker{}, l)
+	})
+
+	t.Run("open store error", func(t *testing.T) {
+		_, err := New(&mockstore.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")}, "a")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open lease store")
+	})
+
+	t.Run("underlying provider of a decorator", func(t *testing.T) {
+		l, err := New(&wrappedProvider{
+			MockStoreProvider: &mockstore.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")},
+			underlying:        mem.NewProvider(),
+		}, "a")
+		require.NoError(t, err)
+		require.IsType(t, &memLocker{}, l)
+	})
+}
+
+type wrappedProvider struct {
+	*mockstore.MockStoreProvider
+	underlying storage.Provider
+}
+
+func (p *wrappedProvider) Unwrap() storage.Provider {
+	return p.underlying
+}
+
+func TestMemLocker(t *testing.T) {
+	provider := mem.NewProvider()
+
+	a, err := New(provider, "a")
+	require.NoError(t, err)
+
+	b, err := New(provider, "b")
+	require.NoError(t, err)
+
+	t.Run("exclusive until released", func(t *testing.T) {
+		acquired, err := a.TryAcquire("l1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		acquired, err = a.TryAcquire("l1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		acquired, err = b.TryAcquire("l1", time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+
+		require.NoError(t, b.Release("l1"))
+
+		acquired, err = b.TryAcquire("l1", time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+
+		require.NoError(t, a.Release("l1"))
+
+		acquired, err = b.TryAcquire("l1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		acquired, err := a.TryAcquire("l2", time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		time.Sleep(5 * time.Millisecond)
+
+		acquired, err = b.TryAcquire("l2", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		l := &memLocker{store: &mockstore.MockStore{
+			Store:  map[string]mockstore.DBEntry{},
+			ErrGet: errors.New("get error"),
+		}, owner: "a"}
+
+		_, err := l.TryAcquire("l", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get lease")
+
+		l = &memLocker{store: &mockstore.MockStore{
+			Store:  map[string]mockstore.DBEntry{},
+			ErrPut: errors.New("put error"),
+		}, owner: "a"}
+
+		_, err = l.TryAcquire("l", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "save lease")
+
+		l = &memLocker{store: &mockstore.MockStore{
+			Store: map[string]mockstore.DBEntry{"l": {Value: []byte("{")}},
+		}, owner: "a"}
+
+		err = l.Release("l")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal lease")
+	})
+}
+
+func TestAcquire(t *testing.T) {
+	provider := mem.NewProvider()
+
+	a, err := New(provider, "a")
+	require.NoError(t, err)
+
+	b, err := New(provider, "b")
+	require.NoError(t, err)
+
+	t.Run("waits for release", func(t *testing.T) {
+		held, err := Acquire(a, "l1", time.Minute, time.Second)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			require.NoError(t, held.Release())
+		}()
+
+		held, err = Acquire(b, "l1", time.Minute, 10*time.Second)
+		require.NoError(t, err)
+		require.NoError(t, held.Release())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		held, err := Acquire(a, "l2", time.Minute, time.Second)
+		require.NoError(t, err)
+
+		defer held.Release() // nolint:errcheck // test
+
+		_, err = Acquire(b, "l2", time.Minute, 100*time.Millisecond)
+		require.ErrorIs(t, err, ErrTimeout)
+		require.Contains(t, err.Error(), "acquire lease 'l2'")
+	})
+
+	t.Run("locker error", func(t *testing.T) {
+		l := &memLocker{store: &mockstore.MockStore{
+			Store:  map[string]mockstore.DBEntry{},
+			ErrGet: errors.New("get error"),
+		}, owner: "a"}
+
+		_, err := Acquire(l, "l3", time.Minute, time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get error")
+	})
+
+	t.Run("renewed while held", func(t *testing.T) {
+		held, err := Acquire(a, "l4", 60*time.Millisecond, time.Second)
+		require.NoError(t, err)
+
+		time.Sleep(200 * time.Millisecond)
+
+		acquired, err := b.TryAcquire("l4", time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+		require.NoError(t, held.Err())
+
+		require.NoError(t, held.Release())
+
+		acquired, err = b.TryAcquire("l4", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	})
+
+	t.Run("lost to another owner", func(t *testing.T) {
+		l := &mockLocker{acquired: []bool{true, false}}
+
+		held, err := Acquire(l, "l5", 30*time.Millisecond, time.Second)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return held.Err() != nil }, time.Second, 10*time.Millisecond)
+		require.ErrorIs(t, held.Err(), ErrLost)
+		require.Contains(t, held.Err().Error(), "held by another owner")
+		require.NoError(t, held.Release())
+	})
+
+	t.Run("lost when not renewed in time", func(t *testing.T) {
+		l := &mockLocker{acquired: []bool{true}, err: errors.New("renew error")}
+
+		held, err := Acquire(l, "l6", 30*time.Millisecond, time.Second)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return held.Err() != nil }, time.Second, 10*time.Millisecond)
+		require.ErrorIs(t, held.Err(), ErrLost)
+		require.Contains(t, held.Err().Error(), "renew error")
+		require.NoError(t, held.Release())
+	})
+}
+
+// mockLocker returns the acquired results in order, then err.
+type mockLocker struct {
+	mu       sync.Mutex
+	acquired []bool
+	err      error
+}
+
+func (m *mockLocker) TryAcquire(string, time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.acquired) == 0 {
+		return false, m.err
+	}
+
+	acquired := m.acquired[0]
+	m.acquired = m.acquired[1:]
+
+	return acquired, nil
+}
+
+func (m *mockLocker) Release(string) error {
+	return nil
+}
+
+type mockBulkWriter struct {
+	models []mongo.WriteModel
+	err    error
+}
+
+func (m *mockBulkWriter) BulkWrite(models []mongo.WriteModel, _ ...*mongooptions.BulkWriteOptions) error {
+	m.models = models
+
+	return m.err
+}
+
+func TestMongoLocker(t *testing.T) {
+	t.Run("acquire", func(t *testing.T) {
+		store := &mockBulkWriter{}
+		l := &mongoLocker{store: store, owner: "a"}
+
+		acquired, err := l.TryAcquire("l", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		require.Len(t, store.models, 1)
+		require.IsType(t, &mongo.UpdateOneModel{}, store.models[0])
+		require.True(t, *store.models[0].(*mongo.UpdateOneModel).Upsert)
+	})
+
+	t.Run("held by another owner", func(t *testing.T) {
+		l := &mongoLocker{store: &mockBulkWriter{err: errors.New("E11000 duplicate key error")}, owner: "a"}
+
+		acquired, err := l.TryAcquire("l", time.Minute)
+		require.NoError(t, err)
+		require.False(t, acquired)
+	})
+
+	t.Run("acquire error", func(t *testing.T) {
+		l := &mongoLocker{store: &mockBulkWriter{err: errors.New("connection error")}, owner: "a"}
+
+		_, err := l.TryAcquire("l", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "acquire lease : connection error")
+	})
+
+	t.Run("release", func(t *testing.T) {
+		store := &mockBulkWriter{}
+		l := &mongoLocker{store: store, owner: "a"}
+
+		require.NoError(t, l.Release("l"))
+		require.IsType(t, &mongo.DeleteOneModel{}, store.models[0])
+
+		store.err = errors.New("connection error")
+
+		err := l.Release("l")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "release lease")
+	})
+}
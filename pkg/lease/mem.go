@@ -0,0 +1,96 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package lease
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// nolint:gochecknoglobals // serializes the read-modify-write of leases of all the mem lockers of the process
+var memMutex sync.Mutex
+
+// memLocker is a Locker for storage providers without conditional writes. The leases are only exclusive among the
+// lockers of the process, which is enough for in-memory storage.
+type memLocker struct {
+	store storage.Store
+	owner string
+}
+
+func (l *memLocker) TryAcquire(name string, ttl time.Duration) (bool, error) {
+	memMutex.Lock()
+	defer memMutex.Unlock()
+
+	rec, err := l.get(name)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+
+	if rec != nil && rec.Owner != l.owner && rec.ExpiresAt >= unixMilli(now) {
+		return false, nil
+	}
+
+	recBytes, err := json.Marshal(&record{Owner: l.owner, ExpiresAt: unixMilli(now.Add(ttl))})
+	if err != nil {
+		return false, fmt.Errorf("marshal lease : %w", err)
+	}
+
+	err = l.store.Put(name, recBytes)
+	if err != nil {
+		return false, fmt.Errorf("save lease : %w", err)
+	}
+
+	return true, nil
+}
+
+func (l *memLocker) Release(name string) error {
+	memMutex.Lock()
+	defer memMutex.Unlock()
+
+	rec, err := l.get(name)
+	if err != nil {
+		return err
+	}
+
+	if rec == nil || rec.Owner != l.owner {
+		return nil
+	}
+
+	err = l.store.Delete(name)
+	if err != nil {
+		return fmt.Errorf("delete lease : %w", err)
+	}
+
+	return nil
+}
+
+func (l *memLocker) get(name string) (*record, error) {
+	recBytes, err := l.store.Get(name)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get lease : %w", err)
+	}
+
+	rec := &record{}
+
+	err = json.Unmarshal(recBytes, rec)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal lease : %w", err)
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package lease
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ownerField     = "doc.owner"
+	expiresAtField = "doc.expiresAt"
+)
+
+// bulkWriter is the part of mongodb.Store used by mongoLocker.
+type bulkWriter interface {
+	BulkWrite(models []mongo.WriteModel, opts ...*mongooptions.BulkWriteOptions) error
+}
+
+// mongoLocker is a Locker using MongoDB conditional writes: a lease document is only updated if it's expired or held
+// by the owner, otherwise the upsert fails on the duplicate lease name.
+type mongoLocker struct {
+	store bulkWriter
+	owner string
+}
+
+func (l *mongoLocker) TryAcquire(name string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	model := mongo.NewUpdateOneModel().
+		SetFilter(bson.M{
+			"_id": name,
+			"$or": bson.A{
+				bson.M{expiresAtField: bson.M{"$lt": unixMilli(now)}},
+				bson.M{ownerField: l.owner},
+			},
+		}).
+		SetUpdate(bson.M{"$set": bson.M{
+			ownerField:     l.owner,
+			expiresAtField: unixMilli(now.Add(ttl)),
+		}}).
+		SetUpsert(true)
+
+	err := l.store.BulkWrite([]mongo.WriteModel{model})
+	if err != nil {
+		if isDuplicateKeyErr(err) {
+			// held by another owner
+			return false, nil
+		}
+
+		return false, fmt.Errorf("acquire lease : %w", err)
+	}
+
+	return true, nil
+}
+
+func (l *mongoLocker) Release(name string) error {
+	model := mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": name, ownerField: l.owner})
+
+	err := l.store.BulkWrite([]mongo.WriteModel{model})
+	if err != nil {
+		return fmt.Errorf("release lease : %w", err)
+	}
+
+	return nil
+}
+
+func isDuplicateKeyErr(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key")
+}
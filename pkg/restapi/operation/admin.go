@@ -0,0 +1,44 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/trustbloc/mediator/pkg/internal/common/support"
+	"github.com/trustbloc/mediator/pkg/restapi/internal/httputil"
+)
+
+const bearerPrefix = "Bearer "
+
+// adminHandler returns a handler serving the admin endpoint only to the requests with the admin token. The admin
+// endpoints are disabled without an admin token.
+func (o *Operation) adminHandler(path, method string, handle http.HandlerFunc) Handler {
+	return support.NewHTTPHandler(path, method, func(rw http.ResponseWriter, req *http.Request) {
+		if o.adminToken == "" {
+			httputil.WriteErrorResponseWithLog(rw, http.StatusForbidden,
+				"admin endpoints are disabled, the mediator has no admin token", path, logger)
+
+			return
+		}
+
+		auth := req.Header.Get("Authorization")
+
+		if !strings.HasPrefix(auth, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, bearerPrefix)), []byte(o.adminToken)) != 1 {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="mediator-admin"`)
+			httputil.WriteErrorResponseWithLog(rw, http.StatusUnauthorized, "missing or invalid admin token", path,
+				logger)
+
+			return
+		}
+
+		handle(rw, req)
+	})
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/aries"
+	"github.com/trustbloc/mediator/pkg/restapi/internal/httputil"
+)
+
+// Connection and mediation admin endpoints.
+const (
+	connectionsPath     = "/connections"
+	connectionPath      = connectionsPath + "/{id}"
+	mediationPath       = "/mediation"
+	revokeMediationPath = mediationPath + "/{id}/revoke"
+)
+
+// ConnectionManager lists and removes the DIDComm connections of the router, and the mediation granted over them.
+type ConnectionManager interface {
+	List() ([]*connection.Record, error)
+	Get(connectionID string) (*connection.Record, error)
+	Remove(connectionID string) error
+	Mediations() ([]*aries.Mediation, error)
+	RevokeMediation(connectionID string) (int, error)
+}
+
+func (o *Operation) listConnections(rw http.ResponseWriter, _ *http.Request) {
+	records, err := o.connections.List()
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError, err.Error(), connectionsPath, logger)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &ConnectionsResp{Connections: records}, connectionsPath, logger)
+}
+
+func (o *Operation) getConnection(rw http.ResponseWriter, req *http.Request) {
+	record, err := o.connections.Get(mux.Vars(req)["id"])
+	if err != nil {
+		writeConnectionError(rw, err, connectionPath)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &ConnectionResp{Connection: record}, connectionPath, logger)
+}
+
+func (o *Operation) deleteConnection(rw http.ResponseWriter, req *http.Request) {
+	connectionID := mux.Vars(req)["id"]
+
+	err := o.connections.Remove(connectionID)
+	if err != nil {
+		writeConnectionError(rw, err, connectionPath)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &DeleteConnectionResp{ConnectionID: connectionID}, connectionPath, logger)
+}
+
+func (o *Operation) listMediations(rw http.ResponseWriter, _ *http.Request) {
+	mediations, err := o.connections.Mediations()
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError, err.Error(), mediationPath, logger)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &MediationsResp{Mediations: mediations}, mediationPath, logger)
+}
+
+func (o *Operation) revokeMediation(rw http.ResponseWriter, req *http.Request) {
+	connectionID := mux.Vars(req)["id"]
+
+	count, err := o.connections.RevokeMediation(connectionID)
+	if err != nil {
+		writeConnectionError(rw, err, revokeMediationPath)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &RevokeMediationResp{
+		ConnectionID: connectionID,
+		RevokedKeys:  count,
+	}, revokeMediationPath, logger)
+}
+
+func writeConnectionError(rw http.ResponseWriter, err error, path string) {
+	status := http.StatusInternalServerError
+
+	if errors.Is(err, storage.ErrDataNotFound) {
+		status = http.StatusNotFound
+	}
+
+	httputil.WriteErrorResponseWithLog(rw, status, err.Error(), path, logger)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/aries"
+)
+
+func TestConnectionHandlers(t *testing.T) {
+	newOperation := func(t *testing.T, m *mockConnections) *Operation {
+		t.Helper()
+
+		config := config()
+		config.Connections = m
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		return o
+	}
+
+	withID := func(method, path, id string) *http.Request {
+		return mux.SetURLVars(httptest.NewRequest(method, path, nil), map[string]string{"id": id})
+	}
+
+	t.Run("handlers", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{})
+
+		require.Len(t, o.GetRESTHandlers(), 5)
+
+		handlers := o.GetAdminRESTHandlers()
+		require.Len(t, handlers, 5)
+		require.Equal(t, connectionsPath, handlers[0].Path())
+		require.Equal(t, connectionPath, handlers[2].Path())
+		require.Equal(t, http.MethodDelete, handlers[2].Method())
+		require.Equal(t, revokeMediationPath, handlers[4].Path())
+		require.Equal(t, http.MethodPost, handlers[4].Method())
+	})
+
+	t.Run("list connections", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{records: []*connection.Record{{ConnectionID: "conn1"}}})
+
+		w := httptest.NewRecorder()
+		o.listConnections(w, httptest.NewRequest(http.MethodGet, connectionsPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := &ConnectionsResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Len(t, resp.Connections, 1)
+		require.Equal(t, "conn1", resp.Connections[0].ConnectionID)
+	})
+
+	t.Run("list connections error", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{err: errors.New("query error")})
+
+		w := httptest.NewRecorder()
+		o.listConnections(w, httptest.NewRequest(http.MethodGet, connectionsPath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "query error")
+	})
+
+	t.Run("get connection", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{records: []*connection.Record{{ConnectionID: "conn1"}}})
+
+		w := httptest.NewRecorder()
+		o.getConnection(w, withID(http.MethodGet, "/connections/conn1", "conn1"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := &ConnectionResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "conn1", resp.Connection.ConnectionID)
+
+		w = httptest.NewRecorder()
+		o.getConnection(w, withID(http.MethodGet, "/connections/conn2", "conn2"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete connection", func(t *testing.T) {
+		m := &mockConnections{records: []*connection.Record{{ConnectionID: "conn1"}}}
+		o := newOperation(t, m)
+
+		w := httptest.NewRecorder()
+		o.deleteConnection(w, withID(http.MethodDelete, "/connections/conn1", "conn1"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, m.records)
+
+		resp := &DeleteConnectionResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "conn1", resp.ConnectionID)
+
+		w = httptest.NewRecorder()
+		o.deleteConnection(w, withID(http.MethodDelete, "/connections/conn1", "conn1"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("list mediation", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{mediations: []*aries.Mediation{
+			{ConnectionID: "conn1", TheirDID: "did:peer:1", RecipientKeys: []string{"key1"}},
+		}})
+
+		w := httptest.NewRecorder()
+		o.listMediations(w, httptest.NewRequest(http.MethodGet, mediationPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := &MediationsResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Len(t, resp.Mediations, 1)
+		require.Equal(t, []string{"key1"}, resp.Mediations[0].RecipientKeys)
+	})
+
+	t.Run("list mediation error", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{err: errors.New("list error")})
+
+		w := httptest.NewRecorder()
+		o.listMediations(w, httptest.NewRequest(http.MethodGet, mediationPath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "list error")
+	})
+
+	t.Run("revoke mediation", func(t *testing.T) {
+		o := newOperation(t, &mockConnections{
+			records: []*connection.Record{{ConnectionID: "conn1"}},
+			revoked: 2,
+		})
+
+		w := httptest.NewRecorder()
+		o.revokeMediation(w, withID(http.MethodPost, "/mediation/conn1/revoke", "conn1"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := &RevokeMediationResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "conn1", resp.ConnectionID)
+		require.Equal(t, 2, resp.RevokedKeys)
+
+		w = httptest.NewRecorder()
+		o.revokeMediation(w, withID(http.MethodPost, "/mediation/conn2/revoke", "conn2"))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("admin token required", func(t *testing.T) {
+		newRouter := func(adminToken string, m *mockConnections) *mux.Router {
+			config := config()
+			config.Connections = m
+			config.AdminToken = adminToken
+
+			o, err := New(config)
+			require.NoError(t, err)
+
+			router := mux.NewRouter()
+			for _, h := range o.GetAdminRESTHandlers() {
+				router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+			}
+
+			return router
+		}
+
+		send := func(router *mux.Router, method, path, authorization string) int {
+			req := httptest.NewRequest(method, path, nil)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			return w.Code
+		}
+
+		m := &mockConnections{records: []*connection.Record{{ConnectionID: "conn1"}, {ConnectionID: "conn2"}}}
+		router := newRouter("admin-secret", m)
+
+		for _, r := range []struct{ method, path string }{
+			{http.MethodDelete, "/connections/conn1"},
+			{http.MethodPost, "/mediation/conn2/revoke"},
+			{http.MethodGet, connectionsPath},
+			{http.MethodGet, mediationPath},
+		} {
+			require.Equal(t, http.StatusUnauthorized, send(router, r.method, r.path, ""), r.path)
+			require.Equal(t, http.StatusUnauthorized, send(router, r.method, r.path, "Bearer wrong-secret"), r.path)
+		}
+
+		require.Len(t, m.records, 2)
+
+		require.Equal(t, http.StatusOK, send(router, http.MethodPost, "/mediation/conn2/revoke", "Bearer admin-secret"))
+		require.Equal(t, http.StatusOK, send(router, http.MethodDelete, "/connections/conn1", "Bearer admin-secret"))
+		require.Len(t, m.records, 1)
+
+		// the admin endpoints are disabled without an admin token
+		router = newRouter("", m)
+
+		require.Equal(t, http.StatusForbidden, send(router, http.MethodDelete, "/connections/conn2", "Bearer admin-secret"))
+		require.Equal(t, http.StatusForbidden, send(router, http.MethodPost, "/mediation/conn2/revoke", ""))
+		require.Len(t, m.records, 1)
+	})
+}
+
+type mockConnections struct {
+	records    []*connection.Record
+	mediations []*aries.Mediation
+	revoked    int
+	err        error
+}
+
+func (m *mockConnections) List() ([]*connection.Record, error) {
+	return m.records, m.err
+}
+
+func (m *mockConnections) Get(connectionID string) (*connection.Record, error) {
+	for _, record := range m.records {
+		if record.ConnectionID == connectionID {
+			return record, nil
+		}
+	}
+
+	return nil, fmt.Errorf("get connection %s : %w", connectionID, storage.ErrDataNotFound)
+}
+
+func (m *mockConnections) Remove(connectionID string) error {
+	for i, record := range m.records {
+		if record.ConnectionID == connectionID {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("get connection %s : %w", connectionID, storage.ErrDataNotFound)
+}
+
+func (m *mockConnections) Mediations() ([]*aries.Mediation, error) {
+	return m.mediations, m.err
+}
+
+func (m *mockConnections) RevokeMediation(connectionID string) (int, error) {
+	_, err := m.Get(connectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.revoked, nil
+}
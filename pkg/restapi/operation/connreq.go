@@ -0,0 +1,204 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	connReqStoreName = "blinded-routing-conn-req"
+
+	connReqDIDKeyPrefix = "did_"
+	connReqMsgKeyPrefix = "msg_"
+
+	// connReqTagName tags the records, so that the expired ones can be swept.
+	connReqTagName = "connReq"
+
+	// DefaultConnReqReplayWindow is used when Config.ConnReqReplayWindow is not set.
+	DefaultConnReqReplayWindow = 10 * time.Minute
+)
+
+// connReqRecord is saved for every handled create-conn-req, so that retries of the same request
+// get the previously created router DID doc instead of a new one.
+type connReqRecord struct {
+	RouterDIDDoc json.RawMessage `json:"routerDIDDoc"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// connReqCache keeps track of create-conn-req messages handled within the replay window. The expired records are
+// deleted when read, and swept at most once per replay window when new records are saved.
+type connReqCache struct {
+	store  storage.Store
+	window time.Duration
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func newConnReqCache(provider storage.Provider, window time.Duration) (*connReqCache, error) {
+	store, err := provider.OpenStore(connReqStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("open store : %w", err)
+	}
+
+	if window <= 0 {
+		window = DefaultConnReqReplayWindow
+	}
+
+	return &connReqCache{store: store, window: window, lastSweep: time.Now()}, nil
+}
+
+// get returns the router DID doc created for an earlier request from the sender with either the same
+// counterparty DID or the same message ID. Returns nil if there is no such request within the replay window.
+func (c *connReqCache) get(sender, theirDID, msgID string) (json.RawMessage, error) {
+	for _, key := range connReqKeys(sender, theirDID, msgID) {
+		recBytes, err := c.store.Get(key)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get create-conn-req record : %w", err)
+		}
+
+		rec := &connReqRecord{}
+
+		err = json.Unmarshal(recBytes, rec)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal create-conn-req record : %w", err)
+		}
+
+		if time.Since(rec.CreatedAt) > c.window {
+			// the request is handled anyway, the record is swept later otherwise
+			if e := c.delete(key); e != nil {
+				logger.Warnf("%s", e)
+			}
+
+			continue
+		}
+
+		return rec.RouterDIDDoc, nil
+	}
+
+	return nil, nil
+}
+
+// put saves the router DID doc created for the request.
+func (c *connReqCache) put(sender, theirDID, msgID string, routerDIDDoc json.RawMessage) error {
+	recBytes, err := json.Marshal(&connReqRecord{
+		RouterDIDDoc: routerDIDDoc,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal create-conn-req record : %w", err)
+	}
+
+	for _, key := range connReqKeys(sender, theirDID, msgID) {
+		err = c.store.Put(key, recBytes, storage.Tag{Name: connReqTagName})
+		if err != nil {
+			return fmt.Errorf("save create-conn-req record : %w", err)
+		}
+	}
+
+	// the request is handled, the expired records are swept on a later request otherwise
+	if err = c.sweep(); err != nil {
+		logger.Warnf("sweep create-conn-req records : %s", err)
+	}
+
+	return nil
+}
+
+// sweep deletes the expired records, if the last sweep is older than the replay window.
+func (c *connReqCache) sweep() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastSweep) < c.window {
+		return nil
+	}
+
+	iter, err := c.store.Query(connReqTagName)
+	if err != nil {
+		return fmt.Errorf("query create-conn-req records : %w", err)
+	}
+
+	defer func() {
+		if e := iter.Close(); e != nil {
+			logger.Warnf("close create-conn-req records iterator : %s", e)
+		}
+	}()
+
+	var expired []string
+
+	for {
+		ok, e := iter.Next()
+		if e != nil {
+			return fmt.Errorf("next create-conn-req record : %w", e)
+		}
+
+		if !ok {
+			break
+		}
+
+		key, e := iter.Key()
+		if e != nil {
+			return fmt.Errorf("get create-conn-req record key : %w", e)
+		}
+
+		recBytes, e := iter.Value()
+		if e != nil {
+			return fmt.Errorf("get create-conn-req record : %w", e)
+		}
+
+		rec := &connReqRecord{}
+
+		// unreadable records are expired as well, they can't be used
+		if e = json.Unmarshal(recBytes, rec); e != nil || time.Since(rec.CreatedAt) > c.window {
+			expired = append(expired, key)
+		}
+	}
+
+	for _, key := range expired {
+		if e := c.delete(key); e != nil {
+			return e
+		}
+	}
+
+	c.lastSweep = time.Now()
+
+	return nil
+}
+
+func (c *connReqCache) delete(key string) error {
+	err := c.store.Delete(key)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("delete expired create-conn-req record : %w", err)
+	}
+
+	return nil
+}
+
+func connReqKeys(sender, theirDID, msgID string) []string {
+	var keys []string
+
+	if theirDID != "" {
+		keys = append(keys, connReqDIDKeyPrefix+sender+"_"+theirDID)
+	}
+
+	if msgID != "" {
+		keys = append(keys, connReqMsgKeyPrefix+sender+"_"+msgID)
+	}
+
+	return keys
+}
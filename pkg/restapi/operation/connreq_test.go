@@ -0,0 +1,156 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnReqCache(t *testing.T) {
+	routerDoc := json.RawMessage(`{"id":"did:peer:router"}`)
+
+	t.Run("match on counterparty DID or message ID", func(t *testing.T) {
+		c, err := newConnReqCache(mem.NewProvider(), 0)
+		require.NoError(t, err)
+		require.Equal(t, DefaultConnReqReplayWindow, c.window)
+
+		require.NoError(t, c.put("did:sender", "did:their", "msg-1", routerDoc))
+
+		doc, err := c.get("did:sender", "did:their", "msg-2")
+		require.NoError(t, err)
+		require.Equal(t, routerDoc, doc)
+
+		doc, err = c.get("did:sender", "did:other", "msg-1")
+		require.NoError(t, err)
+		require.Equal(t, routerDoc, doc)
+
+		doc, err = c.get("did:other-sender", "did:their", "msg-1")
+		require.NoError(t, err)
+		require.Nil(t, doc)
+	})
+
+	t.Run("outside replay window", func(t *testing.T) {
+		c, err := newConnReqCache(mem.NewProvider(), time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, c.put("did:sender", "did:their", "msg-1", routerDoc))
+
+		c.window = time.Nanosecond
+
+		time.Sleep(time.Millisecond)
+
+		doc, err := c.get("did:sender", "did:their", "msg-1")
+		require.NoError(t, err)
+		require.Nil(t, doc)
+
+		// the expired records are deleted when read
+		_, err = c.store.Get(connReqDIDKeyPrefix + "did:sender_did:their")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		_, err = c.store.Get(connReqMsgKeyPrefix + "did:sender_msg-1")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("expired records swept", func(t *testing.T) {
+		c, err := newConnReqCache(mem.NewProvider(), time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, c.put("did:sender", "did:their", "msg-1", routerDoc))
+		require.NoError(t, c.store.Put(connReqMsgKeyPrefix+"did:sender_msg-0", []byte("not json"),
+			storage.Tag{Name: connReqTagName}))
+
+		// the records of msg-1 are expired when msg-2 is saved
+		c.window = time.Millisecond
+		c.lastSweep = time.Now().Add(-time.Hour)
+
+		time.Sleep(2 * time.Millisecond)
+
+		require.NoError(t, c.put("did:sender", "", "msg-2", routerDoc))
+
+		records, err := c.store.Query(connReqTagName)
+		require.NoError(t, err)
+
+		var keys []string
+
+		for {
+			ok, e := records.Next()
+			require.NoError(t, e)
+
+			if !ok {
+				break
+			}
+
+			key, e := records.Key()
+			require.NoError(t, e)
+
+			keys = append(keys, key)
+		}
+
+		require.NoError(t, records.Close())
+		require.Equal(t, []string{connReqMsgKeyPrefix + "did:sender_msg-2"}, keys)
+	})
+
+	t.Run("no sweep within the replay window", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}, ErrQuery: errors.New("query error")}
+
+		c, err := newConnReqCache(mockstore.NewCustomMockStoreProvider(store), 0)
+		require.NoError(t, err)
+
+		require.NoError(t, c.put("did:sender", "did:their", "msg-1", routerDoc))
+		require.NoError(t, c.sweep())
+
+		// a failed sweep doesn't fail the request
+		c.lastSweep = time.Now().Add(-2 * DefaultConnReqReplayWindow)
+
+		require.NoError(t, c.put("did:sender", "did:their", "msg-2", routerDoc))
+		require.Error(t, c.sweep())
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		_, err := newConnReqCache(&mockstore.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")}, 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open store")
+
+		store := &mockstore.MockStore{
+			Store:  map[string]mockstore.DBEntry{},
+			ErrGet: errors.New("get error"),
+			ErrPut: errors.New("put error"),
+		}
+
+		c, err := newConnReqCache(mockstore.NewCustomMockStoreProvider(store), 0)
+		require.NoError(t, err)
+
+		_, err = c.get("did:sender", "did:their", "msg-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get create-conn-req record")
+
+		err = c.put("did:sender", "did:their", "msg-1", routerDoc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "save create-conn-req record")
+	})
+
+	t.Run("invalid record", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string]mockstore.DBEntry{
+			connReqMsgKeyPrefix + "did:sender_msg-1": {Value: []byte("not json")},
+		}}
+
+		c, err := newConnReqCache(mockstore.NewCustomMockStoreProvider(store), 0)
+		require.NoError(t, err)
+
+		_, err = c.get("did:sender", "", "msg-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal create-conn-req record")
+	})
+}
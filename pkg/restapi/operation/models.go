@@ -12,6 +12,9 @@ import (
 
 	"github.com/hyperledger/aries-framework-go/pkg/client/outofband"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofbandv2"
+	"github.com/hyperledger/aries-framework-go/pkg/store/connection"
+
+	"github.com/trustbloc/mediator/pkg/aries"
 )
 
 type healthCheckResp struct {
@@ -32,6 +35,13 @@ type DIDCommInvitationV2Req struct {
 // DIDCommInvitationV2Resp model.
 type DIDCommInvitationV2Resp struct {
 	Invitation *outofbandv2.Invitation `json:"invitation"`
+	// SignedInvitation is the invitation signed by the public DID, as a compact JWS. Only set if requested.
+	SignedInvitation string `json:"signedInvitation,omitempty"`
+}
+
+// VerifyInvitationV2Req model.
+type VerifyInvitationV2Req struct {
+	SignedInvitation string `json:"signedInvitation"`
 }
 
 // CreateConnReq model.
@@ -57,8 +67,9 @@ type CreateConnResp struct {
 
 // CreateConnRespData model for error data in CreateConnResp.
 type CreateConnRespData struct {
-	ErrorMsg string          `json:"errorMsg"`
-	DIDDoc   json.RawMessage `json:"didDoc"`
+	ErrorMsg    string          `json:"errorMsg"`
+	ProblemCode string          `json:"problemCode,omitempty"`
+	DIDDoc      json.RawMessage `json:"didDoc"`
 }
 
 // DIDCommMsg model.
@@ -66,3 +77,49 @@ type DIDCommMsg struct {
 	ID   string `json:"@id"`
 	Type string `json:"@type"`
 }
+
+// PublicDIDReq model for public DID update and recovery requests.
+type PublicDIDReq struct {
+	// ServiceEndpoint is the new DIDComm endpoint of the public DID. Defaults to the configured endpoint.
+	ServiceEndpoint string `json:"serviceEndpoint,omitempty"`
+	// RotateKeys replaces the router keys of the public DID on update. Recovery always replaces them.
+	RotateKeys bool `json:"rotateKeys,omitempty"`
+}
+
+// PublicDIDResp model.
+type PublicDIDResp struct {
+	DID         string          `json:"did"`
+	DIDDocument json.RawMessage `json:"didDocument,omitempty"`
+}
+
+// ReloadResp model.
+type ReloadResp struct {
+	Status string `json:"status"`
+}
+
+// ConnectionsResp model.
+type ConnectionsResp struct {
+	Connections []*connection.Record `json:"connections"`
+}
+
+// ConnectionResp model.
+type ConnectionResp struct {
+	Connection *connection.Record `json:"connection"`
+}
+
+// DeleteConnectionResp model.
+type DeleteConnectionResp struct {
+	ConnectionID string `json:"connectionID"`
+}
+
+// MediationsResp model.
+type MediationsResp struct {
+	Mediations []*aries.Mediation `json:"mediations"`
+}
+
+// RevokeMediationResp model.
+type RevokeMediationResp struct {
+	ConnectionID string `json:"connectionID"`
+	// RevokedKeys is the number of recipient keys the router stopped routing messages to.
+	RevokedKeys int `json:"revokedKeys"`
+}
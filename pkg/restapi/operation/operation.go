@@ -7,22 +7,27 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/hyperledger/aries-framework-go/pkg/client/didexchange"
 	"github.com/hyperledger/aries-framework-go/pkg/client/outofband"
 	"github.com/hyperledger/aries-framework-go/pkg/client/outofbandv2"
-	"github.com/hyperledger/aries-framework-go/pkg/common/model"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/common/service"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/messaging/msghandler"
 	didexdsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/didexchange"
 	mediatordsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/mediator"
+	outofbandv2svc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofbandv2"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/transport"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/kmsdidkey"
 	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/peer"
@@ -37,8 +42,15 @@ import (
 // API endpoints.
 const (
 	healthCheckPath  = "/healthcheck"
+	readinessPath    = "/readiness"
 	invitationPath   = "/didcomm/invitation"
 	invitationV2Path = "/didcomm/invitation-v2"
+	verifyInvV2Path  = "/didcomm/invitation-v2/verify"
+
+	// defaultInvitationLabel is the label of the invitations created without the label query parameter.
+	defaultInvitationLabel = "mediator"
+
+	didJSONContentType = "application/did+json"
 )
 
 // Msg svc constants.
@@ -73,6 +85,36 @@ type Config struct {
 	MsgRegistrar   *msghandler.Registrar
 	Storage        *Storage
 	PublicDID      string
+	// ConnReqReplayWindow is how long a repeated create-conn-req gets the previously created router DID doc.
+	ConnReqReplayWindow time.Duration
+	// MaxDIDDocSize is the size limit, in bytes, of DID docs sent in create-conn-req.
+	MaxDIDDocSize int
+	// DIDMethods are the DID methods accepted for DID docs sent in create-conn-req. Any method is accepted if empty.
+	DIDMethods []string
+	// PublicDIDDocPath is the path the doc of a did:web public DID is served from. Defaults to the path did:web
+	// resolvers use for the DID.
+	PublicDIDDocPath string
+	// PublicDIDWebDomain is the domain of the did:web public DID, if the public DID method is web. The doc route is
+	// then registered even if the public DID isn't created yet, and returns 404 until it is.
+	PublicDIDWebDomain string
+	// PublicDIDManager serves the public DID admin endpoints, if set.
+	PublicDIDManager PublicDIDManager
+	// AdminToken is the bearer token of the requests to the admin endpoints. The admin endpoints are disabled if
+	// empty.
+	AdminToken string
+	// PublicDIDPending is set if the public DID isn't resolvable yet: v2 invitations are unavailable and readiness
+	// reports not-ready until SetPublicDIDReady is called.
+	PublicDIDPending bool
+	// PublicDIDOptional is set if the router runs without the public DID until it's available, in which case
+	// readiness reports a degraded status instead of not-ready.
+	PublicDIDOptional bool
+	// Reloader serves the reload admin endpoint, if set.
+	Reloader Reloader
+	// Connections serves the connection and mediation admin endpoints, if set.
+	Connections ConnectionManager
+	// DIDCommEndpoints are the endpoints advertised in OOB v1 invitations and in the router peer DIDs created for
+	// create-conn-req. Defaults to the router endpoint of the aries framework.
+	DIDCommEndpoints []aries.DIDCommEndpoint
 }
 
 // Operation implements mediator operations.
@@ -86,9 +128,24 @@ type Operation struct {
 	vdriRegistry vdrapi.Registry
 	keyManager   kms.KeyManager
 	endpoint     string
+	endpoints    []aries.DIDCommEndpoint
 	publicDID    string
 	keyType      kms.KeyType
 	keyAgrType   kms.KeyType
+	connReqs     *connReqCache
+	docValidator *didDocValidator
+	ariesStore   storage.Provider
+	didDocPath   string
+	serveWebDoc  bool
+
+	publicDIDManager  PublicDIDManager
+	adminToken        string
+	signInvitation    func(inv *outofbandv2svc.Invitation) (string, error)
+	publicDIDMu       sync.RWMutex
+	publicDIDReady    bool
+	publicDIDOptional bool
+	reloader          Reloader
+	connections       ConnectionManager
 }
 
 // New returns a new Operation.
@@ -116,6 +173,11 @@ func New(config *Config) (*Operation, error) {
 		return nil, fmt.Errorf("didexchange client: %w", err)
 	}
 
+	connReqs, err := newConnReqCache(config.Storage.Transient, config.ConnReqReplayWindow)
+	if err != nil {
+		return nil, fmt.Errorf("create-conn-req cache: %w", err)
+	}
+
 	o := &Operation{
 		storage:      config.Storage,
 		oob:          oobClient,
@@ -125,13 +187,42 @@ func New(config *Config) (*Operation, error) {
 		messenger:    config.AriesMessenger,
 		vdriRegistry: config.Aries.VDRegistry(),
 		endpoint:     config.Aries.RouterEndpoint(),
+		endpoints:    config.DIDCommEndpoints,
 		keyManager:   config.Aries.KMS(),
 		publicDID:    config.PublicDID,
 		keyType:      config.Aries.KeyType(),
 		keyAgrType:   config.Aries.KeyAgreementType(),
+		connReqs:     connReqs,
+		docValidator: newDIDDocValidator(config.Aries.VDRegistry(), config.MaxDIDDocSize, config.DIDMethods),
+		ariesStore:   config.Aries.StorageProvider(),
+		didDocPath:   config.PublicDIDDocPath,
+
+		publicDIDManager:  config.PublicDIDManager,
+		adminToken:        config.AdminToken,
+		publicDIDOptional: config.PublicDIDOptional,
+		reloader:          config.Reloader,
+		connections:       config.Connections,
+		signInvitation: func(inv *outofbandv2svc.Invitation) (string, error) {
+			return aries.SignInvitation(config.Aries, inv)
+		},
 	}
 
-	msgCh := make(chan service.DIDCommMsg, 1)
+	if !config.PublicDIDPending {
+		o.SetPublicDIDReady()
+	}
+
+	webDID := o.publicDID
+	if config.PublicDIDWebDomain != "" {
+		webDID = aries.WebDID(config.PublicDIDWebDomain)
+	}
+
+	o.serveWebDoc = strings.HasPrefix(webDID, "did:web:")
+
+	if o.didDocPath == "" {
+		o.didDocPath = aries.WebDIDDocPath(webDID)
+	}
+
+	msgCh := make(chan aries.InboundMsg, 1)
 
 	msgSvc := aries.NewMsgSvc("create-connection", createConnReq, msgCh)
 
@@ -149,16 +240,59 @@ func New(config *Config) (*Operation, error) {
 	return o, nil
 }
 
-// GetRESTHandlers get all controller API handler available for this service.
+// GetRESTHandlers get the public controller API handlers available for this service, used by wallets and
+// monitoring. The admin handlers are returned by GetAdminRESTHandlers.
 func (o *Operation) GetRESTHandlers() []Handler {
-	return []Handler{
+	handlers := []Handler{
 		// healthcheck
 		support.NewHTTPHandler(healthCheckPath, http.MethodGet, o.healthCheckHandler),
+		support.NewHTTPHandler(readinessPath, http.MethodGet, o.readinessHandler),
 
 		// router
 		support.NewHTTPHandler(invitationPath, http.MethodGet, o.generateInvitation),
 		support.NewHTTPHandler(invitationV2Path, http.MethodGet, o.generateInvitationV2),
+		support.NewHTTPHandler(verifyInvV2Path, http.MethodPost, o.verifyInvitationV2),
+	}
+
+	if o.serveWebDoc {
+		// did:web public DID
+		handlers = append(handlers, support.NewHTTPHandler(o.didDocPath, http.MethodGet, o.publicDIDDoc))
+	}
+
+	return handlers
+}
+
+// GetAdminRESTHandlers get the admin controller API handlers available for this service, used by the operators of
+// the router. They're served only to the requests with the admin token.
+func (o *Operation) GetAdminRESTHandlers() []Handler {
+	var handlers []Handler
+
+	if o.publicDIDManager != nil {
+		// public DID admin
+		handlers = append(handlers,
+			o.adminHandler(publicDIDPath, http.MethodGet, o.getPublicDID),
+			o.adminHandler(publicDIDUpdatePath, http.MethodPost, o.updatePublicDID),
+			o.adminHandler(publicDIDRecoverPath, http.MethodPost, o.recoverPublicDID),
+			o.adminHandler(publicDIDDeactivatePath, http.MethodPost, o.deactivatePublicDID),
+		)
+	}
+
+	if o.reloader != nil {
+		handlers = append(handlers, o.adminHandler(reloadPath, http.MethodPost, o.reload))
 	}
+
+	if o.connections != nil {
+		// connection and mediation admin
+		handlers = append(handlers,
+			o.adminHandler(connectionsPath, http.MethodGet, o.listConnections),
+			o.adminHandler(connectionPath, http.MethodGet, o.getConnection),
+			o.adminHandler(connectionPath, http.MethodDelete, o.deleteConnection),
+			o.adminHandler(mediationPath, http.MethodGet, o.listMediations),
+			o.adminHandler(revokeMediationPath, http.MethodPost, o.revokeMediation),
+		)
+	}
+
+	return handlers
 }
 
 func (o *Operation) healthCheckHandler(rw http.ResponseWriter, _ *http.Request) {
@@ -170,12 +304,66 @@ func (o *Operation) healthCheckHandler(rw http.ResponseWriter, _ *http.Request)
 	httputil.WriteResponseWithLog(rw, resp, healthCheckPath, logger)
 }
 
-func (o *Operation) generateInvitation(rw http.ResponseWriter, _ *http.Request) {
-	// TODO configure mediator label
-	invitation, err := o.oob.CreateInvitation(nil, outofband.WithLabel("mediator"),
-		outofband.WithAccept(
-			transport.MediaTypeAIP2RFC0019Profile,
-			transport.MediaTypeProfileDIDCommAIP1))
+// SetPublicDID sets the public DID, when created after the router started. v2 invitations are enabled once
+// SetPublicDIDReady is called.
+func (o *Operation) SetPublicDID(didID string) {
+	o.publicDIDMu.Lock()
+	defer o.publicDIDMu.Unlock()
+
+	o.publicDID = didID
+}
+
+// SetPublicDIDReady marks the public DID as resolvable, enabling v2 invitations.
+func (o *Operation) SetPublicDIDReady() {
+	o.publicDIDMu.Lock()
+	defer o.publicDIDMu.Unlock()
+
+	o.publicDIDReady = true
+}
+
+// readyPublicDID returns the public DID, and whether it's resolvable.
+func (o *Operation) readyPublicDID() (string, bool) {
+	o.publicDIDMu.RLock()
+	defer o.publicDIDMu.RUnlock()
+
+	return o.publicDID, o.publicDIDReady
+}
+
+func (o *Operation) readinessHandler(rw http.ResponseWriter, _ *http.Request) {
+	status := "ready"
+
+	if _, ready := o.readyPublicDID(); !ready {
+		if !o.publicDIDOptional {
+			httputil.WriteErrorResponseWithLog(rw, http.StatusServiceUnavailable,
+				"public DID not resolvable yet", readinessPath, logger)
+
+			return
+		}
+
+		// DIDComm v1 is served without the public DID
+		status = "degraded"
+	}
+
+	httputil.WriteResponseWithLog(rw, &healthCheckResp{
+		Status:      status,
+		CurrentTime: time.Now(),
+	}, readinessPath, logger)
+}
+
+// generateInvitation creates an OOB v1 invitation. The label query parameter sets the label of the invitation.
+func (o *Operation) generateInvitation(rw http.ResponseWriter, req *http.Request) {
+	accept := []string{transport.MediaTypeAIP2RFC0019Profile, transport.MediaTypeProfileDIDCommAIP1}
+
+	services, err := o.invitationServices(accept)
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError,
+			fmt.Sprintf("failed to create router invitation - err=%s", err.Error()), invitationPath, logger)
+
+		return
+	}
+
+	invitation, err := o.oob.CreateInvitation(services, outofband.WithLabel(invitationLabel(req)),
+		outofband.WithAccept(accept...))
 	if err != nil {
 		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError,
 			fmt.Sprintf("failed to create router invitation - err=%s", err.Error()), invitationPath, logger)
@@ -188,11 +376,21 @@ func (o *Operation) generateInvitation(rw http.ResponseWriter, _ *http.Request)
 	}, invitationPath, logger)
 }
 
-func (o *Operation) generateInvitationV2(rw http.ResponseWriter, _ *http.Request) {
-	// TODO configure mediator label
+// generateInvitationV2 creates an OOB v2 invitation from the public DID. With the signed=true query parameter, the
+// response also has the invitation signed by the public DID. The label query parameter sets the label of the
+// invitation.
+func (o *Operation) generateInvitationV2(rw http.ResponseWriter, req *http.Request) {
+	publicDID, ready := o.readyPublicDID()
+	if !ready {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusServiceUnavailable,
+			"public DID not resolvable yet", invitationV2Path, logger)
+
+		return
+	}
+
 	invitation, err := o.oobv2.CreateInvitation(
-		outofbandv2.WithFrom(o.publicDID),
-		outofbandv2.WithLabel("mediator"),
+		outofbandv2.WithFrom(publicDID),
+		outofbandv2.WithLabel(invitationLabel(req)),
 		outofbandv2.WithAccept(
 			transport.MediaTypeDIDCommV2Profile,
 			transport.MediaTypeAIP2RFC0587Profile,
@@ -205,9 +403,86 @@ func (o *Operation) generateInvitationV2(rw http.ResponseWriter, _ *http.Request
 		return
 	}
 
+	resp := &DIDCommInvitationV2Resp{
+		Invitation: invitation,
+	}
+
+	if signed, _ := strconv.ParseBool(req.URL.Query().Get("signed")); signed { // nolint:errcheck // false if invalid
+		resp.SignedInvitation, err = o.signInvitation(invitation)
+		if err != nil {
+			httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError,
+				fmt.Sprintf("error signing invitation: %s", err), invitationV2Path, logger)
+
+			return
+		}
+	}
+
+	httputil.WriteResponseWithLog(rw, resp, invitationV2Path, logger)
+}
+
+func invitationLabel(req *http.Request) string {
+	if label := req.URL.Query().Get("label"); label != "" {
+		return label
+	}
+
+	return defaultInvitationLabel
+}
+
+// verifyInvitationV2 verifies an invitation signed by a public DID.
+func (o *Operation) verifyInvitationV2(rw http.ResponseWriter, req *http.Request) {
+	verifyReq := &VerifyInvitationV2Req{}
+
+	err := json.NewDecoder(req.Body).Decode(verifyReq)
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusBadRequest,
+			fmt.Sprintf("invalid request : %s", err), verifyInvV2Path, logger)
+
+		return
+	}
+
+	invitation, err := aries.VerifyInvitation(o.vdriRegistry, verifyReq.SignedInvitation)
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusBadRequest, err.Error(), verifyInvV2Path, logger)
+
+		return
+	}
+
 	httputil.WriteResponseWithLog(rw, &DIDCommInvitationV2Resp{
 		Invitation: invitation,
-	}, invitationV2Path, logger)
+	}, verifyInvV2Path, logger)
+}
+
+// publicDIDDoc serves the doc of the did:web public DID. The doc is read from the store on each request, so that
+// updates made at startup, by this or another router instance, are served right away.
+func (o *Operation) publicDIDDoc(rw http.ResponseWriter, _ *http.Request) {
+	publicDID, _ := o.readyPublicDID()
+
+	// the stored doc is of another method until the did:web public DID is created
+	if !strings.HasPrefix(publicDID, "did:web:") {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusNotFound, "public DID doc not created yet", o.didDocPath,
+			logger)
+
+		return
+	}
+
+	docBytes, err := aries.GetPublicDIDDoc(o.ariesStore)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusNotFound, "public DID doc not created yet", o.didDocPath,
+			logger)
+
+		return
+	}
+
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError,
+			"error getting public DID doc", o.didDocPath, logger)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", didJSONContentType)
+
+	httputil.WriteResponseWithLog(rw, json.RawMessage(docBytes), o.didDocPath, logger)
 }
 
 func (o *Operation) didCommActionListener(ch <-chan service.DIDCommAction) {
@@ -237,7 +512,7 @@ func (o *Operation) didCommActionListener(ch <-chan service.DIDCommAction) {
 	}
 }
 
-func (o *Operation) didCommMsgListener(ch <-chan service.DIDCommMsg) {
+func (o *Operation) didCommMsgListener(ch <-chan aries.InboundMsg) {
 	for msg := range ch {
 		var err error
 
@@ -251,10 +526,17 @@ func (o *Operation) didCommMsgListener(ch <-chan service.DIDCommMsg) {
 		}
 
 		if err != nil {
+			data := &CreateConnRespData{ErrorMsg: err.Error()}
+
+			var problem *connReqProblem
+			if errors.As(err, &problem) {
+				data.ProblemCode = problem.code
+			}
+
 			msgMap = service.NewDIDCommMsgMap(&CreateConnResp{
 				ID:   uuid.New().String(),
 				Type: createConnResp,
-				Data: &CreateConnRespData{ErrorMsg: err.Error()},
+				Data: data,
 			})
 
 			logger.Errorf("msgType=[%s] id=[%s] errMsg=[%s]", msg.Type(), msg.ID(), err.Error())
@@ -271,7 +553,7 @@ func (o *Operation) didCommMsgListener(ch <-chan service.DIDCommMsg) {
 	}
 }
 
-func (o *Operation) handleCreateConnReq(msg service.DIDCommMsg) (service.DIDCommMsgMap, error) { // nolint:funlen,lll,gocyclo // ignore
+func (o *Operation) handleCreateConnReq(msg aries.InboundMsg) (service.DIDCommMsgMap, error) { // nolint:funlen,lll,gocyclo // ignore
 	pMsg := CreateConnReq{}
 
 	err := msg.Decode(&pMsg)
@@ -280,51 +562,67 @@ func (o *Operation) handleCreateConnReq(msg service.DIDCommMsg) (service.DIDComm
 	}
 
 	// get the peerDID from the request
-	if pMsg.Data == nil || pMsg.Data.DIDDoc == nil || len(pMsg.Data.DIDDoc) == 0 {
-		return nil, errors.New("did document mandatory")
+	if pMsg.Data == nil {
+		return nil, newConnReqProblem(ProblemDIDDocMissing, "did document mandatory")
+	}
+
+	didDoc, didCommSvc, err := o.docValidator.validate(pMsg.Data.DIDDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	// the replay cache is keyed by the sender, requests without a connection can't be told apart
+	if msg.TheirDID == "" {
+		return nil, newConnReqProblem(ProblemConnectionMissing, "create-conn-req must be sent over a DIDComm connection")
 	}
 
-	didDoc, err := did.ParseDocument(pMsg.Data.DIDDoc)
+	// a retried request (ex: the response was lost) gets the router did doc created the first time
+	routerDocBytes, err := o.connReqs.get(msg.TheirDID, didDoc.ID, msg.ID())
 	if err != nil {
-		return nil, fmt.Errorf("parse did doc : %w", err)
+		return nil, fmt.Errorf("check for repeated request : %w", err)
 	}
 
-	// TODO - key type should be configurable
-	keyID, pubKeyBytes, err := o.keyManager.CreateAndExportPubKeyBytes(kms.ED25519Type)
+	if routerDocBytes != nil {
+		logger.Infof("repeated create-conn-req : id=[%s] theirDID=[%s]", msg.ID(), didDoc.ID)
+
+		return newCreateConnResp(routerDocBytes), nil
+	}
+
+	keyTypes, err := o.routerKeyTypes(didDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	ver, err := aries.CreateVerification(o.keyManager, "#key-1", keyTypes.verification,
+		did.VerificationRelationshipGeneral)
 	if err != nil {
-		return nil, fmt.Errorf("kms failed to create key: %w", err)
+		return nil, fmt.Errorf("creating verification VM: %w", err)
 	}
 
-	auth, err := aries.CreateVerification(o.keyManager, "#key-2", o.keyType, did.Authentication)
+	auth, err := aries.CreateVerification(o.keyManager, "#key-2", keyTypes.authentication, did.Authentication)
 	if err != nil {
 		return nil, fmt.Errorf("creating authentication VM: %w", err)
 	}
 
-	kagr, err := aries.CreateVerification(o.keyManager, "#key-3", o.keyAgrType, did.KeyAgreement)
+	kagr, err := aries.CreateVerification(o.keyManager, "#key-3", keyTypes.keyAgreement, did.KeyAgreement)
 	if err != nil {
 		return nil, fmt.Errorf("creating keyagreement VM: %w", err)
 	}
 
-	svc := did.Service{Type: vdrapi.DIDCommServiceType, ServiceEndpoint: model.NewDIDCommV1Endpoint(o.endpoint)}
+	services := aries.DIDCommV1Services(o.routerEndpoints(), nil, nil)
 
-	if didDoc.Service[0].Type == didCommV2ServiceType {
-		svc = did.Service{Type: vdrapi.DIDCommV2ServiceType,
-			ServiceEndpoint: model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{{URI: o.endpoint}})}
+	if didCommSvc.Type == didCommV2ServiceType {
+		services = aries.DIDCommV2Services(o.routerEndpoints())
 	}
 
 	// create peer DID
 	docResolution, err := o.vdriRegistry.Create(
 		peer.DIDMethod,
 		&did.Doc{
-			Service: []did.Service{svc},
-			VerificationMethod: []did.VerificationMethod{*did.NewVerificationMethodFromBytes(
-				"#"+keyID,
-				"Ed25519VerificationKey2018",
-				"",
-				pubKeyBytes,
-			)},
-			Authentication: []did.Verification{*auth},
-			KeyAgreement:   []did.Verification{*kagr},
+			Service:            services,
+			VerificationMethod: []did.VerificationMethod{ver.VerificationMethod},
+			Authentication:     []did.Verification{*auth},
+			KeyAgreement:       []did.Verification{*kagr},
 		},
 		vdrapi.WithOption(peer.DefaultServiceType, vdrapi.DIDCommServiceType),
 	)
@@ -345,12 +643,135 @@ func (o *Operation) handleCreateConnReq(msg service.DIDCommMsg) (service.DIDComm
 
 	logger.Debugf("created PEER DID: %s", newDocBytes)
 
+	err = o.connReqs.put(msg.TheirDID, didDoc.ID, msg.ID(), newDocBytes)
+	if err != nil {
+		return nil, fmt.Errorf("save create-conn-req : %w", err)
+	}
+
 	// send router did doc
+	return newCreateConnResp(newDocBytes), nil
+}
+
+// routerEndpoints returns the DIDComm endpoints of the router peer DIDs.
+func (o *Operation) routerEndpoints() []aries.DIDCommEndpoint {
+	if len(o.endpoints) == 0 {
+		return []aries.DIDCommEndpoint{{URI: o.endpoint}}
+	}
+
+	return o.endpoints
+}
+
+// invitationServices returns the services of an OOB v1 invitation: a service per configured DIDComm endpoint, with a
+// new recipient key. Without configured endpoints, the aries framework creates the service of its router endpoint.
+func (o *Operation) invitationServices(accept []string) ([]interface{}, error) {
+	if len(o.endpoints) == 0 {
+		return nil, nil
+	}
+
+	keyType := o.keyType
+	if keyType == "" {
+		keyType = kms.ED25519Type
+	}
+
+	_, pubKey, err := o.keyManager.CreateAndExportPubKeyBytes(keyType)
+	if err != nil {
+		return nil, fmt.Errorf("create recipient key : %w", err)
+	}
+
+	didKey, err := kmsdidkey.BuildDIDKeyByKeyType(pubKey, keyType)
+	if err != nil {
+		return nil, fmt.Errorf("create recipient key : %w", err)
+	}
+
+	didCommServices := aries.DIDCommV1Services(o.endpoints, []string{didKey}, accept)
+
+	// the aries framework validates and serializes pointers to the services
+	services := make([]interface{}, len(didCommServices))
+
+	for i := range didCommServices {
+		services[i] = &didCommServices[i]
+	}
+
+	return services, nil
+}
+
+type routerKeyTypes struct {
+	verification   kms.KeyType
+	authentication kms.KeyType
+	keyAgreement   kms.KeyType
+}
+
+// routerKeyTypes returns the key types of the router peer DID created for the requester, so that they match the key
+// types in the requester DID doc. The configured key types are used where the requester DID doc has no such keys.
+// Fails if the requester DID doc has keys of a relationship, but none of a type the router supports.
+func (o *Operation) routerKeyTypes(theirDoc *did.Doc) (*routerKeyTypes, error) {
+	auth, err := firstSupportedKeyType(theirDoc.Authentication, did.Authentication, o.keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	keyTypes := &routerKeyTypes{authentication: auth, verification: auth}
+
+	if len(theirDoc.VerificationMethod) > 0 {
+		vms := make([]did.Verification, len(theirDoc.VerificationMethod))
+		for i := range theirDoc.VerificationMethod {
+			vms[i] = did.Verification{VerificationMethod: theirDoc.VerificationMethod[i]}
+		}
+
+		keyTypes.verification, err = firstSupportedKeyType(vms, did.VerificationRelationshipGeneral, auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keyTypes.keyAgreement, err = firstSupportedKeyType(theirDoc.KeyAgreement, did.KeyAgreement, o.keyAgrType)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyTypes, nil
+}
+
+// firstSupportedKeyType returns the key type of the first verification method the router supports. Key agreement
+// keys are skipped for the other relationships, since verification methods list all the keys. Returns the default
+// key type if there's no other verification method, and a problem if none of them is supported.
+func firstSupportedKeyType(vms []did.Verification, rel did.VerificationRelationship,
+	defaultKeyType kms.KeyType) (kms.KeyType, error) {
+	var errs []string
+
+	for i := range vms {
+		vm := &vms[i].VerificationMethod
+
+		kt, err := aries.KeyTypeFor(vm, rel)
+		if err == nil {
+			return kt, nil
+		}
+
+		if rel != did.KeyAgreement {
+			if _, e := aries.KeyTypeFor(vm, did.KeyAgreement); e == nil {
+				logger.Debugf("skipping key agreement key '%s' of requester DID doc", vm.ID)
+
+				continue
+			}
+		}
+
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return "", newConnReqProblem(ProblemKeyTypeNotSupported, "no supported key type in did document : [%s]",
+			strings.Join(errs, "; "))
+	}
+
+	return defaultKeyType, nil
+}
+
+func newCreateConnResp(routerDIDDoc []byte) service.DIDCommMsgMap {
 	return service.NewDIDCommMsgMap(&CreateConnResp{
 		ID:   uuid.New().String(),
 		Type: createConnResp,
-		Data: &CreateConnRespData{DIDDoc: newDocBytes},
-	}), nil
+		Data: &CreateConnRespData{DIDDoc: routerDIDDoc},
+	})
 }
 
 func (o *Operation) stateMsgHandler(stateMsgCh chan service.StateMsg) {
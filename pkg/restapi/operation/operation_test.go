@@ -8,10 +8,14 @@ package operation
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +26,9 @@ import (
 	outofbandsvc "github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofband"
 	"github.com/hyperledger/aries-framework-go/pkg/didcomm/protocol/outofbandv2"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	mocksvc "github.com/hyperledger/aries-framework-go/pkg/mock/didcomm/protocol/didexchange"
 	mockroute "github.com/hyperledger/aries-framework-go/pkg/mock/didcomm/protocol/mediator"
 	mockdiddoc "github.com/hyperledger/aries-framework-go/pkg/mock/diddoc"
@@ -31,6 +38,7 @@ import (
 	mockvdri "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
 	"github.com/stretchr/testify/require"
 
+	"github.com/trustbloc/mediator/pkg/aries"
 	"github.com/trustbloc/mediator/pkg/internal/mock/didexchange"
 	"github.com/trustbloc/mediator/pkg/internal/mock/messenger"
 	mockoutofband "github.com/trustbloc/mediator/pkg/internal/mock/outofband"
@@ -42,7 +50,18 @@ func TestNew(t *testing.T) {
 		o, err := New(config())
 		require.NoError(t, err)
 
-		require.Len(t, o.GetRESTHandlers(), 3)
+		require.Len(t, o.GetRESTHandlers(), 5)
+		require.Empty(t, o.GetAdminRESTHandlers())
+	})
+
+	t.Run("create-conn-req store error", func(t *testing.T) {
+		config := config()
+		config.Storage.Transient = &mockstore.MockStoreProvider{ErrOpenStoreHandle: errors.New("open error")}
+
+		o, err := New(config)
+		require.Nil(t, o)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create-conn-req cache")
 	})
 
 	t.Run("aries store error", func(t *testing.T) {
@@ -117,13 +136,58 @@ func TestOperation_HealthCheck(t *testing.T) {
 	})
 }
 
+func TestOperation_Readiness(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		o, err := New(config())
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.readinessHandler(w, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "ready")
+	})
+
+	t.Run("not ready until the public DID resolves", func(t *testing.T) {
+		config := config()
+		config.PublicDIDPending = true
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.readinessHandler(w, nil)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.Contains(t, w.Body.String(), "public DID not resolvable yet")
+
+		o.SetPublicDIDReady()
+
+		w = httptest.NewRecorder()
+		o.readinessHandler(w, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("degraded without the public DID", func(t *testing.T) {
+		config := config()
+		config.PublicDIDPending = true
+		config.PublicDIDOptional = true
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.readinessHandler(w, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "degraded")
+	})
+}
+
 func TestGenerateInvitationHandler(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		o, err := New(config())
 		require.NoError(t, err)
 
 		w := httptest.NewRecorder()
-		o.generateInvitation(w, nil)
+		o.generateInvitation(w, httptest.NewRequest(http.MethodGet, invitationPath, nil))
 		require.Equal(t, http.StatusOK, w.Code)
 
 		var result *DIDCommInvitationResp
@@ -135,6 +199,56 @@ func TestGenerateInvitationHandler(t *testing.T) {
 		require.Equal(t, result.Invitation.Type, "https://didcomm.org/out-of-band/1.0/invitation")
 	})
 
+	t.Run("label", func(t *testing.T) {
+		o, err := New(config())
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.generateInvitation(w, httptest.NewRequest(http.MethodGet, invitationPath+"?label=Example+Mediator", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result *DIDCommInvitationResp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Equal(t, "Example Mediator", result.Invitation.Label)
+	})
+
+	t.Run("a service per DIDComm endpoint", func(t *testing.T) {
+		cfg := config()
+		cfg.DIDCommEndpoints = []aries.DIDCommEndpoint{
+			{URI: "https://example.com", Priority: 1},
+			{URI: "wss://example.com", Accept: []string{"didcomm/aip1"}},
+		}
+
+		o, err := New(cfg)
+		require.NoError(t, err)
+
+		o.oob = &mockoutofband.MockClient{}
+
+		w := httptest.NewRecorder()
+		o.generateInvitation(w, httptest.NewRequest(http.MethodGet, invitationPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result struct {
+			Invitation struct {
+				Services []did.Service `json:"services"`
+			} `json:"invitation"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+
+		services := result.Invitation.Services
+		require.Len(t, services, 2)
+		require.Equal(t, uint(0), services[0].Priority)
+		require.Equal(t, []string{"didcomm/aip1"}, services[0].Accept)
+		require.Equal(t, uint(1), services[1].Priority)
+		require.Equal(t, []string{"didcomm/aip2;env=rfc19", "didcomm/aip1"}, services[1].Accept)
+		require.Len(t, services[1].RecipientKeys, 1)
+		require.Equal(t, services[0].RecipientKeys, services[1].RecipientKeys)
+
+		uri, err := services[1].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com", uri)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		o, err := New(config())
 		require.NoError(t, err)
@@ -142,7 +256,7 @@ func TestGenerateInvitationHandler(t *testing.T) {
 		o.oob = &mockoutofband.MockClient{CreateInvitationErr: errors.New("invitation error")}
 
 		w := httptest.NewRecorder()
-		o.generateInvitation(w, nil)
+		o.generateInvitation(w, httptest.NewRequest(http.MethodGet, invitationPath, nil))
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 		require.Contains(t, w.Body.String(), "failed to create router invitation")
 	})
@@ -175,6 +289,88 @@ func TestGenerateInvitationV2Handler(t *testing.T) {
 		require.Equal(t, result.Invitation.Type, "https://didcomm.org/out-of-band/2.0/invitation")
 	})
 
+	t.Run("label", func(t *testing.T) {
+		o, err := New(config())
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.generateInvitationV2(w, httptest.NewRequest(http.MethodGet, invitationV2Path+"?label=Example+Mediator", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result *DIDCommInvitationV2Resp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Equal(t, "Example Mediator", result.Invitation.Label)
+	})
+
+	t.Run("public DID not resolvable yet", func(t *testing.T) {
+		config := config()
+		config.PublicDIDPending = true
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, invitationV2Path, bytes.NewReader(reqBytes))
+
+		w := httptest.NewRecorder()
+		o.generateInvitationV2(w, req)
+		require.Equal(t, http.StatusServiceUnavailable, w.Code)
+		require.Contains(t, w.Body.String(), "public DID not resolvable yet")
+
+		o.SetPublicDIDReady()
+
+		w = httptest.NewRecorder()
+		o.generateInvitationV2(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		o, err := New(config())
+		require.NoError(t, err)
+
+		o.signInvitation = func(inv *outofbandv2.Invitation) (string, error) {
+			return "signed-" + inv.ID, nil
+		}
+
+		w := httptest.NewRecorder()
+		o.generateInvitationV2(w, httptest.NewRequest(http.MethodGet, invitationV2Path+"?signed=true", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result *DIDCommInvitationV2Resp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Equal(t, "signed-"+result.Invitation.ID, result.SignedInvitation)
+	})
+
+	t.Run("sign error", func(t *testing.T) {
+		o, err := New(config())
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.generateInvitationV2(w, httptest.NewRequest(http.MethodGet, invitationV2Path+"?signed=true", nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "error signing invitation")
+	})
+
+	t.Run("public DID created after startup", func(t *testing.T) {
+		config := config()
+		config.PublicDID = ""
+		config.PublicDIDPending = true
+		config.PublicDIDOptional = true
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		o.SetPublicDID("did:orb:foo")
+		o.SetPublicDIDReady()
+
+		w := httptest.NewRecorder()
+		o.generateInvitationV2(w, httptest.NewRequest(http.MethodGet, invitationV2Path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result *DIDCommInvitationV2Resp
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Equal(t, "did:orb:foo", result.Invitation.From)
+	})
+
 	t.Run("create error", func(t *testing.T) {
 		o, err := New(config())
 		require.NoError(t, err)
@@ -190,6 +386,145 @@ func TestGenerateInvitationV2Handler(t *testing.T) {
 	})
 }
 
+func TestVerifyInvitationV2Handler(t *testing.T) {
+	o, err := New(config())
+	require.NoError(t, err)
+
+	t.Run("invalid request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		o.verifyInvitationV2(w, httptest.NewRequest(http.MethodPost, verifyInvV2Path, strings.NewReader("{")))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "invalid request")
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&VerifyInvitationV2Req{SignedInvitation: "eyJhbGciOiJFZERTQSJ9.e30.c2ln"})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.verifyInvitationV2(w, httptest.NewRequest(http.MethodPost, verifyInvV2Path, bytes.NewReader(reqBytes)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "verify signed invitation")
+	})
+}
+
+func TestPublicDIDDocHandler(t *testing.T) {
+	t.Run("not served for other DID methods", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:orb:foo"
+
+		o, err := New(config)
+		require.NoError(t, err)
+		require.Len(t, o.GetRESTHandlers(), 5)
+	})
+
+	t.Run("served at the did:web path", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:web:example.com:mediator"
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		handlers := o.GetRESTHandlers()
+		require.Len(t, handlers, 6)
+		require.Equal(t, "/mediator/did.json", handlers[5].Path())
+	})
+
+	t.Run("served before the did:web public DID is created", func(t *testing.T) {
+		config := config()
+		config.PublicDID = ""
+		config.PublicDIDWebDomain = "example.com:8443/mediator"
+		config.PublicDIDPending = true
+
+		store, err := config.Aries.StorageProvider().OpenStore("router-invitation-did")
+		require.NoError(t, err)
+		// doc of the public DID of the previous method
+		require.NoError(t, store.Put("did-doc", []byte(`{"id":"did:key:z6Mk"}`)))
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		handlers := o.GetRESTHandlers()
+		require.Len(t, handlers, 6)
+		require.Equal(t, "/mediator/did.json", handlers[5].Path())
+
+		w := httptest.NewRecorder()
+		handlers[5].Handle()(w, httptest.NewRequest(http.MethodGet, "/mediator/did.json", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Contains(t, w.Body.String(), "public DID doc not created yet")
+
+		require.NoError(t, store.Put("did-doc", []byte(`{"id":"did:web:example.com%3A8443:mediator"}`)))
+		o.SetPublicDID("did:web:example.com%3A8443:mediator")
+
+		w = httptest.NewRecorder()
+		handlers[5].Handle()(w, httptest.NewRequest(http.MethodGet, "/mediator/did.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"id":"did:web:example.com%3A8443:mediator"}`, w.Body.String())
+	})
+
+	t.Run("served at the configured path", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:web:example.com"
+		config.PublicDIDDocPath = "/did.json"
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		handlers := o.GetRESTHandlers()
+		require.Len(t, handlers, 6)
+		require.Equal(t, "/did.json", handlers[5].Path())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:web:example.com"
+
+		store, err := config.Aries.StorageProvider().OpenStore("router-invitation-did")
+		require.NoError(t, err)
+		require.NoError(t, store.Put("did-doc", []byte(`{"id":"did:web:example.com"}`)))
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.publicDIDDoc(w, httptest.NewRequest(http.MethodGet, aries.DefaultWebDIDDocPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/did+json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"id":"did:web:example.com"}`, w.Body.String())
+	})
+
+	t.Run("missing doc", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:web:example.com"
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.publicDIDDoc(w, httptest.NewRequest(http.MethodGet, aries.DefaultWebDIDDocPath, nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Contains(t, w.Body.String(), "public DID doc not created yet")
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		config := config()
+		config.PublicDID = "did:web:example.com"
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		o.ariesStore = mockstore.NewCustomMockStoreProvider(&mockstore.MockStore{
+			Store:  map[string]mockstore.DBEntry{},
+			ErrGet: errors.New("get error"),
+		})
+
+		w := httptest.NewRecorder()
+		o.publicDIDDoc(w, httptest.NewRequest(http.MethodGet, aries.DefaultWebDIDDocPath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "error getting public DID doc")
+	})
+}
+
 func TestDIDCommListener(t *testing.T) {
 	c, err := New(config())
 	require.NoError(t, err)
@@ -414,12 +749,12 @@ func TestDIDCommMsgListener(t *testing.T) {
 			},
 		}
 
-		msgCh := make(chan service.DIDCommMsg, 1)
+		msgCh := make(chan aries.InboundMsg, 1)
 		go c.didCommMsgListener(msgCh)
 
-		msgCh <- service.NewDIDCommMsgMap(struct {
+		msgCh <- aries.InboundMsg{DIDCommMsg: service.NewDIDCommMsgMap(struct {
 			Type string `json:"@type,omitempty"`
-		}{Type: "unsupported-message-type"})
+		}{Type: "unsupported-message-type"})}
 
 		select {
 		case <-done:
@@ -438,12 +773,51 @@ func TestDIDCommMsgListener(t *testing.T) {
 			},
 		}
 
-		msgCh := make(chan service.DIDCommMsg, 1)
+		msgCh := make(chan aries.InboundMsg, 1)
 		go c.didCommMsgListener(msgCh)
 
-		msgCh <- service.NewDIDCommMsgMap(struct {
+		msgCh <- aries.InboundMsg{DIDCommMsg: service.NewDIDCommMsgMap(struct {
 			Type string `json:"@type,omitempty"`
-		}{Type: "unsupported-message-type"})
+		}{Type: "unsupported-message-type"})}
+	})
+
+	t.Run("create connection request with invalid did doc", func(t *testing.T) {
+		c, err := New(config())
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+
+		c.messenger = &messenger.MockMessenger{
+			ReplyToFunc: func(msgID string, msg service.DIDCommMsgMap, _ ...service.Opt) error {
+				pMsg := &CreateConnResp{}
+				dErr := msg.Decode(pMsg)
+				require.NoError(t, dErr)
+
+				require.Contains(t, pMsg.Data.ErrorMsg, "parse did doc")
+				require.Equal(t, ProblemDIDDocInvalid, pMsg.Data.ProblemCode)
+
+				done <- struct{}{}
+
+				return nil
+			},
+		}
+
+		msgCh := make(chan aries.InboundMsg, 1)
+		go c.didCommMsgListener(msgCh)
+
+		msgCh <- aries.InboundMsg{DIDCommMsg: service.NewDIDCommMsgMap(CreateConnReq{
+			ID:   uuid.New().String(),
+			Type: createConnReq,
+			Data: &CreateConnReqData{
+				DIDDoc: []byte("invalid-diddoc"),
+			},
+		})}
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "tests are not validated due to timeout")
+		}
 	})
 
 	t.Run("create connection request", func(t *testing.T) {
@@ -473,19 +847,19 @@ func TestDIDCommMsgListener(t *testing.T) {
 			},
 		}
 
-		msgCh := make(chan service.DIDCommMsg, 1)
+		msgCh := make(chan aries.InboundMsg, 1)
 		go c.didCommMsgListener(msgCh)
 
 		didDocBytes, err := mockdiddoc.GetMockDIDDoc(t, false).JSONBytes()
 		require.NoError(t, err)
 
-		msgCh <- service.NewDIDCommMsgMap(CreateConnReq{
+		msgCh <- aries.InboundMsg{DIDCommMsg: service.NewDIDCommMsgMap(CreateConnReq{
 			ID:   uuid.New().String(),
 			Type: createConnReq,
 			Data: &CreateConnReqData{
 				DIDDoc: didDocBytes,
 			},
-		})
+		}), TheirDID: "did:example:wallet"}
 
 		select {
 		case <-done:
@@ -506,7 +880,7 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			Data: &CreateConnReqData{},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Contains(t, err.Error(), "did document mandatory")
 	})
 
@@ -522,7 +896,7 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Contains(t, err.Error(), "parse did doc")
 	})
 
@@ -545,7 +919,7 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "creating authentication VM")
 	})
@@ -569,7 +943,7 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "creating keyagreement VM")
 	})
@@ -593,7 +967,7 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Contains(t, err.Error(), "create new peer did")
 	})
 
@@ -616,11 +990,114 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "create connection")
 	})
 
+	t.Run("repeated request returns the same router did doc", func(t *testing.T) {
+		c, err := New(config())
+		require.NoError(t, err)
+
+		didDocBytes, err := mockdiddoc.GetMockDIDDoc(t, false).JSONBytes()
+		require.NoError(t, err)
+
+		msg := service.NewDIDCommMsgMap(CreateConnReq{
+			ID:   uuid.New().String(),
+			Type: createConnReq,
+			Data: &CreateConnReqData{
+				DIDDoc: didDocBytes,
+			},
+		})
+
+		first, err := c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
+		require.NoError(t, err)
+
+		// fail if a new peer did would be created
+		c.vdriRegistry = &mockvdri.MockVDRegistry{
+			CreateErr: errors.New("did create error"),
+		}
+
+		retry, err := c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
+		require.NoError(t, err)
+
+		firstResp := &CreateConnResp{}
+		require.NoError(t, first.Decode(firstResp))
+
+		retryResp := &CreateConnResp{}
+		require.NoError(t, retry.Decode(retryResp))
+
+		require.Equal(t, firstResp.Data.DIDDoc, retryResp.Data.DIDDoc)
+
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:other-wallet"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "create new peer did")
+	})
+
+	t.Run("no connection", func(t *testing.T) {
+		c, err := New(config())
+		require.NoError(t, err)
+
+		didDocBytes, err := mockdiddoc.GetMockDIDDoc(t, false).JSONBytes()
+		require.NoError(t, err)
+
+		msg := service.NewDIDCommMsgMap(CreateConnReq{
+			ID:   uuid.New().String(),
+			Type: createConnReq,
+			Data: &CreateConnReqData{
+				DIDDoc: didDocBytes,
+			},
+		})
+
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg})
+		require.Error(t, err)
+
+		var problem *connReqProblem
+		require.True(t, errors.As(err, &problem))
+		require.Equal(t, ProblemConnectionMissing, problem.code)
+	})
+
+	t.Run("a service per DIDComm endpoint", func(t *testing.T) {
+		cfg := config()
+		cfg.DIDCommEndpoints = []aries.DIDCommEndpoint{
+			{URI: "https://example.com", Priority: 1},
+			{URI: "wss://example.com"},
+		}
+
+		c, err := New(cfg)
+		require.NoError(t, err)
+
+		var services []did.Service
+
+		c.vdriRegistry = &mockvdri.MockVDRegistry{
+			CreateFunc: func(_ string, doc *did.Doc, _ ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+				services = doc.Service
+
+				return &did.DocResolution{DIDDocument: doc}, nil
+			},
+		}
+
+		didDocBytes, err := mockdiddoc.GetMockDIDDoc(t, false).JSONBytes()
+		require.NoError(t, err)
+
+		msg := service.NewDIDCommMsgMap(CreateConnReq{
+			ID:   uuid.New().String(),
+			Type: createConnReq,
+			Data: &CreateConnReqData{
+				DIDDoc: didDocBytes,
+			},
+		})
+
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
+		require.NoError(t, err)
+		require.Len(t, services, 2)
+
+		uri, err := services[0].ServiceEndpoint.URI()
+		require.NoError(t, err)
+		require.Equal(t, "wss://example.com", uri)
+		require.Equal(t, uint(1), services[1].Priority)
+	})
+
 	t.Run("error if cannot create key", func(t *testing.T) {
 		expected := errors.New("test")
 
@@ -640,7 +1117,152 @@ func TestCreateConnectionReqHandler(t *testing.T) {
 			},
 		})
 
-		_, err = c.handleCreateConnReq(msg)
+		_, err = c.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
 		require.ErrorIs(t, err, expected)
 	})
 }
+
+func TestRouterKeyTypes(t *testing.T) {
+	ctx := getMockProvider()
+	ctx.KeyTypeValue = kms.ECDSAP384TypeIEEEP1363
+	ctx.KeyAgreementTypeValue = kms.NISTP384ECDHKWType
+
+	c, err := New(config(ctx))
+	require.NoError(t, err)
+
+	t.Run("defaults to configured key types", func(t *testing.T) {
+		keyTypes, err := c.routerKeyTypes(&did.Doc{ID: "did:example:123"})
+		require.NoError(t, err)
+		require.Equal(t, kms.ECDSAP384TypeIEEEP1363, keyTypes.verification)
+		require.Equal(t, kms.ECDSAP384TypeIEEEP1363, keyTypes.authentication)
+		require.Equal(t, kms.NISTP384ECDHKWType, keyTypes.keyAgreement)
+	})
+
+	t.Run("follows requester key types", func(t *testing.T) {
+		keyTypes, err := c.routerKeyTypes(&did.Doc{
+			ID:             "did:example:123",
+			Authentication: []did.Verification{*p256Verification(t, "#key-1", did.Authentication)},
+			KeyAgreement:   []did.Verification{*p256Verification(t, "#key-2", did.KeyAgreement)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, keyTypes.verification)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, keyTypes.authentication)
+		require.Equal(t, kms.NISTP256ECDHKWType, keyTypes.keyAgreement)
+
+		keyTypes, err = c.routerKeyTypes(mockdiddoc.GetMockDIDDoc(t, true))
+		require.NoError(t, err)
+		require.Equal(t, kms.ED25519Type, keyTypes.verification)
+		require.Equal(t, kms.X25519ECDHKWType, keyTypes.keyAgreement)
+	})
+
+	t.Run("unsupported requester key types", func(t *testing.T) {
+		rsa := did.VerificationMethod{ID: "#key-1", Type: "RsaVerificationKey2018"}
+
+		for name, doc := range map[string]*did.Doc{
+			"authentication": {
+				ID:             "did:example:123",
+				Authentication: []did.Verification{{VerificationMethod: rsa}},
+			},
+			"verification methods": {
+				ID:                 "did:example:123",
+				VerificationMethod: []did.VerificationMethod{rsa, {ID: "#key-2", Type: "EcdsaSecp256k1VerificationKey2019"}},
+			},
+			"key agreement": {
+				ID: "did:example:123",
+				KeyAgreement: []did.Verification{{VerificationMethod: did.VerificationMethod{
+					ID:   "#key-2",
+					Type: "Ed25519VerificationKey2018",
+				}}},
+			},
+		} {
+			_, err := c.routerKeyTypes(doc)
+			require.Error(t, err, name)
+
+			var problem *connReqProblem
+			require.True(t, errors.As(err, &problem), name)
+			require.Equal(t, ProblemKeyTypeNotSupported, problem.code, name)
+		}
+	})
+
+	t.Run("key agreement key only in verification methods", func(t *testing.T) {
+		kagr := p256Verification(t, "#key-1", did.KeyAgreement)
+		kagr.VerificationMethod.Type = "X25519KeyAgreementKey2019"
+
+		keyTypes, err := c.routerKeyTypes(&did.Doc{
+			ID:                 "did:example:123",
+			VerificationMethod: []did.VerificationMethod{kagr.VerificationMethod},
+			KeyAgreement:       []did.Verification{*kagr},
+		})
+		require.NoError(t, err)
+		require.Equal(t, kms.ECDSAP384TypeIEEEP1363, keyTypes.verification)
+		require.Equal(t, kms.ECDSAP384TypeIEEEP1363, keyTypes.authentication)
+		require.Equal(t, kms.X25519ECDHKWType, keyTypes.keyAgreement)
+	})
+
+	t.Run("mixed verification method types", func(t *testing.T) {
+		kagr := p256Verification(t, "#key-1", did.KeyAgreement)
+		kagr.VerificationMethod.Type = "X25519KeyAgreementKey2019"
+
+		keyTypes, err := c.routerKeyTypes(&did.Doc{
+			ID: "did:example:123",
+			VerificationMethod: []did.VerificationMethod{
+				{ID: "#key-0", Type: "RsaVerificationKey2018"},
+				kagr.VerificationMethod,
+				p256Verification(t, "#key-2", did.VerificationRelationshipGeneral).VerificationMethod,
+			},
+			KeyAgreement: []did.Verification{*kagr},
+		})
+		require.NoError(t, err)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, keyTypes.verification)
+		require.Equal(t, kms.ECDSAP384TypeIEEEP1363, keyTypes.authentication)
+		require.Equal(t, kms.X25519ECDHKWType, keyTypes.keyAgreement)
+	})
+
+	t.Run("create-conn-req with unsupported key types", func(t *testing.T) {
+		didDoc := mockdiddoc.GetMockDIDDoc(t, false)
+		didDoc.VerificationMethod = []did.VerificationMethod{{
+			ID:         didDoc.ID + "#key-1",
+			Type:       "RsaVerificationKey2018",
+			Controller: didDoc.ID,
+			Value:      []byte("rsa"),
+		}}
+		didDoc.Authentication = nil
+
+		didDocBytes, err := didDoc.JSONBytes()
+		require.NoError(t, err)
+
+		msg := service.NewDIDCommMsgMap(CreateConnReq{
+			ID:   uuid.New().String(),
+			Type: createConnReq,
+			Data: &CreateConnReqData{
+				DIDDoc: didDocBytes,
+			},
+		})
+
+		o, err := New(config())
+		require.NoError(t, err)
+
+		_, err = o.handleCreateConnReq(aries.InboundMsg{DIDCommMsg: msg, TheirDID: "did:example:wallet"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no supported key type in did document")
+
+		var problem *connReqProblem
+		require.True(t, errors.As(err, &problem))
+		require.Equal(t, ProblemKeyTypeNotSupported, problem.code)
+	})
+}
+
+func p256Verification(t *testing.T, id string, rel did.VerificationRelationship) *did.Verification {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	j, err := jwksupport.JWKFromKey(&priv.PublicKey)
+	require.NoError(t, err)
+
+	vm, err := did.NewVerificationMethodFromJWK(id, "JsonWebKey2020", "", j)
+	require.NoError(t, err)
+
+	return did.NewReferencedVerification(vm, rel)
+}
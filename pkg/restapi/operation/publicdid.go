@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/restapi/internal/httputil"
+)
+
+// Public DID admin endpoints.
+const (
+	publicDIDPath           = "/public-did"
+	publicDIDUpdatePath     = "/public-did/update"
+	publicDIDRecoverPath    = "/public-did/recover"
+	publicDIDDeactivatePath = "/public-did/deactivate"
+)
+
+// PublicDIDManager gets, updates, recovers and deactivates the public DID of the router.
+type PublicDIDManager interface {
+	Get() (*did.Doc, error)
+	Update(didcommEndPoint string, rotateKeys bool) (*did.Doc, error)
+	Recover(didcommEndPoint string) (*did.Doc, error)
+	Deactivate() (string, error)
+}
+
+func (o *Operation) getPublicDID(rw http.ResponseWriter, _ *http.Request) {
+	doc, err := o.publicDIDManager.Get()
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		if errors.Is(err, storage.ErrDataNotFound) {
+			status = http.StatusNotFound
+		}
+
+		httputil.WriteErrorResponseWithLog(rw, status, err.Error(), publicDIDPath, logger)
+
+		return
+	}
+
+	writePublicDIDDoc(rw, doc, publicDIDPath)
+}
+
+func (o *Operation) updatePublicDID(rw http.ResponseWriter, req *http.Request) {
+	o.managePublicDID(rw, req, publicDIDUpdatePath, func(pdReq *PublicDIDReq) (*did.Doc, error) {
+		return o.publicDIDManager.Update(pdReq.ServiceEndpoint, pdReq.RotateKeys)
+	})
+}
+
+func (o *Operation) recoverPublicDID(rw http.ResponseWriter, req *http.Request) {
+	o.managePublicDID(rw, req, publicDIDRecoverPath, func(pdReq *PublicDIDReq) (*did.Doc, error) {
+		return o.publicDIDManager.Recover(pdReq.ServiceEndpoint)
+	})
+}
+
+func (o *Operation) managePublicDID(rw http.ResponseWriter, req *http.Request, path string,
+	op func(pdReq *PublicDIDReq) (*did.Doc, error)) {
+	pdReq := &PublicDIDReq{}
+
+	if req.ContentLength != 0 {
+		err := json.NewDecoder(req.Body).Decode(pdReq)
+		if err != nil {
+			httputil.WriteErrorResponseWithLog(rw, http.StatusBadRequest,
+				fmt.Sprintf("invalid request : %s", err.Error()), path, logger)
+
+			return
+		}
+	}
+
+	doc, err := op(pdReq)
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError, err.Error(), path, logger)
+
+		return
+	}
+
+	writePublicDIDDoc(rw, doc, path)
+}
+
+func writePublicDIDDoc(rw http.ResponseWriter, doc *did.Doc, path string) {
+	docBytes, err := doc.JSONBytes()
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError,
+			fmt.Sprintf("marshal public DID doc : %s", err.Error()), path, logger)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &PublicDIDResp{DID: doc.ID, DIDDocument: docBytes}, path, logger)
+}
+
+func (o *Operation) deactivatePublicDID(rw http.ResponseWriter, _ *http.Request) {
+	didID, err := o.publicDIDManager.Deactivate()
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError, err.Error(),
+			publicDIDDeactivatePath, logger)
+
+		return
+	}
+
+	logger.Warnf("public DID %s deactivated: restart the router to create a new one", didID)
+
+	httputil.WriteResponseWithLog(rw, &PublicDIDResp{DID: didID}, publicDIDDeactivatePath, logger)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicDIDAdmin(t *testing.T) {
+	newOperation := func(t *testing.T, m *mockPublicDIDManager) *Operation {
+		t.Helper()
+
+		config := config()
+		config.PublicDIDManager = m
+		config.AdminToken = "admin-secret"
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		return o
+	}
+
+	t.Run("handlers", func(t *testing.T) {
+		o := newOperation(t, &mockPublicDIDManager{})
+
+		require.Len(t, o.GetRESTHandlers(), 5)
+
+		handlers := o.GetAdminRESTHandlers()
+		require.Len(t, handlers, 4)
+		require.Equal(t, publicDIDPath, handlers[0].Path())
+		require.Equal(t, http.MethodGet, handlers[0].Method())
+		require.Equal(t, publicDIDUpdatePath, handlers[1].Path())
+		require.Equal(t, http.MethodPost, handlers[1].Method())
+	})
+
+	t.Run("get", func(t *testing.T) {
+		o := newOperation(t, &mockPublicDIDManager{doc: &did.Doc{ID: "did:orb:foo", Context: []string{did.ContextV1}}})
+
+		w := httptest.NewRecorder()
+		o.getPublicDID(w, httptest.NewRequest(http.MethodGet, publicDIDPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		resp := &PublicDIDResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "did:orb:foo", resp.DID)
+		require.NotEmpty(t, resp.DIDDocument)
+
+		o = newOperation(t, &mockPublicDIDManager{err: fmt.Errorf("get public DID: %w", storage.ErrDataNotFound)})
+
+		w = httptest.NewRecorder()
+		o.getPublicDID(w, httptest.NewRequest(http.MethodGet, publicDIDPath, nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		m := &mockPublicDIDManager{doc: &did.Doc{ID: "did:orb:foo", Context: []string{did.ContextV1}}}
+		o := newOperation(t, m)
+
+		reqBytes, err := json.Marshal(&PublicDIDReq{ServiceEndpoint: "https://example.com/didcomm", RotateKeys: true})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		o.updatePublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDUpdatePath, bytes.NewReader(reqBytes)))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "https://example.com/didcomm", m.endpoint)
+		require.True(t, m.rotateKeys)
+
+		resp := &PublicDIDResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "did:orb:foo", resp.DID)
+		require.NotEmpty(t, resp.DIDDocument)
+	})
+
+	t.Run("recover without body", func(t *testing.T) {
+		m := &mockPublicDIDManager{doc: &did.Doc{ID: "did:orb:foo", Context: []string{did.ContextV1}}}
+		o := newOperation(t, m)
+
+		w := httptest.NewRecorder()
+		o.recoverPublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDRecoverPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.True(t, m.recovered)
+		require.Empty(t, m.endpoint)
+	})
+
+	t.Run("deactivate", func(t *testing.T) {
+		o := newOperation(t, &mockPublicDIDManager{doc: &did.Doc{ID: "did:orb:foo"}})
+
+		w := httptest.NewRecorder()
+		o.deactivatePublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDDeactivatePath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "did:orb:foo")
+	})
+
+	t.Run("admin token required", func(t *testing.T) {
+		send := func(o *Operation, path, authorization string) *httptest.ResponseRecorder {
+			for _, h := range o.GetAdminRESTHandlers() {
+				if h.Path() != path {
+					continue
+				}
+
+				req := httptest.NewRequest(http.MethodPost, path, nil)
+				if authorization != "" {
+					req.Header.Set("Authorization", authorization)
+				}
+
+				w := httptest.NewRecorder()
+				h.Handle()(w, req)
+
+				return w
+			}
+
+			require.Fail(t, "no handler for "+path)
+
+			return nil
+		}
+
+		m := &mockPublicDIDManager{doc: &did.Doc{ID: "did:orb:foo", Context: []string{did.ContextV1}}}
+		o := newOperation(t, m)
+
+		for _, path := range []string{publicDIDPath, publicDIDUpdatePath, publicDIDRecoverPath, publicDIDDeactivatePath} {
+			w := send(o, path, "")
+			require.Equal(t, http.StatusUnauthorized, w.Code, path)
+			require.Equal(t, `Bearer realm="mediator-admin"`, w.Header().Get("WWW-Authenticate"))
+
+			require.Equal(t, http.StatusUnauthorized, send(o, path, "Bearer wrong-secret").Code, path)
+			require.Equal(t, http.StatusUnauthorized, send(o, path, "admin-secret").Code, path)
+		}
+
+		require.False(t, m.recovered)
+		require.Equal(t, http.StatusOK, send(o, publicDIDRecoverPath, "Bearer admin-secret").Code)
+		require.True(t, m.recovered)
+
+		// the admin endpoints are disabled without an admin token
+		config := config()
+		config.PublicDIDManager = m
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		w := send(o, publicDIDDeactivatePath, "Bearer ")
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Contains(t, w.Body.String(), "admin endpoints are disabled")
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		o := newOperation(t, &mockPublicDIDManager{})
+
+		w := httptest.NewRecorder()
+		o.updatePublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDUpdatePath, bytes.NewReader([]byte("{"))))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "invalid request")
+	})
+
+	t.Run("manager errors", func(t *testing.T) {
+		o := newOperation(t, &mockPublicDIDManager{err: errors.New("orb error")})
+
+		w := httptest.NewRecorder()
+		o.getPublicDID(w, httptest.NewRequest(http.MethodGet, publicDIDPath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "orb error")
+
+		w = httptest.NewRecorder()
+		o.updatePublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDUpdatePath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "orb error")
+
+		w = httptest.NewRecorder()
+		o.deactivatePublicDID(w, httptest.NewRequest(http.MethodPost, publicDIDDeactivatePath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "orb error")
+	})
+}
+
+type mockPublicDIDManager struct {
+	doc        *did.Doc
+	err        error
+	endpoint   string
+	rotateKeys bool
+	recovered  bool
+}
+
+func (m *mockPublicDIDManager) Get() (*did.Doc, error) {
+	return m.doc, m.err
+}
+
+func (m *mockPublicDIDManager) Update(didcommEndPoint string, rotateKeys bool) (*did.Doc, error) {
+	m.endpoint = didcommEndPoint
+	m.rotateKeys = rotateKeys
+
+	return m.doc, m.err
+}
+
+func (m *mockPublicDIDManager) Recover(didcommEndPoint string) (*did.Doc, error) {
+	m.endpoint = didcommEndPoint
+	m.recovered = true
+
+	return m.doc, m.err
+}
+
+func (m *mockPublicDIDManager) Deactivate() (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+
+	return m.doc.ID, nil
+}
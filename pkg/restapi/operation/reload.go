@@ -0,0 +1,32 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"net/http"
+
+	"github.com/trustbloc/mediator/pkg/restapi/internal/httputil"
+)
+
+// Reload admin endpoint.
+const reloadPath = "/reload"
+
+// Reloader re-reads the configuration of the router and applies the parameters that don't need a restart.
+type Reloader interface {
+	Reload() error
+}
+
+func (o *Operation) reload(rw http.ResponseWriter, _ *http.Request) {
+	err := o.reloader.Reload()
+	if err != nil {
+		httputil.WriteErrorResponseWithLog(rw, http.StatusInternalServerError, err.Error(), reloadPath, logger)
+
+		return
+	}
+
+	httputil.WriteResponseWithLog(rw, &ReloadResp{Status: "reloaded"}, reloadPath, logger)
+}
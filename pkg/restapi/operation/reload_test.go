@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	newOperation := func(t *testing.T, r *mockReloader) *Operation {
+		t.Helper()
+
+		config := config()
+		config.Reloader = r
+
+		o, err := New(config)
+		require.NoError(t, err)
+
+		return o
+	}
+
+	t.Run("handlers", func(t *testing.T) {
+		o := newOperation(t, &mockReloader{})
+
+		require.Len(t, o.GetRESTHandlers(), 5)
+
+		handlers := o.GetAdminRESTHandlers()
+		require.Len(t, handlers, 1)
+		require.Equal(t, reloadPath, handlers[0].Path())
+		require.Equal(t, http.MethodPost, handlers[0].Method())
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		r := &mockReloader{}
+		o := newOperation(t, r)
+
+		w := httptest.NewRecorder()
+		o.reload(w, httptest.NewRequest(http.MethodPost, reloadPath, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, 1, r.reloads)
+
+		resp := &ReloadResp{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		require.Equal(t, "reloaded", resp.Status)
+	})
+
+	t.Run("admin token required", func(t *testing.T) {
+		r := &mockReloader{}
+		o := newOperation(t, r)
+
+		handlers := o.GetAdminRESTHandlers()
+		require.Len(t, handlers, 1)
+
+		w := httptest.NewRecorder()
+		handlers[0].Handle()(w, httptest.NewRequest(http.MethodPost, reloadPath, nil))
+		require.Equal(t, http.StatusForbidden, w.Code)
+		require.Zero(t, r.reloads)
+	})
+
+	t.Run("reload error", func(t *testing.T) {
+		o := newOperation(t, &mockReloader{err: errors.New("invalid config file")})
+
+		w := httptest.NewRecorder()
+		o.reload(w, httptest.NewRequest(http.MethodPost, reloadPath, nil))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Contains(t, w.Body.String(), "invalid config file")
+	})
+}
+
+type mockReloader struct {
+	reloads int
+	err     error
+}
+
+func (m *mockReloader) Reload() error {
+	m.reloads++
+
+	return m.err
+}
@@ -0,0 +1,284 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+)
+
+// DefaultMaxDIDDocSize is used when Config.MaxDIDDocSize is not set.
+const DefaultMaxDIDDocSize = 64 * 1024
+
+// Problem codes reported for create-conn-req requests with an invalid requester DID doc.
+const (
+	ProblemConnectionMissing        = "connection-missing"
+	ProblemDIDDocMissing            = "did-doc-missing"
+	ProblemDIDDocTooLarge           = "did-doc-too-large"
+	ProblemDIDDocInvalid            = "did-doc-invalid"
+	ProblemDIDMethodNotSupported    = "did-method-not-supported"
+	ProblemAuthenticationMissing    = "authentication-missing"
+	ProblemKeyAgreementMissing      = "key-agreement-missing"
+	ProblemDIDCommServiceMissing    = "didcomm-service-missing"
+	ProblemDIDCommServiceInvalid    = "didcomm-service-invalid"
+	ProblemRoutingKeyUnresolvable   = "routing-key-unresolvable"
+	ProblemRecipientKeyUnresolvable = "recipient-key-unresolvable"
+	ProblemKeyTypeNotSupported      = "key-type-not-supported"
+)
+
+// connReqProblem is a create-conn-req failure caused by the request itself; its code is reported to the requester.
+type connReqProblem struct {
+	code string
+	msg  string
+}
+
+func (p *connReqProblem) Error() string {
+	return p.msg
+}
+
+func newConnReqProblem(code, format string, args ...interface{}) error {
+	return &connReqProblem{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Kinds of DIDComm service keys checked by the validator.
+const (
+	routingKeyKind   = "routing"
+	recipientKeyKind = "recipient"
+)
+
+// keyErr is returned for DIDComm service routing or recipient keys that don't resolve.
+type keyErr struct {
+	kind string
+	key  string
+	err  error
+}
+
+func (e *keyErr) Error() string {
+	return fmt.Sprintf("resolve %s key '%s' : %s", e.kind, e.key, e.err.Error())
+}
+
+// didDocValidator validates requester DID docs sent in create-conn-req messages.
+type didDocValidator struct {
+	vdrRegistry vdrapi.Registry
+	didKeyVDR   *key.VDR
+	maxDocSize  int
+	didMethods  map[string]struct{}
+}
+
+func newDIDDocValidator(vdrRegistry vdrapi.Registry, maxDocSize int, didMethods []string) *didDocValidator {
+	if maxDocSize <= 0 {
+		maxDocSize = DefaultMaxDIDDocSize
+	}
+
+	// without configured methods, any DID method is accepted
+	var methods map[string]struct{}
+
+	if len(didMethods) > 0 {
+		methods = make(map[string]struct{}, len(didMethods))
+		for _, method := range didMethods {
+			methods[method] = struct{}{}
+		}
+	}
+
+	return &didDocValidator{
+		vdrRegistry: vdrRegistry,
+		didKeyVDR:   key.New(),
+		maxDocSize:  maxDocSize,
+		didMethods:  methods,
+	}
+}
+
+// validate parses the requester DID doc and returns it along with the DIDComm service the router DID should match.
+func (v *didDocValidator) validate(docBytes []byte) (*did.Doc, *did.Service, error) {
+	if len(docBytes) == 0 {
+		return nil, nil, newConnReqProblem(ProblemDIDDocMissing, "did document mandatory")
+	}
+
+	if len(docBytes) > v.maxDocSize {
+		return nil, nil, newConnReqProblem(ProblemDIDDocTooLarge,
+			"did document size %d exceeds the limit of %d bytes", len(docBytes), v.maxDocSize)
+	}
+
+	didDoc, err := did.ParseDocument(docBytes)
+	if err != nil {
+		return nil, nil, newConnReqProblem(ProblemDIDDocInvalid, "parse did doc : %s", err.Error())
+	}
+
+	err = v.validateMethod(didDoc.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	svc, err := v.didCommService(didDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if svc.Type == didCommV2ServiceType {
+		if len(didDoc.KeyAgreement) == 0 {
+			return nil, nil, newConnReqProblem(ProblemKeyAgreementMissing,
+				"did document has a DIDComm V2 service but no key agreement")
+		}
+	} else if len(didDoc.Authentication) == 0 && len(didDoc.VerificationMethod) == 0 {
+		return nil, nil, newConnReqProblem(ProblemAuthenticationMissing,
+			"did document has neither authentication nor verification methods")
+	}
+
+	return didDoc, svc, nil
+}
+
+func (v *didDocValidator) validateMethod(didID string) error {
+	parsed, err := did.Parse(didID)
+	if err != nil {
+		return newConnReqProblem(ProblemDIDDocInvalid, "parse did doc id : %s", err.Error())
+	}
+
+	if v.didMethods == nil {
+		return nil
+	}
+
+	if _, ok := v.didMethods[parsed.Method]; !ok {
+		return newConnReqProblem(ProblemDIDMethodNotSupported, "did method '%s' is not supported", parsed.Method)
+	}
+
+	return nil
+}
+
+// didCommService returns the first valid DIDComm service of the did doc.
+func (v *didDocValidator) didCommService(didDoc *did.Doc) (*did.Service, error) {
+	var (
+		problems         []string
+		badRoutingKeys   bool
+		badRecipientKeys bool
+	)
+
+	for i := range didDoc.Service {
+		svc := &didDoc.Service[i]
+
+		if svc.Type != vdrapi.DIDCommServiceType && svc.Type != didCommV2ServiceType {
+			continue
+		}
+
+		err := v.validateService(didDoc, svc)
+		if err == nil {
+			return svc, nil
+		}
+
+		problems = append(problems, err.Error())
+
+		var kErr *keyErr
+		if errors.As(err, &kErr) {
+			badRoutingKeys = badRoutingKeys || kErr.kind == routingKeyKind
+			badRecipientKeys = badRecipientKeys || kErr.kind == recipientKeyKind
+		}
+	}
+
+	if len(problems) > 0 {
+		code := ProblemDIDCommServiceInvalid
+
+		switch {
+		case badRoutingKeys:
+			code = ProblemRoutingKeyUnresolvable
+		case badRecipientKeys:
+			code = ProblemRecipientKeyUnresolvable
+		}
+
+		return nil, newConnReqProblem(code,
+			"no valid DIDComm service in did document : [%s]", strings.Join(problems, "; "))
+	}
+
+	return nil, newConnReqProblem(ProblemDIDCommServiceMissing, "did document has no DIDComm service")
+}
+
+func (v *didDocValidator) validateService(didDoc *did.Doc, svc *did.Service) error {
+	uri, err := svc.ServiceEndpoint.URI()
+	if err != nil || uri == "" {
+		return fmt.Errorf("service '%s' has no endpoint URI", svc.ID)
+	}
+
+	routingKeys := svc.RoutingKeys
+
+	if svc.Type == didCommV2ServiceType {
+		routingKeys, err = svc.ServiceEndpoint.RoutingKeys()
+		if err != nil {
+			return fmt.Errorf("service '%s' is not a DIDComm V2 endpoint", svc.ID)
+		}
+	} else {
+		// DIDComm V1 messages are packed for the service recipient keys, so there must be usable ones
+		if len(svc.RecipientKeys) == 0 {
+			return fmt.Errorf("service '%s' has no recipient keys", svc.ID)
+		}
+
+		for _, recipientKey := range svc.RecipientKeys {
+			err = v.resolveKey(didDoc, recipientKeyKind, recipientKey)
+			if err != nil {
+				return fmt.Errorf("service '%s' : %w", svc.ID, err)
+			}
+		}
+	}
+
+	for _, routingKey := range routingKeys {
+		err = v.resolveKey(didDoc, routingKeyKind, routingKey)
+		if err != nil {
+			return fmt.Errorf("service '%s' : %w", svc.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveKey checks that DID key references resolve; references to the did doc itself must match one of its
+// verification methods, other (raw) keys are accepted as they are.
+func (v *didDocValidator) resolveKey(didDoc *did.Doc, kind, keyRef string) error {
+	if strings.HasPrefix(keyRef, "#") || strings.HasPrefix(keyRef, didDoc.ID+"#") {
+		if !hasVerificationMethod(didDoc, keyRef) {
+			return &keyErr{kind: kind, key: keyRef, err: errors.New("no such verification method in did document")}
+		}
+
+		return nil
+	}
+
+	if !strings.HasPrefix(keyRef, "did:") {
+		return nil
+	}
+
+	didID := strings.Split(keyRef, "#")[0]
+
+	var err error
+
+	if strings.HasPrefix(didID, "did:key:") {
+		_, err = v.didKeyVDR.Read(didID)
+	} else {
+		_, err = v.vdrRegistry.Resolve(didID)
+	}
+
+	if err != nil {
+		return &keyErr{kind: kind, key: keyRef, err: err}
+	}
+
+	return nil
+}
+
+// hasVerificationMethod reports whether the did doc has a verification method with the fragment of keyRef.
+func hasVerificationMethod(didDoc *did.Doc, keyRef string) bool {
+	fragment := keyRef[strings.Index(keyRef, "#"):]
+
+	for _, vms := range didDoc.VerificationMethods() {
+		for _, vm := range vms {
+			if strings.HasSuffix(vm.VerificationMethod.ID, fragment) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
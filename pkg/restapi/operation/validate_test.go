@@ -0,0 +1,274 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/model"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	mockdiddoc "github.com/hyperledger/aries-framework-go/pkg/mock/diddoc"
+	mockvdri "github.com/hyperledger/aries-framework-go/pkg/mock/vdr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDDocValidator(t *testing.T) {
+	v := newDIDDocValidator(&mockvdri.MockVDRegistry{}, 0, nil)
+
+	t.Run("defaults", func(t *testing.T) {
+		require.Equal(t, DefaultMaxDIDDocSize, v.maxDocSize)
+		require.Nil(t, v.didMethods)
+
+		// any DID method is accepted
+		doc := mockdiddoc.GetMockDIDDoc(t, false)
+		doc.ID = "did:example:123"
+
+		_, _, err := v.validate(docBytes(t, doc))
+		require.NoError(t, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		for _, doc := range []*did.Doc{mockdiddoc.GetMockDIDDoc(t, false), mockDIDCommV2Doc(t)} {
+			didDoc, svc, err := v.validate(docBytes(t, doc))
+			require.NoError(t, err)
+			require.Equal(t, doc.ID, didDoc.ID)
+			require.Equal(t, doc.Service[0].Type, svc.Type)
+		}
+	})
+
+	t.Run("DIDComm service in any position", func(t *testing.T) {
+		doc := mockDIDCommV2Doc(t)
+		doc.Service = append([]did.Service{{ID: "linked", Type: "LinkedDomains",
+			ServiceEndpoint: model.NewDIDCoreEndpoint([]string{"https://example.com"})}}, doc.Service...)
+
+		_, svc, err := v.validate(docBytes(t, doc))
+		require.NoError(t, err)
+		require.Equal(t, didCommV2ServiceType, svc.Type)
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			doc    func() []byte
+			code   string
+			errMsg string
+		}{
+			{
+				name:   "missing",
+				doc:    func() []byte { return nil },
+				code:   ProblemDIDDocMissing,
+				errMsg: "did document mandatory",
+			},
+			{
+				name:   "too large",
+				doc:    func() []byte { return []byte(strings.Repeat(" ", DefaultMaxDIDDocSize+1)) },
+				code:   ProblemDIDDocTooLarge,
+				errMsg: "exceeds the limit",
+			},
+			{
+				name:   "invalid",
+				doc:    func() []byte { return []byte("invalid-diddoc") },
+				code:   ProblemDIDDocInvalid,
+				errMsg: "parse did doc",
+			},
+			{
+				name: "no DIDComm service",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service = nil
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemDIDCommServiceMissing,
+				errMsg: "did document has no DIDComm service",
+			},
+			{
+				name: "unresolvable routing key",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service[0].RoutingKeys = []string{"did:example:routing#key-1"}
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemRoutingKeyUnresolvable,
+				errMsg: "resolve routing key 'did:example:routing#key-1'",
+			},
+			{
+				name: "invalid did:key routing key",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service[0].RoutingKeys = []string{"did:key:invalid"}
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemRoutingKeyUnresolvable,
+				errMsg: "resolve routing key 'did:key:invalid'",
+			},
+			{
+				name: "DIDComm V1 without recipient keys",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service[0].RecipientKeys = nil
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemDIDCommServiceInvalid,
+				errMsg: "has no recipient keys",
+			},
+			{
+				name: "unresolvable recipient key",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service[0].RecipientKeys = []string{"did:example:recipient#key-1"}
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemRecipientKeyUnresolvable,
+				errMsg: "resolve recipient key 'did:example:recipient#key-1'",
+			},
+			{
+				name: "recipient key missing from the did doc",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service[0].RecipientKeys = []string{doc.ID + "#unknown"}
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemRecipientKeyUnresolvable,
+				errMsg: "no such verification method in did document",
+			},
+			{
+				name: "unresolvable routing and recipient keys",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.Service = append(doc.Service, doc.Service[0])
+					doc.Service[0].RecipientKeys = []string{"did:example:recipient#key-1"}
+					doc.Service[1].RoutingKeys = []string{"did:example:routing#key-1"}
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemRoutingKeyUnresolvable,
+				errMsg: "resolve recipient key 'did:example:recipient#key-1'",
+			},
+			{
+				name: "DIDComm V2 without key agreement",
+				doc: func() []byte {
+					doc := mockDIDCommV2Doc(t)
+					doc.KeyAgreement = nil
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemKeyAgreementMissing,
+				errMsg: "no key agreement",
+			},
+			{
+				name: "DIDComm V1 without keys",
+				doc: func() []byte {
+					doc := mockdiddoc.GetMockDIDDoc(t, false)
+					doc.VerificationMethod = nil
+
+					return docBytes(t, doc)
+				},
+				code:   ProblemAuthenticationMissing,
+				errMsg: "neither authentication nor verification methods",
+			},
+		}
+
+		for _, tc := range tests {
+			_, _, err := v.validate(tc.doc())
+			require.Error(t, err, tc.name)
+			require.Contains(t, err.Error(), tc.errMsg, tc.name)
+
+			var problem *connReqProblem
+			require.True(t, errors.As(err, &problem), tc.name)
+			require.Equal(t, tc.code, problem.code, tc.name)
+		}
+	})
+
+	t.Run("service without endpoint", func(t *testing.T) {
+		doc := mockDIDCommV2Doc(t)
+
+		err := v.validateService(doc, &did.Service{ID: "svc-1", Type: didCommV2ServiceType})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "service 'svc-1' has no endpoint URI")
+
+		err = v.validateService(doc, &did.Service{ID: "svc-1", Type: didCommV2ServiceType,
+			ServiceEndpoint: model.NewDIDCommV1Endpoint("https://example.com")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "service 'svc-1' is not a DIDComm V2 endpoint")
+	})
+
+	t.Run("recipient keys of the did doc", func(t *testing.T) {
+		doc := mockdiddoc.GetMockDIDDoc(t, false)
+		fragment := doc.VerificationMethod[0].ID[strings.Index(doc.VerificationMethod[0].ID, "#"):]
+
+		for _, recipientKey := range []string{fragment, doc.ID + fragment} {
+			doc.Service[0].RecipientKeys = []string{recipientKey}
+
+			_, _, err := v.validate(docBytes(t, doc))
+			require.NoError(t, err, recipientKey)
+		}
+	})
+
+	t.Run("configured DID methods and routing key resolution", func(t *testing.T) {
+		doc := mockdiddoc.GetMockDIDDoc(t, false)
+		doc.ID = "did:example:123"
+		doc.Service[0].RoutingKeys = []string{"did:example:routing#key-1"}
+
+		exampleValidator := newDIDDocValidator(&mockvdri.MockVDRegistry{ResolveValue: &did.Doc{ID: "did:example:routing"}},
+			1024*1024, []string{"example"})
+
+		_, _, err := exampleValidator.validate(docBytes(t, doc))
+		require.NoError(t, err)
+
+		peerValidator := newDIDDocValidator(&mockvdri.MockVDRegistry{}, 0, []string{"peer"})
+
+		_, _, err = peerValidator.validate(docBytes(t, doc))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "did method 'example' is not supported")
+
+		var problem *connReqProblem
+		require.True(t, errors.As(err, &problem))
+		require.Equal(t, ProblemDIDMethodNotSupported, problem.code)
+	})
+}
+
+func docBytes(t *testing.T, doc *did.Doc) []byte {
+	t.Helper()
+
+	b, err := doc.JSONBytes()
+	require.NoError(t, err)
+
+	return b
+}
+
+func mockDIDCommV2Doc(t *testing.T) *did.Doc {
+	t.Helper()
+
+	doc := mockdiddoc.GetMockDIDDoc(t, false)
+	doc.Service[0].Type = didCommV2ServiceType
+	doc.Service[0].RecipientKeys = nil
+	doc.Service[0].ServiceEndpoint = model.NewDIDCommV2Endpoint([]model.DIDCommV2Endpoint{{
+		URI:         "https://localhost:8090",
+		Accept:      []string{"didcomm/v2"},
+		RoutingKeys: doc.Service[0].RoutingKeys,
+	}})
+	doc.Service[0].RoutingKeys = nil
+
+	j, err := jwksupport.JWKFromX25519Key(make([]byte, 32))
+	require.NoError(t, err)
+
+	vm, err := did.NewVerificationMethodFromJWK(doc.ID+"#key-2", "JsonWebKey2020", doc.ID, j)
+	require.NoError(t, err)
+
+	doc.KeyAgreement = []did.Verification{*did.NewEmbeddedVerification(vm, did.KeyAgreement)}
+
+	return doc
+}
@@ -0,0 +1,441 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package archive provides the encrypted backup archives of the storage of the mediator.
+//
+// An archive starts with a plaintext JSON header line holding the format version and a random salt. The rest is the
+// gzipped stream of the stores and their entries, as JSON lines, encrypted with AES-256-GCM in chunks. The key of the
+// archive is derived from the backup key and the salt. The nonce of a chunk is its index, with a flag marking the last
+// chunk, and the header is authenticated with each chunk, so that reordered, truncated or altered archives are
+// rejected.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is the size of the backup key in bytes.
+const KeySize = 32
+
+const (
+	// Format is the format name in the header of the archives.
+	Format = "mediator-backup"
+	// Version is the version of the archives written.
+	Version = 1
+
+	saltSize  = 32
+	chunkSize = 64 * 1024
+	keyInfo   = "mediator backup"
+
+	lastChunkFlag = 1
+)
+
+// ReadKeyFile reads a backup key from a file holding it base64 encoded.
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path) // nolint:gosec // path set by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read backup key : %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("decode backup key %s : %w", path, err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("backup key %s must be %d bytes, got %d", path, KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// Header is the plaintext header of an archive.
+type Header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Salt    []byte    `json:"salt"`
+}
+
+// Store is the record starting the entries of a store in an archive.
+type Store struct {
+	// Provider is the storage prefix of the provider the store is opened with, ex: hubrouter_aries.
+	Provider string   `json:"provider"`
+	Name     string   `json:"name"`
+	TagNames []string `json:"tagNames,omitempty"`
+}
+
+// Entry is an entry of the last store in an archive.
+type Entry struct {
+	Key   string        `json:"key"`
+	Value []byte        `json:"value"`
+	Tags  []storage.Tag `json:"tags,omitempty"`
+}
+
+// end is the last record of an archive.
+type end struct {
+	Stores  int `json:"stores"`
+	Entries int `json:"entries"`
+}
+
+type record struct {
+	Store *Store `json:"store,omitempty"`
+	Entry *Entry `json:"entry,omitempty"`
+	End   *end   `json:"end,omitempty"`
+}
+
+// Writer writes an archive.
+type Writer struct {
+	chunks  *chunkWriter
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	stores  int
+	entries int
+	inStore bool
+}
+
+// NewWriter writes the header of an archive encrypted with the backup key to w, and returns the Writer of its
+// stores and entries. Close must be called to complete the archive.
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	salt := make([]byte, saltSize)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generate salt : %w", err)
+	}
+
+	headerBytes, err := json.Marshal(&Header{
+		Format:  Format,
+		Version: Version,
+		Created: time.Now().UTC(),
+		Salt:    salt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal header : %w", err)
+	}
+
+	headerBytes = append(headerBytes, '\n')
+
+	aead, err := newAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(headerBytes)
+	if err != nil {
+		return nil, fmt.Errorf("write header : %w", err)
+	}
+
+	chunks := &chunkWriter{w: w, aead: aead, aad: headerBytes}
+	gz := gzip.NewWriter(chunks)
+
+	return &Writer{chunks: chunks, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+// WriteStore starts the entries of a store.
+func (w *Writer) WriteStore(s *Store) error {
+	w.stores++
+	w.inStore = true
+
+	return w.write(&record{Store: s})
+}
+
+// WriteEntry writes an entry of the last store started.
+func (w *Writer) WriteEntry(e *Entry) error {
+	if !w.inStore {
+		return errors.New("entry written before its store")
+	}
+
+	w.entries++
+
+	return w.write(&record{Entry: e})
+}
+
+// Close completes the archive. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	err := w.write(&record{End: &end{Stores: w.stores, Entries: w.entries}})
+	if err != nil {
+		return err
+	}
+
+	err = w.gz.Close()
+	if err != nil {
+		return fmt.Errorf("compress archive : %w", err)
+	}
+
+	return w.chunks.close()
+}
+
+func (w *Writer) write(r *record) error {
+	err := w.enc.Encode(r)
+	if err != nil {
+		return fmt.Errorf("write archive : %w", err)
+	}
+
+	return nil
+}
+
+// Reader reads an archive.
+type Reader struct {
+	header  *Header
+	gz      *gzip.Reader
+	dec     *json.Decoder
+	stores  int
+	entries int
+	inStore bool
+	done    bool
+}
+
+// NewReader reads the header of an archive from r, and returns the Reader of its stores and entries. The backup key
+// must be the one the archive was written with.
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	headerBytes, err := br.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read header : %w", err)
+	}
+
+	header := &Header{}
+
+	err = json.Unmarshal(headerBytes, header)
+	if err != nil || header.Format != Format {
+		return nil, errors.New("not a mediator backup archive")
+	}
+
+	if header.Version < 1 || header.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d: versions up to %d are supported",
+			header.Version, Version)
+	}
+
+	aead, err := newAEAD(key, header.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(&chunkReader{r: br, aead: aead, aad: headerBytes})
+	if err != nil {
+		return nil, fmt.Errorf("read archive : %w", err)
+	}
+
+	return &Reader{header: header, gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+// Header returns the header of the archive.
+func (r *Reader) Header() *Header {
+	return r.header
+}
+
+// Next returns the next store or entry of the archive: either the store or the entry is set. It returns io.EOF at the
+// end of a complete archive.
+func (r *Reader) Next() (*Store, *Entry, error) {
+	if r.done {
+		return nil, nil, io.EOF
+	}
+
+	rec := &record{}
+
+	err := r.dec.Decode(rec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read archive : %w", err)
+	}
+
+	switch {
+	case rec.Store != nil:
+		r.stores++
+		r.inStore = true
+
+		return rec.Store, nil, nil
+	case rec.Entry != nil && r.inStore:
+		r.entries++
+
+		return nil, rec.Entry, nil
+	case rec.End != nil:
+		if rec.End.Stores != r.stores || rec.End.Entries != r.entries {
+			return nil, nil, fmt.Errorf("read archive : %d stores and %d entries read, %d and %d written",
+				r.stores, r.entries, rec.End.Stores, rec.End.Entries)
+		}
+
+		r.done = true
+
+		return nil, nil, io.EOF
+	default:
+		return nil, nil, errors.New("read archive : invalid record")
+	}
+}
+
+func newAEAD(key, salt []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("backup key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	archiveKey := make([]byte, KeySize)
+
+	_, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte(keyInfo)), archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("derive archive key : %w", err)
+	}
+
+	block, err := aes.NewCipher(archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher : %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher : %w", err)
+	}
+
+	return aead, nil
+}
+
+// chunkNonce returns the nonce of the chunk with the given index: the index, followed by the last chunk flag.
+func chunkNonce(size int, index uint64, last bool) []byte {
+	nonce := make([]byte, size)
+	binary.BigEndian.PutUint64(nonce[size-9:size-1], index)
+
+	if last {
+		nonce[size-1] = lastChunkFlag
+	}
+
+	return nonce
+}
+
+// chunkWriter encrypts what's written to it in chunks, each prefixed with its length.
+type chunkWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	aad   []byte
+	buf   []byte
+	index uint64
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		free := chunkSize - len(c.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+
+		c.buf = append(c.buf, p[:free]...)
+		p = p[free:]
+
+		// a full chunk is only sealed once more data comes, since the last chunk is marked
+		if len(c.buf) == chunkSize && len(p) > 0 {
+			err := c.seal(false)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (c *chunkWriter) close() error {
+	return c.seal(true)
+}
+
+func (c *chunkWriter) seal(last bool) error {
+	sealed := c.aead.Seal(nil, chunkNonce(c.aead.NonceSize(), c.index, last), c.buf, c.aad)
+
+	length := make([]byte, 4) // nolint:gomnd // uint32
+	binary.BigEndian.PutUint32(length, uint32(len(sealed)))
+
+	_, err := c.w.Write(append(length, sealed...))
+	if err != nil {
+		return fmt.Errorf("write archive : %w", err)
+	}
+
+	c.index++
+	c.buf = c.buf[:0]
+
+	return nil
+}
+
+// chunkReader decrypts the chunks written by a chunkWriter.
+type chunkReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	aad   []byte
+	buf   []byte
+	index uint64
+	last  bool
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.last {
+			return 0, io.EOF
+		}
+
+		err := c.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+
+	return n, nil
+}
+
+func (c *chunkReader) open() error {
+	length := make([]byte, 4) // nolint:gomnd // uint32
+
+	_, err := io.ReadFull(c.r, length)
+	if err != nil {
+		return fmt.Errorf("archive truncated : %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(length)
+	if size > chunkSize+uint32(c.aead.Overhead()) {
+		return errors.New("invalid archive chunk")
+	}
+
+	sealed := make([]byte, size)
+
+	_, err = io.ReadFull(c.r, sealed)
+	if err != nil {
+		return fmt.Errorf("archive truncated : %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+
+	c.buf, err = c.aead.Open(nil, chunkNonce(nonceSize, c.index, false), sealed, c.aad)
+	if err != nil {
+		c.buf, err = c.aead.Open(nil, chunkNonce(nonceSize, c.index, true), sealed, c.aad)
+		if err != nil {
+			return errors.New("decrypt archive : wrong backup key or altered archive")
+		}
+
+		c.last = true
+	}
+
+	c.index++
+
+	return nil
+}
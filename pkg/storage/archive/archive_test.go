@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package archive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, KeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func newProviders() map[string]storage.Provider {
+	return map[string]storage.Provider{
+		"hubrouter":       enumerable.NewProvider(mem.NewProvider()),
+		"hubrouter_aries": enumerable.NewProvider(mem.NewProvider()),
+	}
+}
+
+func backup(t *testing.T, key []byte, providers map[string]storage.Provider) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(buf, key)
+	require.NoError(t, err)
+
+	stats, err := Backup(w, providers)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, &Stats{Stores: 3, Entries: 4}, stats)
+
+	return buf.Bytes()
+}
+
+func TestBackupRestore(t *testing.T) {
+	key := newKey(t)
+	source := newProviders()
+
+	// bigger than a chunk, and not compressible
+	bigValue := make([]byte, 3*chunkSize)
+	_, err := rand.Read(bigValue)
+	require.NoError(t, err)
+
+	kmsStore, err := source["hubrouter_aries"].OpenStore("kmsdb")
+	require.NoError(t, err)
+	require.NoError(t, kmsStore.Put("key1", []byte("private key")))
+	require.NoError(t, kmsStore.Put("key2", bigValue))
+
+	connStore, err := source["hubrouter_aries"].OpenStore("didexchange")
+	require.NoError(t, err)
+	require.NoError(t, source["hubrouter_aries"].SetStoreConfig("didexchange",
+		storage.StoreConfiguration{TagNames: []string{"theirDID"}}))
+	require.NoError(t, connStore.Put("conn_1", []byte("{}"), storage.Tag{Name: "theirDID", Value: "peer1"}))
+
+	didStore, err := source["hubrouter"].OpenStore("router-invitation-did")
+	require.NoError(t, err)
+	require.NoError(t, didStore.Put("did", []byte("did:orb:1")))
+
+	archive := backup(t, key, source)
+
+	t.Run("restore", func(t *testing.T) {
+		target := newProviders()
+
+		r, err := NewReader(bytes.NewReader(archive), key)
+		require.NoError(t, err)
+		require.Equal(t, Version, r.Header().Version)
+
+		stats, err := Restore(r, target)
+		require.NoError(t, err)
+		require.Equal(t, &Stats{Stores: 3, Entries: 4}, stats)
+
+		s, err := target["hubrouter_aries"].OpenStore("kmsdb")
+		require.NoError(t, err)
+
+		v, err := s.Get("key2")
+		require.NoError(t, err)
+		require.Equal(t, bigValue, v)
+
+		s, err = target["hubrouter_aries"].OpenStore("didexchange")
+		require.NoError(t, err)
+
+		it, err := s.Query("theirDID:peer1")
+		require.NoError(t, err)
+
+		ok, err := it.Next()
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		names, err := enumerable.Stores(target["hubrouter"].(*enumerable.Provider))
+		require.NoError(t, err)
+		require.Equal(t, []string{"router-invitation-did"}, names)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(archive), newKey(t))
+		if err == nil {
+			_, err = Restore(r, newProviders())
+		}
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wrong backup key or altered archive")
+	})
+
+	t.Run("altered archive", func(t *testing.T) {
+		altered := append([]byte{}, archive...)
+		altered[len(altered)-1] ^= 1
+
+		r, err := NewReader(bytes.NewReader(altered), key)
+		if err == nil {
+			_, err = Restore(r, newProviders())
+		}
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wrong backup key or altered archive")
+	})
+
+	t.Run("truncated archive", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(archive[:len(archive)-chunkSize]), key)
+		require.NoError(t, err)
+
+		_, err = Restore(r, newProviders())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "archive truncated")
+	})
+
+	t.Run("altered header", func(t *testing.T) {
+		altered := strings.Replace(string(archive), `"version":1`, `"version":1 `, 1)
+
+		r, err := NewReader(strings.NewReader(altered), key)
+		if err == nil {
+			_, err = Restore(r, newProviders())
+		}
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wrong backup key or altered archive")
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		altered := strings.Replace(string(archive), `"version":1`, `"version":2`, 1)
+
+		_, err := NewReader(strings.NewReader(altered), key)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported archive version 2")
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		_, err := NewReader(strings.NewReader("{}\n"), key)
+		require.EqualError(t, err, "not a mediator backup archive")
+	})
+
+	t.Run("missing storage", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(archive), key)
+		require.NoError(t, err)
+
+		_, err = Restore(r, map[string]storage.Provider{"hubrouter": enumerable.NewProvider(mem.NewProvider())})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no storage hubrouter_aries")
+	})
+
+	t.Run("storage not enumerable", func(t *testing.T) {
+		w, err := NewWriter(&bytes.Buffer{}, key)
+		require.NoError(t, err)
+
+		_, err = Backup(w, map[string]storage.Provider{"hubrouter": mem.NewProvider()})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the stores of storage hubrouter can't be listed")
+	})
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	key := newKey(t)
+
+	path := filepath.Join(dir, "backup.key")
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+
+	read, err := ReadKeyFile(path)
+	require.NoError(t, err)
+	require.Equal(t, key, read)
+
+	path = filepath.Join(dir, "short.key")
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key[:16])), 0o600))
+
+	_, err = ReadKeyFile(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be 32 bytes")
+
+	_, err = ReadKeyFile(filepath.Join(dir, "missing.key"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "read backup key")
+}
@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+// restoreBatchSize is the number of entries put at once on restore.
+const restoreBatchSize = 100
+
+// Stats are the numbers of stores and entries backed up or restored.
+type Stats struct {
+	Stores  int `json:"stores"`
+	Entries int `json:"entries"`
+}
+
+// Backup writes all the stores of the providers, by storage prefix, to the archive. The providers must be
+// enumerable.Providers, for their stores to be listed.
+func Backup(w *Writer, providers map[string]storage.Provider) (*Stats, error) {
+	stats := &Stats{}
+
+	for _, prefix := range sortedPrefixes(providers) {
+		p, ok := providers[prefix].(*enumerable.Provider)
+		if !ok {
+			return stats, fmt.Errorf("the stores of storage %s can't be listed", prefix)
+		}
+
+		names, err := enumerable.Stores(p)
+		if err != nil {
+			return stats, fmt.Errorf("list stores of storage %s : %w", prefix, err)
+		}
+
+		for _, name := range names {
+			n, err := backupStore(w, p, prefix, name)
+			if err != nil {
+				return stats, fmt.Errorf("back up store %s of storage %s : %w", name, prefix, err)
+			}
+
+			stats.Stores++
+			stats.Entries += n
+		}
+	}
+
+	return stats, nil
+}
+
+func backupStore(w *Writer, p storage.Provider, prefix, name string) (int, error) {
+	s, err := p.OpenStore(name)
+	if err != nil {
+		return 0, err
+	}
+
+	config, err := p.GetStoreConfig(name)
+	// depending on the driver, stores without tags have no config
+	if err != nil && !errors.Is(err, storage.ErrStoreNotFound) && !errors.Is(err, storage.ErrDataNotFound) {
+		return 0, fmt.Errorf("get store config : %w", err)
+	}
+
+	err = w.WriteStore(&Store{Provider: prefix, Name: name, TagNames: config.TagNames})
+	if err != nil {
+		return 0, err
+	}
+
+	it, err := enumerable.All(s)
+	if err != nil {
+		return 0, err
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	count := 0
+
+	for {
+		ok, e := it.Next()
+		if e != nil {
+			return count, fmt.Errorf("list store : %w", e)
+		}
+
+		if !ok {
+			return count, nil
+		}
+
+		entry, e := readEntry(it)
+		if e != nil {
+			return count, e
+		}
+
+		e = w.WriteEntry(entry)
+		if e != nil {
+			return count, e
+		}
+
+		count++
+	}
+}
+
+func readEntry(it storage.Iterator) (*Entry, error) {
+	key, err := it.Key()
+	if err != nil {
+		return nil, fmt.Errorf("read key : %w", err)
+	}
+
+	value, err := it.Value()
+	if err != nil {
+		return nil, fmt.Errorf("read value of %s : %w", key, err)
+	}
+
+	tags, err := it.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("read tags of %s : %w", key, err)
+	}
+
+	return &Entry{Key: key, Value: value, Tags: tags}, nil
+}
+
+// Restore puts the stores and entries of the archive in the providers, by storage prefix.
+func Restore(r *Reader, providers map[string]storage.Provider) (*Stats, error) {
+	stats := &Stats{}
+
+	var (
+		current storage.Store
+		batch   []storage.Operation
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := current.Batch(batch)
+		if err != nil {
+			return fmt.Errorf("put entries : %w", err)
+		}
+
+		stats.Entries += len(batch)
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for {
+		s, entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return stats, flush()
+		}
+
+		if err != nil {
+			return stats, err
+		}
+
+		if entry != nil {
+			batch = append(batch, storage.Operation{Key: entry.Key, Value: entry.Value, Tags: entry.Tags})
+
+			if len(batch) == restoreBatchSize {
+				err = flush()
+			}
+		} else {
+			err = flush()
+			if err == nil {
+				current, err = openStore(providers, s)
+			}
+
+			if err == nil {
+				stats.Stores++
+			}
+		}
+
+		if err != nil {
+			return stats, err
+		}
+	}
+}
+
+func openStore(providers map[string]storage.Provider, s *Store) (storage.Store, error) {
+	p, ok := providers[s.Provider]
+	if !ok {
+		return nil, fmt.Errorf("no storage %s to restore store %s to", s.Provider, s.Name)
+	}
+
+	store, err := p.OpenStore(s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("open store %s of storage %s : %w", s.Name, s.Provider, err)
+	}
+
+	err = p.SetStoreConfig(s.Name, storage.StoreConfiguration{TagNames: s.TagNames})
+	if err != nil {
+		return nil, fmt.Errorf("set config of store %s of storage %s : %w", s.Name, s.Provider, err)
+	}
+
+	return store, nil
+}
+
+func sortedPrefixes(providers map[string]storage.Provider) []string {
+	prefixes := make([]string, 0, len(providers))
+
+	for prefix := range providers {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	return prefixes
+}
@@ -0,0 +1,237 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package encrypted provides a storage provider that encrypts the data of another provider at rest.
+//
+// Values are encrypted with AES-256-GCM, together with their key and tags. Keys, tag names and tag values are
+// replaced by keyed hashes (HMAC-SHA256), so that lookups by key and tag queries still work on the underlying
+// provider without revealing them.
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/formattedstore"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is the size of the master key in bytes.
+const KeySize = 32
+
+const (
+	encryptionKeyInfo = "mediator storage encryption"
+	hashKeyInfo       = "mediator storage hash"
+
+	// the keyed hashes of the tag names and values are domain separated so that equal names and values differ.
+	tagNameDomain  = "n"
+	tagValueDomain = "v"
+)
+
+// Provider is a storage.Provider that stores the data of the underlying provider encrypted.
+type Provider struct {
+	*formattedstore.FormattedProvider
+	underlying storage.Provider
+}
+
+// NewProvider returns a Provider that encrypts the data stored in the underlying provider with keys derived from the
+// master key, which must be KeySize bytes long.
+func NewProvider(underlying storage.Provider, masterKey []byte) (*Provider, error) {
+	f, err := newFormatter(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		FormattedProvider: formattedstore.NewProvider(underlying, f),
+		underlying:        underlying,
+	}, nil
+}
+
+// Unwrap returns the underlying provider, for the data that doesn't need to be encrypted.
+func (p *Provider) Unwrap() storage.Provider {
+	return p.underlying
+}
+
+// ReadKeyFile reads a master key from a file holding it base64 encoded.
+func ReadKeyFile(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path) // nolint:gosec // path set by the operator
+	if err != nil {
+		return nil, fmt.Errorf("read storage encryption key : %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("decode storage encryption key %s : %w", path, err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("storage encryption key %s must be %d bytes, got %d", path, KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// document is the plaintext of a stored value. The key and the tags are kept in it since their keyed hashes can't be
+// reversed.
+type document struct {
+	Key   string        `json:"k"`
+	Value []byte        `json:"v"`
+	Tags  []storage.Tag `json:"t,omitempty"`
+}
+
+// formatter is the formattedstore.Formatter encrypting the values and hashing the keys and tags.
+type formatter struct {
+	aead    cipher.AEAD
+	hashKey []byte
+}
+
+func newFormatter(masterKey []byte) (*formatter, error) {
+	if len(masterKey) != KeySize {
+		return nil, fmt.Errorf("storage encryption key must be %d bytes, got %d", KeySize, len(masterKey))
+	}
+
+	encryptionKey, err := deriveKey(masterKey, encryptionKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	hashKey, err := deriveKey(masterKey, hashKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher : %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher : %w", err)
+	}
+
+	return &formatter{aead: aead, hashKey: hashKey}, nil
+}
+
+func deriveKey(masterKey []byte, info string) ([]byte, error) {
+	key := make([]byte, KeySize)
+
+	_, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, []byte(info)), key)
+	if err != nil {
+		return nil, fmt.Errorf("derive %s key : %w", info, err)
+	}
+
+	return key, nil
+}
+
+// Format hashes the key and the tags, and encrypts the value with them. The value is nil if it is.
+func (f *formatter) Format(key string, value []byte,
+	tags ...storage.Tag) (string, []byte, []storage.Tag, error) {
+	formattedKey := ""
+	if key != "" {
+		formattedKey = f.hash("", key)
+	}
+
+	formattedTags := make([]storage.Tag, len(tags))
+
+	for i, tag := range tags {
+		formattedTags[i] = storage.Tag{
+			Name:  f.hash(tagNameDomain, tag.Name),
+			Value: f.hash(tagValueDomain, tag.Name+":"+tag.Value),
+		}
+	}
+
+	if value == nil {
+		return formattedKey, nil, formattedTags, nil
+	}
+
+	formattedValue, err := f.encrypt(&document{Key: key, Value: value, Tags: tags})
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return formattedKey, formattedValue, formattedTags, nil
+}
+
+// Deformat decrypts the value, which holds the key and the tags.
+func (f *formatter) Deformat(formattedKey string, formattedValue []byte,
+	_ ...storage.Tag) (string, []byte, []storage.Tag, error) {
+	if formattedValue == nil {
+		return "", nil, nil, errors.New("no encrypted value")
+	}
+
+	doc, err := f.decrypt(formattedValue)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if formattedKey != "" && !hmac.Equal([]byte(formattedKey), []byte(f.hash("", doc.Key))) {
+		return "", nil, nil, errors.New("encrypted value stored under another key")
+	}
+
+	return doc.Key, doc.Value, doc.Tags, nil
+}
+
+// UsesDeterministicKeyFormatting is true: the keyed hash of a key is always the same.
+func (f *formatter) UsesDeterministicKeyFormatting() bool {
+	return true
+}
+
+func (f *formatter) hash(domain, s string) string {
+	mac := hmac.New(sha256.New, f.hashKey)
+	mac.Write([]byte(domain + s)) // nolint:errcheck,gosec // never fails
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (f *formatter) encrypt(doc *document) ([]byte, error) {
+	plaintext, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal value : %w", err)
+	}
+
+	nonce := make([]byte, f.aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce : %w", err)
+	}
+
+	return f.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (f *formatter) decrypt(value []byte) (*document, error) {
+	nonceSize := f.aead.NonceSize()
+	if len(value) < nonceSize {
+		return nil, errors.New("decrypt value : too short")
+	}
+
+	plaintext, err := f.aead.Open(nil, value[:nonceSize], value[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt value : %w", err)
+	}
+
+	doc := &document{}
+
+	err = json.Unmarshal(plaintext, doc)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal value : %w", err)
+	}
+
+	return doc, nil
+}
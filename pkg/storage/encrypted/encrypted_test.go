@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package encrypted
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, KeySize)
+
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestProvider(t *testing.T) {
+	underlying := mem.NewProvider()
+	key := newKey(t)
+
+	provider, err := NewProvider(underlying, key)
+	require.NoError(t, err)
+	require.Equal(t, underlying, provider.Unwrap())
+
+	store, err := provider.OpenStore("connections")
+	require.NoError(t, err)
+
+	require.NoError(t, provider.SetStoreConfig("connections", storage.StoreConfiguration{TagNames: []string{"theirDID"}}))
+
+	require.NoError(t, store.Put("conn1", []byte("routing metadata 1"), storage.Tag{Name: "theirDID", Value: "did1"}))
+	require.NoError(t, store.Put("conn2", []byte("routing metadata 2"), storage.Tag{Name: "theirDID", Value: "did2"}))
+
+	t.Run("get", func(t *testing.T) {
+		value, err := store.Get("conn1")
+		require.NoError(t, err)
+		require.Equal(t, "routing metadata 1", string(value))
+
+		tags, err := store.GetTags("conn1")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Tag{{Name: "theirDID", Value: "did1"}}, tags)
+
+		values, err := store.GetBulk("conn2", "missing")
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("routing metadata 2"), nil}, values)
+
+		_, err = store.Get("missing")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		it, err := store.Query("theirDID:did2")
+		require.NoError(t, err)
+
+		ok, err := it.Next()
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		k, err := it.Key()
+		require.NoError(t, err)
+		require.Equal(t, "conn2", k)
+
+		ok, err = it.Next()
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		it, err = store.Query("theirDID")
+		require.NoError(t, err)
+
+		count := 0
+
+		for ok, err = it.Next(); ok; ok, err = it.Next() {
+			count++
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("stored encrypted", func(t *testing.T) {
+		raw, err := underlying.OpenStore("connections")
+		require.NoError(t, err)
+
+		_, err = raw.Get("conn1")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		it, err := raw.Query("theirDID:did1")
+		require.NoError(t, err)
+
+		ok, err := it.Next()
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("batch and delete", func(t *testing.T) {
+		require.NoError(t, store.Batch([]storage.Operation{
+			{Key: "conn3", Value: []byte("routing metadata 3")},
+			{Key: "conn1"},
+		}))
+
+		_, err := store.Get("conn1")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+
+		value, err := store.Get("conn3")
+		require.NoError(t, err)
+		require.Equal(t, "routing metadata 3", string(value))
+
+		require.NoError(t, store.Delete("conn3"))
+
+		_, err = store.Get("conn3")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := NewProvider(underlying, newKey(t))
+		require.NoError(t, err)
+
+		otherStore, err := other.OpenStore("connections")
+		require.NoError(t, err)
+
+		_, err = otherStore.Get("conn2")
+		require.ErrorIs(t, err, storage.ErrDataNotFound)
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		_, err := NewProvider(underlying, []byte("short"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be 32 bytes")
+	})
+}
+
+func TestFormatter(t *testing.T) {
+	f, err := newFormatter(newKey(t))
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		key, value, tags, err := f.Format("k", []byte("secret"), storage.Tag{Name: "name", Value: "value"})
+		require.NoError(t, err)
+		require.NotEqual(t, "k", key)
+		require.False(t, bytes.Contains(value, []byte("secret")))
+		require.NotEqual(t, "name", tags[0].Name)
+		require.NotEqual(t, "value", tags[0].Value)
+
+		k, v, tt, err := f.Deformat(key, value, tags...)
+		require.NoError(t, err)
+		require.Equal(t, "k", k)
+		require.Equal(t, "secret", string(v))
+		require.Equal(t, []storage.Tag{{Name: "name", Value: "value"}}, tt)
+	})
+
+	t.Run("deterministic hashes, random encryption", func(t *testing.T) {
+		key1, value1, tags1, err := f.Format("k", []byte("secret"), storage.Tag{Name: "name", Value: "value"})
+		require.NoError(t, err)
+
+		key2, value2, tags2, err := f.Format("k", []byte("secret"), storage.Tag{Name: "name", Value: "value"})
+		require.NoError(t, err)
+
+		require.Equal(t, key1, key2)
+		require.Equal(t, tags1, tags2)
+		require.NotEqual(t, value1, value2)
+
+		_, _, tags3, err := f.Format("", nil, storage.Tag{Name: "value", Value: "name"})
+		require.NoError(t, err)
+		require.NotEqual(t, tags1[0].Name, tags3[0].Value)
+	})
+
+	t.Run("value under another key", func(t *testing.T) {
+		_, value, _, err := f.Format("k", []byte("secret"))
+		require.NoError(t, err)
+
+		otherKey, _, _, err := f.Format("other", nil)
+		require.NoError(t, err)
+
+		_, _, _, err = f.Deformat(otherKey, value)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "another key")
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, _, _, err := f.Deformat("", nil)
+		require.Error(t, err)
+
+		_, _, _, err = f.Deformat("", []byte("short"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "too short")
+
+		_, value, _, err := f.Format("k", []byte("secret"))
+		require.NoError(t, err)
+
+		value[len(value)-1] ^= 1
+
+		_, _, _, err = f.Deformat("", value)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decrypt value")
+	})
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	t.Run("success", func(t *testing.T) {
+		key := newKey(t)
+
+		read, err := ReadKeyFile(write("key", base64.StdEncoding.EncodeToString(key)+"\n"))
+		require.NoError(t, err)
+		require.Equal(t, key, read)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := ReadKeyFile(filepath.Join(dir, "missing"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read storage encryption key")
+	})
+
+	t.Run("not base64", func(t *testing.T) {
+		_, err := ReadKeyFile(write("invalid", "not base64!"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode storage encryption key")
+	})
+
+	t.Run("wrong size", func(t *testing.T) {
+		_, err := ReadKeyFile(write("short", base64.StdEncoding.EncodeToString([]byte("short"))))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be 32 bytes")
+	})
+}
@@ -0,0 +1,301 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package enumerable provides a storage provider whose stores can be listed, for the offline tools working on all the
+// data of a store.
+//
+// The storage interface has no way to list a store: the provider tags all the values it puts with EntryTagName so
+// that they can be found with a tag query. The tag is hidden from the users of the provider. MongoDB stores are listed
+// natively, which includes the values put before the tag was introduced.
+//
+// The provider also keeps the names of the stores it opens in a catalog store, so that the stores of a provider can be
+// listed with Stores. The stores opened before the catalog was introduced are listed once they're opened again.
+package enumerable
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/aries-framework-go-ext/component/storage/mongodb"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EntryTagName is the name of the tag put on all the values.
+const EntryTagName = "mediatorEntry"
+
+// catalogStoreName is the name of the store holding the names of the stores opened with the provider.
+const catalogStoreName = "mediatorstores"
+
+// Provider is a storage.Provider whose stores can be listed with All, and which lists its stores with Stores.
+type Provider struct {
+	underlying storage.Provider
+
+	mu      sync.Mutex
+	catalog storage.Store
+	// cataloged are the names of the stores known to be in the catalog.
+	cataloged map[string]bool
+}
+
+// NewProvider returns a Provider tagging the values put in the underlying provider.
+func NewProvider(underlying storage.Provider) *Provider {
+	return &Provider{underlying: underlying, cataloged: map[string]bool{}}
+}
+
+// Unwrap returns the underlying provider.
+func (p *Provider) Unwrap() storage.Provider {
+	return p.underlying
+}
+
+// OpenStore opens the store with the given name, adding it to the catalog.
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	s, err := p.underlying.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.addToCatalog(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store{Store: s}, nil
+}
+
+func (p *Provider) addToCatalog(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cataloged[name] {
+		return nil
+	}
+
+	catalog, err := p.openCatalog()
+	if err != nil {
+		return err
+	}
+
+	err = catalog.Put(name, []byte(name))
+	if err != nil {
+		return fmt.Errorf("add store %s to the catalog : %w", name, err)
+	}
+
+	p.cataloged[name] = true
+
+	return nil
+}
+
+// openCatalog opens the catalog store, with the lock held.
+func (p *Provider) openCatalog() (storage.Store, error) {
+	if p.catalog == nil {
+		s, err := p.underlying.OpenStore(catalogStoreName)
+		if err != nil {
+			return nil, fmt.Errorf("open store catalog : %w", err)
+		}
+
+		err = p.underlying.SetStoreConfig(catalogStoreName,
+			storage.StoreConfiguration{TagNames: []string{EntryTagName}})
+		if err != nil {
+			return nil, fmt.Errorf("open store catalog : %w", err)
+		}
+
+		p.catalog = s
+	}
+
+	return &store{Store: p.catalog}, nil
+}
+
+// Stores returns the sorted names of the stores opened with a Provider.
+func Stores(p *Provider) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	catalog, err := p.openCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := All(catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	var names []string
+
+	for {
+		ok, e := it.Next()
+		if e != nil {
+			return nil, fmt.Errorf("list store catalog : %w", e)
+		}
+
+		if !ok {
+			break
+		}
+
+		name, e := it.Key()
+		if e != nil {
+			return nil, fmt.Errorf("list store catalog : %w", e)
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// SetStoreConfig sets the configuration of the store, indexing the entry tag with the configured tags.
+func (p *Provider) SetStoreConfig(name string, config storage.StoreConfiguration) error {
+	config.TagNames = append(withoutEntryTagName(config.TagNames), EntryTagName)
+
+	return p.underlying.SetStoreConfig(name, config)
+}
+
+// GetStoreConfig returns the configuration of the store.
+func (p *Provider) GetStoreConfig(name string) (storage.StoreConfiguration, error) {
+	config, err := p.underlying.GetStoreConfig(name)
+	if err != nil {
+		return storage.StoreConfiguration{}, err
+	}
+
+	config.TagNames = withoutEntryTagName(config.TagNames)
+
+	return config, nil
+}
+
+// GetOpenStores returns the open stores, except the catalog.
+func (p *Provider) GetOpenStores() []storage.Store {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var stores []storage.Store
+
+	for _, s := range p.underlying.GetOpenStores() {
+		if s != p.catalog {
+			stores = append(stores, &store{Store: s})
+		}
+	}
+
+	return stores
+}
+
+// Close closes the underlying provider.
+func (p *Provider) Close() error {
+	return p.underlying.Close()
+}
+
+// All returns an iterator over all the entries of a store opened by a Provider.
+func All(s storage.Store) (storage.Iterator, error) {
+	if tagged, ok := s.(*store); ok {
+		s = tagged.Store
+	}
+
+	if mongoStore, ok := s.(*mongodb.Store); ok {
+		it, err := mongoStore.QueryCustom(bson.D{})
+		if err != nil {
+			return nil, fmt.Errorf("list store : %w", err)
+		}
+
+		return &iterator{Iterator: it}, nil
+	}
+
+	it, err := s.Query(EntryTagName)
+	if err != nil {
+		return nil, fmt.Errorf("list store : %w", err)
+	}
+
+	return &iterator{Iterator: it}, nil
+}
+
+type store struct {
+	storage.Store
+}
+
+func (s *store) Put(key string, value []byte, tags ...storage.Tag) error {
+	return s.Store.Put(key, value, withEntryTag(tags)...)
+}
+
+func (s *store) GetTags(key string) ([]storage.Tag, error) {
+	tags, err := s.Store.GetTags(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return withoutEntryTag(tags), nil
+}
+
+func (s *store) Query(expression string, options ...storage.QueryOption) (storage.Iterator, error) {
+	it, err := s.Store.Query(expression, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &iterator{Iterator: it}, nil
+}
+
+func (s *store) Batch(operations []storage.Operation) error {
+	tagged := make([]storage.Operation, len(operations))
+
+	for i, op := range operations {
+		tagged[i] = op
+
+		// a nil value deletes the entry
+		if op.Value != nil {
+			tagged[i].Tags = withEntryTag(op.Tags)
+		}
+	}
+
+	return s.Store.Batch(tagged)
+}
+
+type iterator struct {
+	storage.Iterator
+}
+
+func (i *iterator) Tags() ([]storage.Tag, error) {
+	tags, err := i.Iterator.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	return withoutEntryTag(tags), nil
+}
+
+func withEntryTag(tags []storage.Tag) []storage.Tag {
+	tagged := make([]storage.Tag, 0, len(tags)+1)
+
+	tagged = append(tagged, withoutEntryTag(tags)...)
+
+	return append(tagged, storage.Tag{Name: EntryTagName})
+}
+
+func withoutEntryTag(tags []storage.Tag) []storage.Tag {
+	filtered := make([]storage.Tag, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag.Name != EntryTagName {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered
+}
+
+func withoutEntryTagName(names []string) []string {
+	filtered := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if name != EntryTagName {
+			filtered = append(filtered, name)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enumerable
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	mockstore "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func listKeys(t *testing.T, s storage.Store) []string {
+	t.Helper()
+
+	it, err := All(s)
+	require.NoError(t, err)
+
+	defer it.Close() // nolint:errcheck // test
+
+	var keys []string
+
+	for {
+		ok, err := it.Next()
+		require.NoError(t, err)
+
+		if !ok {
+			break
+		}
+
+		k, err := it.Key()
+		require.NoError(t, err)
+
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestProvider(t *testing.T) {
+	underlying := mem.NewProvider()
+	provider := NewProvider(underlying)
+	require.Equal(t, underlying, provider.Unwrap())
+
+	s, err := provider.OpenStore("test")
+	require.NoError(t, err)
+
+	require.NoError(t, provider.SetStoreConfig("test", storage.StoreConfiguration{TagNames: []string{"t"}}))
+
+	config, err := provider.GetStoreConfig("test")
+	require.NoError(t, err)
+	require.Equal(t, []string{"t"}, config.TagNames)
+
+	require.NoError(t, s.Put("a", []byte("1"), storage.Tag{Name: "t", Value: "x"}))
+	require.NoError(t, s.Batch([]storage.Operation{
+		{Key: "b", Value: []byte("2")},
+		{Key: "c", Value: []byte("3"), Tags: []storage.Tag{{Name: "t", Value: "y"}}},
+	}))
+
+	t.Run("list", func(t *testing.T) {
+		require.Equal(t, []string{"a", "b", "c"}, listKeys(t, s))
+
+		require.NoError(t, s.Batch([]storage.Operation{{Key: "b"}}))
+		require.Equal(t, []string{"a", "c"}, listKeys(t, s))
+	})
+
+	t.Run("entry tag hidden", func(t *testing.T) {
+		tags, err := s.GetTags("a")
+		require.NoError(t, err)
+		require.Equal(t, []storage.Tag{{Name: "t", Value: "x"}}, tags)
+
+		it, err := s.Query("t:y")
+		require.NoError(t, err)
+
+		ok, err := it.Next()
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		tags, err = it.Tags()
+		require.NoError(t, err)
+		require.Equal(t, []storage.Tag{{Name: "t", Value: "y"}}, tags)
+
+		require.Len(t, provider.GetOpenStores(), 1)
+	})
+
+	t.Run("values put before are not listed", func(t *testing.T) {
+		raw, err := underlying.OpenStore("test")
+		require.NoError(t, err)
+		require.NoError(t, raw.Put("d", []byte("4")))
+
+		require.Equal(t, []string{"a", "c"}, listKeys(t, s))
+	})
+
+	t.Run("stores", func(t *testing.T) {
+		_, err := provider.OpenStore("other")
+		require.NoError(t, err)
+
+		names, err := Stores(provider)
+		require.NoError(t, err)
+		require.Equal(t, []string{"other", "test"}, names)
+
+		// the catalog is kept in the underlying provider
+		names, err = Stores(NewProvider(underlying))
+		require.NoError(t, err)
+		require.Equal(t, []string{"other", "test"}, names)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		failing := NewProvider(&mockstore.MockStoreProvider{
+			ErrOpenStoreHandle: errors.New("open error"),
+		})
+
+		_, err := failing.OpenStore("test")
+		require.EqualError(t, err, "open error")
+
+		_, err = All(&mockstore.MockStore{ErrQuery: errors.New("query error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "list store")
+
+		_, err = NewProvider(&mockstore.MockStoreProvider{
+			Store:         &mockstore.MockStore{Store: map[string]mockstore.DBEntry{}},
+			FailNamespace: catalogStoreName,
+		}).OpenStore("test")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "open store catalog")
+	})
+
+	require.NoError(t, provider.Close())
+}
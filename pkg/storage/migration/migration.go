@@ -0,0 +1,386 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package migration copies the storage of the mediator from a set of providers to another, store by store, to move
+// the mediator to another storage driver or database.
+//
+// The entries of a store are streamed in batches, with their tags, and the number of entries of the target store is
+// checked against the number of entries copied. The stores copied are recorded in a progress file, so that an
+// interrupted migration resumes after the last store copied.
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+const (
+	// batchSize is the number of entries put at once in a target store.
+	batchSize = 100
+
+	progressVersion = 1
+)
+
+// Stats are the numbers of stores and entries migrated.
+type Stats struct {
+	Stores  int `json:"stores"`
+	Entries int `json:"entries"`
+	// Skipped is the number of stores migrated by a previous, interrupted migration.
+	Skipped int `json:"skipped"`
+}
+
+// StoreStats are the numbers of entries of a store migrated.
+type StoreStats struct {
+	// Provider is the storage prefix of the provider the store is opened with, ex: hubrouter_aries.
+	Provider string
+	Name     string
+	Entries  int
+	// Skipped tells that the store was migrated by a previous, interrupted migration.
+	Skipped bool
+}
+
+// Options are the options of a migration.
+type Options struct {
+	// DryRun only counts the entries of the source stores, without writing to the target.
+	DryRun bool
+	// Progress records the stores migrated, which are skipped. Optional.
+	Progress *Progress
+	// Report is called after each store. Optional.
+	Report func(*StoreStats)
+}
+
+// Migrate copies all the stores of the source providers to the target providers, by storage prefix. The source
+// providers must be enumerable.Providers, for their stores to be listed. The target providers aren't used on a dry
+// run.
+func Migrate(from, to map[string]storage.Provider, opts *Options) (*Stats, error) {
+	stats := &Stats{}
+
+	for _, prefix := range sortedPrefixes(from) {
+		source, ok := from[prefix].(*enumerable.Provider)
+		if !ok {
+			return stats, fmt.Errorf("the stores of storage %s can't be listed", prefix)
+		}
+
+		target, ok := to[prefix]
+		if !ok && !opts.DryRun {
+			return stats, fmt.Errorf("no storage %s to migrate to", prefix)
+		}
+
+		names, err := enumerable.Stores(source)
+		if err != nil {
+			return stats, fmt.Errorf("list stores of storage %s : %w", prefix, err)
+		}
+
+		for _, name := range names {
+			s, err := migrateStore(source, target, prefix, name, opts)
+			if err != nil {
+				return stats, fmt.Errorf("migrate store %s of storage %s : %w", name, prefix, err)
+			}
+
+			if s.Skipped {
+				stats.Skipped++
+			}
+
+			stats.Stores++
+			stats.Entries += s.Entries
+
+			if opts.Report != nil {
+				opts.Report(s)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func migrateStore(source, target storage.Provider, prefix, name string, opts *Options) (*StoreStats, error) {
+	s := &StoreStats{Provider: prefix, Name: name}
+
+	if opts.Progress != nil {
+		if n, ok := opts.Progress.Stores[progressKey(prefix, name)]; ok {
+			s.Entries = n
+			s.Skipped = true
+
+			return s, nil
+		}
+	}
+
+	sourceStore, err := source.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		s.Entries, err = count(sourceStore)
+
+		return s, err
+	}
+
+	targetStore, err := openTarget(source, target, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Entries, err = copyStore(sourceStore, targetStore)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := count(targetStore)
+	if err != nil {
+		return nil, fmt.Errorf("verify : %w", err)
+	}
+
+	if n != s.Entries {
+		return nil, fmt.Errorf("verify : %d entries copied, the target store has %d", s.Entries, n)
+	}
+
+	if opts.Progress != nil {
+		err = opts.Progress.add(prefix, name, s.Entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// openTarget opens the target store, with the tags of the source store.
+func openTarget(source, target storage.Provider, name string) (storage.Store, error) {
+	config, err := source.GetStoreConfig(name)
+	// depending on the driver, stores without tags have no config
+	if err != nil && !errors.Is(err, storage.ErrStoreNotFound) && !errors.Is(err, storage.ErrDataNotFound) {
+		return nil, fmt.Errorf("get store config : %w", err)
+	}
+
+	s, err := target.OpenStore(name)
+	if err != nil {
+		return nil, fmt.Errorf("open target store : %w", err)
+	}
+
+	err = target.SetStoreConfig(name, storage.StoreConfiguration{TagNames: config.TagNames})
+	if err != nil {
+		return nil, fmt.Errorf("set target store config : %w", err)
+	}
+
+	return s, nil
+}
+
+func copyStore(source, target storage.Store) (int, error) {
+	it, err := enumerable.All(source)
+	if err != nil {
+		return 0, err
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	count := 0
+
+	var batch []storage.Operation
+
+	for {
+		ok, err := it.Next()
+		if err != nil {
+			return count, fmt.Errorf("list store : %w", err)
+		}
+
+		if ok {
+			op, e := readOperation(it)
+			if e != nil {
+				return count, e
+			}
+
+			batch = append(batch, *op)
+		}
+
+		if len(batch) == batchSize || (!ok && len(batch) > 0) {
+			err = target.Batch(batch)
+			if err != nil {
+				return count, fmt.Errorf("put entries : %w", err)
+			}
+
+			count += len(batch)
+			batch = batch[:0]
+		}
+
+		if !ok {
+			return count, nil
+		}
+	}
+}
+
+func readOperation(it storage.Iterator) (*storage.Operation, error) {
+	key, err := it.Key()
+	if err != nil {
+		return nil, fmt.Errorf("read key : %w", err)
+	}
+
+	value, err := it.Value()
+	if err != nil {
+		return nil, fmt.Errorf("read value of %s : %w", key, err)
+	}
+
+	tags, err := it.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("read tags of %s : %w", key, err)
+	}
+
+	return &storage.Operation{Key: key, Value: value, Tags: tags}, nil
+}
+
+func count(s storage.Store) (int, error) {
+	it, err := enumerable.All(s)
+	if err != nil {
+		return 0, err
+	}
+
+	defer it.Close() // nolint:errcheck // read only
+
+	n := 0
+
+	for {
+		ok, err := it.Next()
+		if err != nil {
+			return n, fmt.Errorf("list store : %w", err)
+		}
+
+		if !ok {
+			return n, nil
+		}
+
+		n++
+	}
+}
+
+// Progress records the stores migrated in a file, to resume an interrupted migration.
+type Progress struct {
+	Version int `json:"version"`
+	// Fingerprint identifies the source and target storage, without holding their datasources, which can hold
+	// credentials.
+	Fingerprint string `json:"fingerprint"`
+	// Stores are the numbers of entries of the stores migrated, by storage prefix and store name.
+	Stores map[string]int `json:"stores"`
+
+	path string
+}
+
+// Fingerprint returns the fingerprint of the datasources of a migration, recorded in its progress file.
+func Fingerprint(datasources ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(datasources, "\n")))
+
+	return hex.EncodeToString(h[:])
+}
+
+// LoadProgress reads the progress of a migration from the file at path. It returns an empty Progress, saved to the
+// file as stores are migrated, if there's no file. The fingerprint must be the one of the interrupted migration.
+func LoadProgress(path, fingerprint string) (*Progress, error) {
+	p := &Progress{Version: progressVersion, Fingerprint: fingerprint, Stores: map[string]int{}, path: path}
+
+	content, err := ioutil.ReadFile(path) // nolint:gosec // path set by the operator
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read progress : %w", err)
+	}
+
+	err = json.Unmarshal(content, p)
+	if err != nil {
+		return nil, fmt.Errorf("parse progress %s : %w", path, err)
+	}
+
+	if p.Version != progressVersion {
+		return nil, fmt.Errorf("unsupported progress version %d in %s", p.Version, path)
+	}
+
+	if p.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("progress %s is the one of a migration between other storage", path)
+	}
+
+	if p.Stores == nil {
+		p.Stores = map[string]int{}
+	}
+
+	return p, nil
+}
+
+// Resumed tells if the progress is the one of an interrupted migration.
+func (p *Progress) Resumed() bool {
+	return len(p.Stores) > 0
+}
+
+// Remove removes the progress file, once the migration is complete.
+func (p *Progress) Remove() error {
+	err := os.Remove(p.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove progress : %w", err)
+	}
+
+	return nil
+}
+
+func (p *Progress) add(prefix, name string, entries int) error {
+	p.Stores[progressKey(prefix, name)] = entries
+
+	content, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal progress : %w", err)
+	}
+
+	// the progress is written to a temporary file, so that an interruption never leaves a partial progress file
+	f, err := ioutil.TempFile(filepath.Dir(p.path), filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save progress : %w", err)
+	}
+
+	defer os.Remove(f.Name()) // nolint:errcheck // removed if not renamed
+
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close() // nolint:errcheck,gosec // write error returned
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), p.path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("save progress : %w", err)
+	}
+
+	return nil
+}
+
+func progressKey(prefix, name string) string {
+	return prefix + "/" + name
+}
+
+func sortedPrefixes(providers map[string]storage.Provider) []string {
+	prefixes := make([]string, 0, len(providers))
+
+	for prefix := range providers {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Strings(prefixes)
+
+	return prefixes
+}
@@ -0,0 +1,194 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/mediator/pkg/storage/enumerable"
+)
+
+func newProviders() map[string]storage.Provider {
+	return map[string]storage.Provider{
+		"hubrouter":       enumerable.NewProvider(mem.NewProvider()),
+		"hubrouter_aries": enumerable.NewProvider(mem.NewProvider()),
+	}
+}
+
+func newSource(t *testing.T) map[string]storage.Provider {
+	t.Helper()
+
+	source := newProviders()
+
+	kmsStore, err := source["hubrouter_aries"].OpenStore("kmsdb")
+	require.NoError(t, err)
+
+	// more than a batch
+	for i := 0; i < 2*batchSize+1; i++ {
+		require.NoError(t, kmsStore.Put(fmt.Sprintf("key%d", i), []byte("private key")))
+	}
+
+	connStore, err := source["hubrouter_aries"].OpenStore("didexchange")
+	require.NoError(t, err)
+	require.NoError(t, source["hubrouter_aries"].SetStoreConfig("didexchange",
+		storage.StoreConfiguration{TagNames: []string{"theirDID"}}))
+	require.NoError(t, connStore.Put("conn_1", []byte("{}"), storage.Tag{Name: "theirDID", Value: "peer1"}))
+
+	didStore, err := source["hubrouter"].OpenStore("router-invitation-did")
+	require.NoError(t, err)
+	require.NoError(t, didStore.Put("did", []byte("did:orb:1")))
+
+	return source
+}
+
+func TestMigrate(t *testing.T) {
+	source := newSource(t)
+
+	t.Run("migrate", func(t *testing.T) {
+		target := newProviders()
+
+		var reported []string
+
+		stats, err := Migrate(source, target, &Options{Report: func(s *StoreStats) {
+			reported = append(reported, fmt.Sprintf("%s/%s:%d", s.Provider, s.Name, s.Entries))
+		}})
+		require.NoError(t, err)
+		require.Equal(t, &Stats{Stores: 3, Entries: 2*batchSize + 3}, stats)
+		require.Equal(t, []string{
+			"hubrouter/router-invitation-did:1",
+			"hubrouter_aries/didexchange:1",
+			fmt.Sprintf("hubrouter_aries/kmsdb:%d", 2*batchSize+1),
+		}, reported)
+
+		s, err := target["hubrouter_aries"].OpenStore("didexchange")
+		require.NoError(t, err)
+
+		it, err := s.Query("theirDID:peer1")
+		require.NoError(t, err)
+
+		ok, err := it.Next()
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		config, err := target["hubrouter_aries"].GetStoreConfig("didexchange")
+		require.NoError(t, err)
+		require.Equal(t, []string{"theirDID"}, config.TagNames)
+
+		s, err = target["hubrouter_aries"].OpenStore("kmsdb")
+		require.NoError(t, err)
+
+		v, err := s.Get("key200")
+		require.NoError(t, err)
+		require.Equal(t, []byte("private key"), v)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		target := newProviders()
+
+		stats, err := Migrate(source, target, &Options{DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, &Stats{Stores: 3, Entries: 2*batchSize + 3}, stats)
+
+		names, err := enumerable.Stores(target["hubrouter_aries"].(*enumerable.Provider))
+		require.NoError(t, err)
+		require.Empty(t, names)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "migrate.progress")
+		fingerprint := Fingerprint("mem://a", "mem://b")
+
+		progress, err := LoadProgress(path, fingerprint)
+		require.NoError(t, err)
+		require.False(t, progress.Resumed())
+		require.NoError(t, progress.add("hubrouter_aries", "kmsdb", 2*batchSize+1))
+
+		progress, err = LoadProgress(path, fingerprint)
+		require.NoError(t, err)
+		require.True(t, progress.Resumed())
+
+		target := newProviders()
+
+		stats, err := Migrate(source, target, &Options{Progress: progress})
+		require.NoError(t, err)
+		require.Equal(t, &Stats{Stores: 3, Entries: 2*batchSize + 3, Skipped: 1}, stats)
+
+		names, err := enumerable.Stores(target["hubrouter_aries"].(*enumerable.Provider))
+		require.NoError(t, err)
+		require.Equal(t, []string{"didexchange"}, names)
+
+		progress, err = LoadProgress(path, fingerprint)
+		require.NoError(t, err)
+		require.Len(t, progress.Stores, 3)
+
+		require.NoError(t, progress.Remove())
+
+		progress, err = LoadProgress(path, fingerprint)
+		require.NoError(t, err)
+		require.False(t, progress.Resumed())
+	})
+
+	t.Run("verification failure", func(t *testing.T) {
+		target := newProviders()
+
+		s, err := target["hubrouter"].OpenStore("router-invitation-did")
+		require.NoError(t, err)
+		require.NoError(t, s.Put("other", []byte("did:orb:2")))
+
+		_, err = Migrate(source, target, &Options{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify : 1 entries copied, the target store has 2")
+	})
+
+	t.Run("missing target storage", func(t *testing.T) {
+		_, err := Migrate(source, map[string]storage.Provider{
+			"hubrouter": enumerable.NewProvider(mem.NewProvider()),
+		}, &Options{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no storage hubrouter_aries to migrate to")
+	})
+
+	t.Run("storage not enumerable", func(t *testing.T) {
+		_, err := Migrate(map[string]storage.Provider{"hubrouter": mem.NewProvider()}, newProviders(), &Options{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the stores of storage hubrouter can't be listed")
+	})
+}
+
+func TestLoadProgress(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "other.progress")
+	progress, err := LoadProgress(path, Fingerprint("a", "b"))
+	require.NoError(t, err)
+	require.NoError(t, progress.add("hubrouter", "store", 1))
+
+	_, err = LoadProgress(path, Fingerprint("a", "c"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is the one of a migration between other storage")
+
+	path = filepath.Join(dir, "invalid.progress")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0o600))
+
+	_, err = LoadProgress(path, Fingerprint("a", "b"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "parse progress")
+
+	path = filepath.Join(dir, "version.progress")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"version":2}`), 0o600))
+
+	_, err = LoadProgress(path, Fingerprint("a", "b"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported progress version 2")
+}